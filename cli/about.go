@@ -0,0 +1,120 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	aboutHelp = "Prints the licenses of the modules that this go-licenses binary is built from."
+
+	// aboutFormat is the output format of the about command.
+	aboutFormat string
+)
+
+// newAboutCmd returns the about command.
+func newAboutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "about",
+		Short: aboutHelp,
+		Long: aboutHelp + `
+
+The modules are read from the build information embedded in the binary, so the output
+matches the binary even if it was built elsewhere. Binaries don't contain license files,
+so the licenses are found in the module cache, to which missing modules are downloaded
+unless --offline is set. Use it to ship go-licenses with its own attributions.`,
+		Args: cobra.NoArgs,
+		RunE: aboutMain,
+	}
+	cmd.Flags().StringVar(&aboutFormat, "format", "attribution", "Output format, one of: attribution, csv, json. Like for report, attribution prints plain-text attributions with the license texts, csv prints one line per module and json prints the JSON report.")
+	return cmd
+}
+
+func aboutMain(cmd *cobra.Command, _ []string) error {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return errors.New("this go-licenses binary has no build information, build it with module support")
+	}
+	switch aboutFormat {
+	case "attribution", "csv", "json":
+	default:
+		return fmt.Errorf("unknown --format %q, want one of: attribution, csv, json", aboutFormat)
+	}
+	metadata := newRunMetadata(cmd, time.Now(), nil)
+	metadata.RootModule = bi.Main.Path
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	opts, err := libraryOptions()
+	if err != nil {
+		return err
+	}
+	opts.DownloadModules = !offline
+	ctx := licenses.WithWarningHandler(runContext(), recordWarning)
+	libs, err := licenses.BuildInfoLibraries(ctx, classifier, opts, bi)
+	if err != nil {
+		return err
+	}
+	applyLicenseOverrides(classifier, libs)
+
+	style, err := shortNameStyle()
+	if err != nil {
+		return err
+	}
+	policy, err := currentPolicy()
+	if err != nil {
+		return err
+	}
+	placeholder, err := parsePlaceholder()
+	if err != nil {
+		return err
+	}
+	reporter := libraryReporter{
+		classifier:      classifier,
+		style:           style,
+		withLicenseText: aboutFormat != "csv",
+		policy:          policy,
+		placeholder:     placeholder,
+	}
+	var data []libraryData
+	for _, result := range reporter.reportAll(ctx, libs) {
+		if result.err != nil {
+			return result.err
+		}
+		if result.included {
+			data = append(data, result.data)
+		}
+	}
+	switch aboutFormat {
+	case "csv":
+		err = reportCSV(data)
+	case "json":
+		err = reportJSON(metadata, classifier, data)
+	default:
+		err = reportAttribution(data)
+	}
+	if err != nil {
+		return err
+	}
+	return reportClassifyErrors()
+}
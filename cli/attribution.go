@@ -0,0 +1,165 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"k8s.io/klog/v2"
+)
+
+// attributionRule separates the blocks of the attribution report.
+var attributionRule = strings.Repeat("=", 80)
+
+// attributionGroup are the libraries whose license file is a verbatim copy of the
+// canonical text of license, so that the text is printed only once for all of them.
+type attributionGroup struct {
+	license string
+	libs    []libraryData
+}
+
+// reportAttribution prints libs as plain-text attributions, ready to ship with a
+// binary. Libraries whose license file is a canonical copy of a license, see
+// licenses.IsCanonicalCopy, are grouped by license: their copyright statements are
+// followed by the canonical text once. Every other library gets a block with its own
+// license text. NOTICE files are reproduced after the license text of each block.
+func reportAttribution(libs []libraryData) error {
+	groups := make(map[string]*attributionGroup)
+	var names []string
+	var others []libraryData
+	for _, lib := range libs {
+		if lib.License == "" || !licenses.IsCanonicalCopy(lib.License, lib.LicenseName) {
+			others = append(others, lib)
+			continue
+		}
+		g, ok := groups[lib.LicenseName]
+		if !ok {
+			g = &attributionGroup{license: lib.LicenseName}
+			groups[lib.LicenseName] = g
+			names = append(names, lib.LicenseName)
+		}
+		g.libs = append(g.libs, lib)
+	}
+	sort.Strings(names)
+
+	w := bufio.NewWriter(out)
+	for _, name := range names {
+		g := groups[name]
+		var used []string
+		var statements []string
+		seen := make(map[string]bool)
+		for _, lib := range g.libs {
+			used = append(used, attributionName(lib))
+			for _, c := range lib.Copyrights {
+				if !seen[c.Statement] {
+					seen[c.Statement] = true
+					statements = append(statements, c.Statement)
+				}
+			}
+		}
+		text, _ := licenses.CanonicalText(name)
+		writeAttributionHeader(w, name, "Used by: "+strings.Join(used, ", "))
+		for _, line := range statements {
+			fmt.Fprintln(w, line)
+		}
+		if len(statements) > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, strings.TrimRight(text, "\n"))
+		for _, lib := range g.libs {
+			writeAttributionNotice(w, lib)
+		}
+	}
+	for _, lib := range others {
+		writeAttributionHeader(w, attributionName(lib), "License: "+lib.LicenseName)
+		switch {
+		case lib.License != "":
+			fmt.Fprintln(w, strings.TrimRight(lib.License, "\n"))
+		case lib.LicenseURL != "" && lib.LicenseURL != UNKNOWN:
+			fmt.Fprintf(w, "The license text is not available, see %s.\n", lib.LicenseURL)
+		default:
+			fmt.Fprintln(w, "The license text is not available.")
+		}
+		writeAttributionNotice(w, lib)
+	}
+	return w.Flush()
+}
+
+// attributionName returns the name of lib followed by its version, if known.
+func attributionName(lib libraryData) string {
+	if lib.Version == "" || lib.Version == UNKNOWN {
+		return lib.Name
+	}
+	return lib.Name + " " + lib.Version
+}
+
+// writeAttributionHeader writes the title and subtitle of a block between rules.
+func writeAttributionHeader(w *bufio.Writer, title, subtitle string) {
+	fmt.Fprintf(w, "%s\n%s\n%s\n%s\n\n", attributionRule, title, subtitle, attributionRule)
+}
+
+// writeAttributionNotice writes the NOTICE file of lib, if any, and the other files whose
+// terms apply along with its license, e.g. PATENTS, after its license text.
+func writeAttributionNotice(w *bufio.Writer, lib libraryData) {
+	if lib.Notice != "" {
+		fmt.Fprintf(w, "\nNOTICE of %s:\n\n%s\n", lib.Name, strings.TrimRight(lib.Notice, "\n"))
+	}
+	for _, f := range lib.LicenseFiles {
+		if f.Text == "" || licenses.IsNoticeFile(path.Base(f.Path)) {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s of %s:\n\n%s\n", f.Path, lib.Name, strings.TrimRight(f.Text, "\n"))
+	}
+	fmt.Fprintln(w)
+}
+
+// copyrightStatements returns the copyright statements in text, in order.
+func copyrightStatements(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if licenses.IsCopyrightStatement(line) {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+// copyrightsData returns the copyright statements in the license file of lib, followed
+// by those in the headers of its Go files that the license file doesn't contain.
+func copyrightsData(lib *licenses.Library) []copyrightData {
+	var cs []licenses.Copyright
+	if lib.LicensePath != "" {
+		fcs, err := licenses.FileCopyrights(lib.LicensePath)
+		if err != nil {
+			klog.Errorf("Error reading license file %q: %v", lib.LicensePath, err)
+		}
+		cs = fcs
+	}
+	var data []copyrightData
+	seen := make(map[string]bool)
+	for _, c := range append(cs, lib.Copyrights...) {
+		if seen[c.Statement] {
+			continue
+		}
+		seen[c.Statement] = true
+		data = append(data, copyrightData{Statement: c.Statement, Years: c.Years, Holder: c.Holder, Path: pathInModule(lib, c.Path)})
+	}
+	return data
+}
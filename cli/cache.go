@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// noCache disables the on-disk cache of classifications, URLs and license texts.
+	noCache bool
+
+	// cache is the on-disk cache of this run, nil if disabled.
+	cache *licenses.Cache
+)
+
+// addCacheFlags adds the cache flags shared by all commands to flags.
+func addCacheFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&noCache, "no_cache", false, "Don't use the on-disk cache of license classifications, license URLs and downloaded license texts of module versions, e.g. after upgrading the classifier data outside of go-licenses.")
+}
+
+// setUpCache opens the on-disk cache, unless --no_cache is set. The cache is also left
+// out when every request has to be made: when recording HTTP interactions or listing the
+// endpoints a command would contact.
+func setUpCache() {
+	if noCache || recordDir != "" || noNetwork {
+		return
+	}
+	dir, err := licenses.DefaultCacheDir()
+	if err != nil {
+		klog.Warningf("Not caching results: %v", err)
+		return
+	}
+	cache = licenses.NewCache(dir, goEnvOr("GOMODCACHE", ""))
+}
+
+// saveCache writes the results of this run to the on-disk cache. Failing to do so doesn't
+// fail the command.
+func saveCache() {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(); err != nil {
+		klog.Warningf("Error saving the cache: %v", err)
+	}
+}
+
+// cachedLicenseText returns the license text of lib downloaded from url in an earlier run,
+// if cached.
+func cachedLicenseText(lib *licenses.Library, url string) (string, bool) {
+	m := lib.Module()
+	if cache == nil || m == nil {
+		return "", false
+	}
+	return cache.LicenseText(m.Path, m.Version, url)
+}
+
+// cacheLicenseText caches the license text of lib downloaded from url.
+func cacheLicenseText(lib *licenses.Library, url, text string) {
+	if m := lib.Module(); cache != nil && m != nil {
+		cache.SetLicenseText(m.Path, m.Version, url, text)
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkHelp = "Checks whether licenses for a package are not allowed."
+
+	allowedLicenses    []string
+	disallowedLicenses []string
+	disallowedTypes    []string
+	failOnNewDeps      bool
+	baselinePath       string
+	approvalsPath      string
+)
+
+// checkResult is the result of check, written to --output as JSON.
+type checkResult struct {
+	// Passed is false if check failed, i.e. exited with status 1.
+	Passed   bool           `json:"passed"`
+	Findings []checkFinding `json:"findings"`
+}
+
+// checkFinding is a finding of check, as printed on stderr.
+type checkFinding struct {
+	Message string `json:"message"`
+	// Library is the library, if the finding is about one of its licenses.
+	Library     string `json:"library,omitempty"`
+	License     string `json:"license,omitempty"`
+	LicenseType string `json:"licenseType,omitempty"`
+	// Tolerated is set if the finding doesn't fail the check, e.g. because of an
+	// exception or maxUnknown.
+	Tolerated bool `json:"tolerated,omitempty"`
+	// File and Line locate the require directive of the library's module in go.mod, if
+	// the library is a direct dependency, e.g. to annotate that line in code review.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// checkFindings are the findings of the current check run.
+var checkFindings []checkFinding
+
+// addFinding prints the diagnostic of f, in yellow if tolerated, and adds it to
+// checkFindings.
+func addFinding(f checkFinding) {
+	color := colorRed
+	if f.Tolerated {
+		color = colorYellow
+	}
+	diagnosticf(color, "%s", f.Message)
+	checkFindings = append(checkFindings, f)
+}
+
+// writeCheckResult writes the result of check to --output, if set.
+func writeCheckResult(passed bool) error {
+	if outputPath == "" {
+		return nil
+	}
+	findings := checkFindings
+	if findings == nil {
+		findings = []checkFinding{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(checkResult{Passed: passed, Findings: findings}); err != nil {
+		return fmt.Errorf("writing check result: %w", err)
+	}
+	return nil
+}
+
+// newCheckCmd returns the check command.
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <package> [package...]",
+		Short: checkHelp,
+		Long:  checkHelp + packageHelp,
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  checkMain,
+	}
+	cmd.Flags().StringSliceVar(&allowedLicenses, "allowed_licenses", []string{}, "list of allowed license names, can't be used in combination with disallowed_types")
+	cmd.Flags().StringSliceVar(&disallowedTypes, "disallowed_types", []string{}, "list of disallowed license types, can't be used in combination with allowed_licenses (default: forbidden, unknown)")
+	cmd.Flags().StringSliceVar(&disallowedLicenses, "disallowed_licenses", []string{}, "list of disallowed license names, e.g. AGPL-3.0, checked in addition to allowed_licenses or disallowed_types")
+
+	cmd.Flags().BoolVar(&failOnNewDeps, "fail_on_new_deps", false, "fail for modules that are neither in the --baseline nor in the --approvals file, regardless of their licenses")
+	cmd.Flags().StringVar(&baselinePath, "baseline", "", "file listing the modules already in use, one module path per line, e.g. created by report --format=modules")
+	cmd.Flags().StringVar(&approvalsPath, "approvals", "", "file listing approved new modules, one module path per line, optionally pinned with @version")
+	cmd.Flags().BoolVar(&silent, "silent", false, "print nothing, not even errors, and report the result only with the exit status and, with --output, the result file")
+
+	return cmd
+}
+
+func checkMain(_ *cobra.Command, args []string) error {
+	policy, err := currentPolicy()
+	if err != nil {
+		return err
+	}
+
+	var knownModules []string
+	if failOnNewDeps {
+		if baselinePath == "" {
+			return errors.New("--fail_on_new_deps requires --baseline")
+		}
+		for _, path := range []string{baselinePath, approvalsPath} {
+			if path == "" {
+				continue
+			}
+			modules, err := readModuleList(path)
+			if err != nil {
+				return err
+			}
+			knownModules = append(knownModules, modules...)
+		}
+	}
+
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+
+	libs, err := libraries(runContext(), classifier, args)
+	if err != nil {
+		return err
+	}
+
+	// indicate that a forbidden license was found
+	foundDisallowed := false
+	// unknowns are the findings of unknown licenses in unknownLibs libraries, if tolerated
+	// up to cfg.MaxUnknown.
+	var unknowns []checkFinding
+	unknownLibs := 0
+
+	for _, lib := range libs {
+		found, libUnknowns, err := checkLibrary(classifier, lib, policy)
+		if err != nil {
+			return err
+		}
+		foundDisallowed = foundDisallowed || found
+		if len(libUnknowns) > 0 {
+			unknowns = append(unknowns, libUnknowns...)
+			unknownLibs++
+		}
+	}
+
+	if cfg.MaxUnknown != nil && unknownLibs > 0 {
+		if max := cfg.MaxUnknown.max(len(libs)); unknownLibs > max {
+			for _, u := range unknowns {
+				addFinding(u)
+			}
+			addFinding(checkFinding{Message: fmt.Sprintf("%d of %d libraries have unknown licenses, more than the %d allowed by maxUnknown %s", unknownLibs, len(libs), max, cfg.MaxUnknown)})
+			foundDisallowed = true
+		} else {
+			for _, u := range unknowns {
+				u.Tolerated = true
+				checkFindings = append(checkFindings, u)
+			}
+			addFinding(checkFinding{Message: fmt.Sprintf("%d of %d libraries have unknown licenses, tolerated by maxUnknown %s", unknownLibs, len(libs), cfg.MaxUnknown), Tolerated: true})
+		}
+	}
+
+	if len(cfg.AllowedModules) > 0 {
+		for _, m := range modulesNotAllowed(libs, cfg.AllowedModules) {
+			addFinding(checkFinding{Message: fmt.Sprintf("Module %s is not in the allowed modules", m)})
+			foundDisallowed = true
+		}
+	}
+
+	if failOnNewDeps {
+		for _, m := range modulesNotAllowed(libs, knownModules) {
+			addFinding(checkFinding{Message: fmt.Sprintf("New module %s is neither in the baseline nor approved", m)})
+			foundDisallowed = true
+		}
+	}
+
+	if err := writeCheckResult(!foundDisallowed); err != nil {
+		return err
+	}
+	if foundDisallowed {
+		// The findings have been printed already.
+		return &ExitError{Code: 1, Err: errors.New("found licenses or modules that are not allowed")}
+	}
+
+	return nil
+}
+
+// checkLibrary prints the licenses of lib that are not allowed and reports whether there
+// were any. Every license of a library following the REUSE specification is checked.
+// Licenses of unknown type tolerated by the policy are returned as findings instead.
+func checkLibrary(classifier licenses.Classifier, lib *licenses.Library, policy licensePolicy) (bool, []checkFinding, error) {
+	libLicenses, err := libraryLicenses(classifier, lib)
+	if err != nil {
+		return false, nil, err
+	}
+	for _, l := range libLicenses {
+		emitClassified(lib, l.name, l.typ)
+	}
+
+	found := false
+	var unknowns []checkFinding
+	for _, v := range policy.violations(lib, libLicenses) {
+		f := checkFinding{Message: v.message, Library: lib.Name(), License: v.name, LicenseType: v.typ.String()}
+		if at := requiredAt(lib); at != nil {
+			f.File, f.Line = at.File, at.Line
+		}
+		switch {
+		case v.exception != "":
+			f.Message = fmt.Sprintf("%s, allowed by exception %s", v.message, v.exception)
+			f.Tolerated = true
+			addFinding(f)
+		case v.unknown:
+			unknowns = append(unknowns, f)
+		default:
+			addFinding(f)
+			found = true
+		}
+	}
+	return found, unknowns, nil
+}
+
+// modulesNotAllowed returns the dependency modules of libs, as path@version, that match
+// none of the allowed entries. The main module and modules under the trusted domains are
+// always allowed.
+func modulesNotAllowed(libs []*licenses.Library, allowed []string) []string {
+	seen := make(map[string]bool)
+	var notAllowed []string
+	for _, lib := range libs {
+		m := lib.Module()
+		if m == nil || m.Main || isTrusted(lib) {
+			continue
+		}
+		id := m.Path + "@" + m.Version
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if !isAllowedModule(m, allowed) {
+			notAllowed = append(notAllowed, id)
+		}
+	}
+	sort.Strings(notAllowed)
+	return notAllowed
+}
+
+// isAllowedModule reports whether m matches one of the allowed entries, which are module
+// path patterns optionally followed by "@version".
+func isAllowedModule(m *licenses.Module, allowed []string) bool {
+	for _, entry := range allowed {
+		pattern := entry
+		if i := strings.Index(entry, "@"); i >= 0 {
+			if entry[i+1:] != m.Version {
+				continue
+			}
+			pattern = entry[:i]
+		}
+		if ok, _ := path.Match(pattern, m.Path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readModuleList reads a file listing one module per line. Empty lines and lines
+// starting with # are ignored.
+func readModuleList(path string) ([]string, error) {
+	return readList(path, "module list")
+}
+
+// readList reads a file listing one entry, e.g. a module, per line. Empty lines and lines
+// starting with # are ignored. what names the list in errors.
+func readList(path, what string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", what, err)
+	}
+	var entries []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, nil
+}
+
+func getDisallowedLicenseTypes() []licenses.Type {
+	types := disallowedTypes
+	if len(types) == 0 {
+		types = cfg.DisallowedTypes
+	}
+	if len(types) == 0 {
+		return []licenses.Type{}
+	}
+
+	excludedLicenseTypes := make([]licenses.Type, 0)
+
+	for _, v := range types {
+		switch strings.TrimSpace(strings.ToLower(v)) {
+		case "forbidden":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Forbidden)
+		case "notice":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Notice)
+		case "permissive":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Permissive)
+		case "reciprocal":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Reciprocal)
+		case "restricted":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Restricted)
+		case "unencumbered":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Unencumbered)
+		case "unknown":
+			excludedLicenseTypes = append(excludedLicenseTypes, licenses.Unknown)
+		default:
+			fmt.Fprintf(
+				diagnostics,
+				"Unknown license type '%s' provided.\n"+
+					"Allowed types: forbidden, notice, permissive, reciprocal, restricted, unencumbered, unknown\n",
+				v)
+		}
+	}
+
+	return excludedLicenseTypes
+}
+
+func isDisallowedLicenseType(licenseType licenses.Type, excludedLicenseTypes []licenses.Type) bool {
+	for _, excluded := range excludedLicenseTypes {
+		if excluded == licenseType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getAllowedLicenseNames() []string {
+	if len(allowedLicenses) == 0 {
+		return trimmedNames(cfg.AllowedLicenses)
+	}
+	return trimmedNames(allowedLicenses)
+}
+
+func getDisallowedLicenseNames() []string {
+	if len(disallowedLicenses) == 0 {
+		return trimmedNames(cfg.DisallowedLicenses)
+	}
+	return trimmedNames(disallowedLicenses)
+}
+
+// trimmedNames returns names with surrounding whitespace removed.
+func trimmedNames(names []string) []string {
+	trimmed := []string{}
+	for _, name := range names {
+		trimmed = append(trimmed, strings.TrimSpace(name))
+	}
+	return trimmed
+}
+
+func isAllowedLicenseName(licenseName string, allowedLicenseNames []string) bool {
+	for _, allowed := range allowedLicenseNames {
+		if allowed == licenseName {
+			return true
+		}
+	}
+
+	return false
+}
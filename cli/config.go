@@ -0,0 +1,170 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+)
+
+// config holds the settings that can be provided in the file passed via --config.
+// Settings that are also available as flags are documented on the flags.
+type config struct {
+	// LicenseConfidenceThresholds maps license names to the minimum confidence
+	// required to identify them, overriding --confidence_threshold for those licenses.
+	LicenseConfidenceThresholds map[string]float64 `json:"licenseConfidenceThresholds,omitempty"`
+	// DeepScanExclude are glob patterns for directory names, e.g. "testdata" or
+	// "examples", whose files are not searched for license information such as SPDX tags.
+	DeepScanExclude []string `json:"deepScanExclude,omitempty"`
+	// DeepScanSkipGenerated excludes generated Go files from those searches.
+	DeepScanSkipGenerated bool `json:"deepScanSkipGenerated,omitempty"`
+	// LocalizedLicenseNames also accepts license files with localized names, e.g. LIZENZ.
+	LocalizedLicenseNames bool `json:"localizedLicenseNames,omitempty"`
+	// AllowedModules is the inventory of modules that may be used. If set, check fails
+	// for any module not matching one of the entries. An entry is a module path, which
+	// may contain path.Match wildcards, optionally followed by "@version".
+	AllowedModules []string `json:"allowedModules,omitempty"`
+	// MaxUnknown, if set, is the number of libraries with unknown licenses that check
+	// tolerates, either absolute, e.g. 3, or relative to all libraries, e.g. "5%".
+	MaxUnknown *unknownLimit `json:"maxUnknown,omitempty"`
+	// AllowedLicenses, DisallowedLicenses and DisallowedTypes configure the license policy
+	// like the flags of the same names, which take precedence if set.
+	AllowedLicenses    []string `json:"allowedLicenses,omitempty"`
+	DisallowedLicenses []string `json:"disallowedLicenses,omitempty"`
+	DisallowedTypes    []string `json:"disallowedTypes,omitempty"`
+	// PolicyExceptions allow modules to use licenses that check would fail for otherwise.
+	PolicyExceptions []exception `json:"policyExceptions,omitempty"`
+	// PreferredLicenses are the licenses that check applies to libraries offering a
+	// choice of licenses, most preferred first, if the policy allows them.
+	PreferredLicenses []string `json:"preferredLicenses,omitempty"`
+	// PolicyImports add the licenses allowed or disallowed by policy definitions of other
+	// tools, e.g. ORT, to AllowedLicenses and DisallowedLicenses.
+	PolicyImports []policyImport `json:"policyImports,omitempty"`
+	// UserAgent replaces the User-Agent of Go's HTTP client in all outbound requests.
+	UserAgent string `json:"userAgent,omitempty"`
+	// HTTPHeaders are the headers added to the outbound https requests to a host, by the
+	// host as in request URLs, e.g. to authenticate with a proxy. Values may refer to
+	// environment variables like ${TOKEN}.
+	HTTPHeaders map[string]map[string]string `json:"httpHeaders,omitempty"`
+	// ModuleOverrides replace report fields of modules whose upstream metadata is wrong
+	// or missing, e.g. their display name or notice text.
+	ModuleOverrides []moduleOverride `json:"moduleOverrides,omitempty"`
+	// CodeHosts map modules on self-hosted code hosts, e.g. GitLab, Gitea or Azure DevOps
+	// instances, to the URLs of their files.
+	CodeHosts []licenses.HostRule `json:"codeHosts,omitempty"`
+	// TrustedDomains are the domains of the organization's own modules, like
+	// --trusted_domains, which takes precedence if set.
+	TrustedDomains []string `json:"trustedDomains,omitempty"`
+}
+
+// unknownLimit is the maximum number of libraries with unknown licenses, see
+// config.MaxUnknown.
+type unknownLimit struct {
+	count   int
+	percent float64
+	// relative is true if the limit is percent of all libraries rather than count.
+	relative bool
+}
+
+func (l *unknownLimit) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return fmt.Errorf("maxUnknown must be a non-negative integer or a percentage, got %v", v)
+		}
+		*l = unknownLimit{count: int(v)}
+		return nil
+	case string:
+		if strings.HasSuffix(v, "%") {
+			p, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			if err == nil && p >= 0 && p <= 100 {
+				*l = unknownLimit{percent: p, relative: true}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("maxUnknown must be a non-negative integer or a percentage like \"5%%\", got %s", b)
+}
+
+func (l unknownLimit) String() string {
+	if l.relative {
+		return strconv.FormatFloat(l.percent, 'f', -1, 64) + "%"
+	}
+	return strconv.Itoa(l.count)
+}
+
+// max returns the number of libraries with unknown licenses tolerated among total libraries.
+func (l unknownLimit) max(total int) int {
+	if l.relative {
+		return int(l.percent * float64(total) / 100)
+	}
+	return l.count
+}
+
+var (
+	// configPath is the path of the JSON config file, if any.
+	configPath string
+	// cfg is the loaded config. It is the zero value when no config file is used.
+	cfg config
+)
+
+// addConfigFlags adds the config flags shared by all commands to flags.
+func addConfigFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&configPath, "config", "", "Path to a JSON config file with additional settings, e.g. per-license confidence thresholds.")
+}
+
+// loadConfig reads and parses the JSON config file at path.
+func loadConfig(path string) (config, error) {
+	var c config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("reading config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := validateModuleOverrides(c.ModuleOverrides); err != nil {
+		return c, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := validateExceptions(c.PolicyExceptions); err != nil {
+		return c, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := validateHTTPHeaders(c.HTTPHeaders); err != nil {
+		return c, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := importPolicies(&c, filepath.Dir(path)); err != nil {
+		return c, fmt.Errorf("importing policies into config %s: %w", path, err)
+	}
+	for _, h := range c.CodeHosts {
+		if err := h.Validate(); err != nil {
+			return c, fmt.Errorf("parsing config %s: codeHosts: %w", path, err)
+		}
+	}
+	return c, nil
+}
@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package cli
 
 import (
 	"github.com/spf13/cobra"
@@ -20,17 +20,17 @@ import (
 
 var (
 	csvHelp = "Prints all licenses that apply to one or more Go packages and their dependencies. (Deprecated: use report instead)"
-	csvCmd  = &cobra.Command{
+)
+
+// newCSVCmd returns the csv command.
+func newCSVCmd() *cobra.Command {
+	return &cobra.Command{
 		Use:   "csv <package> [package...]",
 		Short: csvHelp,
 		Long:  csvHelp + packageHelp,
 		Args:  cobra.MinimumNArgs(1),
 		RunE:  csvMain,
 	}
-)
-
-func init() {
-	rootCmd.AddCommand(csvCmd)
 }
 
 func csvMain(_ *cobra.Command, args []string) error {
@@ -0,0 +1,293 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// cdxNamespace is the XML namespace of CycloneDX 1.5 documents.
+const cdxNamespace = "http://cyclonedx.org/schema/bom/1.5"
+
+// cdxBOM is a CycloneDX 1.5 bill of materials, with the field names of both its JSON
+// and its XML format.
+type cdxBOM struct {
+	XMLName      xml.Name        `json:"-" xml:"http://cyclonedx.org/schema/bom/1.5 bom"`
+	BOMFormat    string          `json:"bomFormat" xml:"-"`
+	SpecVersion  string          `json:"specVersion" xml:"-"`
+	SerialNumber string          `json:"serialNumber" xml:"serialNumber,attr"`
+	Version      int             `json:"version" xml:"version,attr"`
+	Metadata     cdxMetadata     `json:"metadata" xml:"metadata"`
+	Components   []cdxComponent  `json:"components" xml:"components>component"`
+	Dependencies cdxDependencies `json:"dependencies,omitempty" xml:"dependencies,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp" xml:"timestamp"`
+	Tools     cdxTools      `json:"tools" xml:"tools"`
+	Component *cdxComponent `json:"component,omitempty" xml:"component,omitempty"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components" xml:"components>component"`
+}
+
+type cdxComponent struct {
+	Type               string          `json:"type" xml:"type,attr"`
+	BOMRef             string          `json:"bom-ref,omitempty" xml:"bom-ref,attr,omitempty"`
+	Name               string          `json:"name" xml:"name"`
+	Version            string          `json:"version,omitempty" xml:"version,omitempty"`
+	Licenses           cdxLicenses     `json:"licenses,omitempty" xml:"licenses,omitempty"`
+	PURL               string          `json:"purl,omitempty" xml:"purl,omitempty"`
+	ExternalReferences cdxExternalRefs `json:"externalReferences,omitempty" xml:"externalReferences,omitempty"`
+	Properties         cdxProperties   `json:"properties,omitempty" xml:"properties,omitempty"`
+}
+
+// cdxLicenses is the licenses of a component: either licenses or a single expression.
+type cdxLicenses []cdxLicenseChoice
+
+type cdxLicenseChoice struct {
+	License    *cdxLicense `json:"license,omitempty"`
+	Expression string      `json:"expression,omitempty"`
+}
+
+type cdxLicense struct {
+	ID  string `json:"id" xml:"id"`
+	URL string `json:"url,omitempty" xml:"url,omitempty"`
+}
+
+// cdxExternalRefs, cdxProperties and cdxDependencies are lists that are left out of the
+// XML format, including their parent element, when empty.
+type cdxExternalRefs []cdxExternalRef
+
+type cdxProperties []cdxProperty
+
+type cdxDependencies []cdxDependency
+
+type cdxExternalRef struct {
+	Type string `json:"type" xml:"type,attr"`
+	URL  string `json:"url" xml:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name" xml:"name,attr"`
+	Value string `json:"value" xml:",chardata"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// MarshalXML writes the licenses as children of a single licenses element, which the
+// XML format requires in place of the JSON array of choices.
+func (ls cdxLicenses) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, l := range ls {
+		var err error
+		if l.License != nil {
+			err = e.EncodeElement(l.License, xml.StartElement{Name: xml.Name{Local: "license"}})
+		} else {
+			err = e.EncodeElement(l.Expression, xml.StartElement{Name: xml.Name{Local: "expression"}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (rs cdxExternalRefs) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	items := make([]interface{}, len(rs))
+	for i, r := range rs {
+		items[i] = r
+	}
+	return encodeXMLList(e, start, "reference", items)
+}
+
+func (ps cdxProperties) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	items := make([]interface{}, len(ps))
+	for i, p := range ps {
+		items[i] = p
+	}
+	return encodeXMLList(e, start, "property", items)
+}
+
+func (ds cdxDependencies) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	items := make([]interface{}, len(ds))
+	for i, d := range ds {
+		items[i] = d
+	}
+	return encodeXMLList(e, start, "dependency", items)
+}
+
+// encodeXMLList writes items as elements named name in the element start.
+func encodeXMLList(e *xml.Encoder, start xml.StartElement, name string, items []interface{}) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := e.EncodeElement(item, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML writes the dependency with its ref attribute and a nested dependency
+// element for each ref it depends on.
+func (d cdxDependency) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "ref"}, Value: d.Ref})
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, ref := range d.DependsOn {
+		if err := e.EncodeElement(cdxDependency{Ref: ref}, xml.StartElement{Name: xml.Name{Local: "dependency"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// reportCycloneDX prints libs as a CycloneDX 1.5 bill of materials, in JSON format for
+// --format=cyclonedx and in XML format for --format=cyclonedx-xml. Each library becomes
+// a component, and the main module, if known, depends on all of them.
+func reportCycloneDX(metadata runMetadata, libs []libraryData) error {
+	uuid, err := newUUID()
+	if err != nil {
+		return fmt.Errorf("generating CycloneDX serial number: %w", err)
+	}
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: metadata.Timestamp.Format("2006-01-02T15:04:05Z"),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "go-licenses",
+				Version: metadata.ToolVersion,
+			}}},
+		},
+		Components: []cdxComponent{},
+	}
+	refs := make(map[string]bool)
+	var deps []string
+	for _, lib := range libs {
+		c := cdxComponent{
+			Type:     "library",
+			Name:     lib.Name,
+			Licenses: cdxComponentLicenses(lib),
+		}
+		if lib.module != nil {
+			c.PURL = libraryPackageURL(lib)
+			if lib.module.Version != "" {
+				c.Version = spdxModuleVersion(lib.module)
+			}
+			c.Properties = cdxProvenance(lib)
+		}
+		ref := c.PURL
+		if ref == "" {
+			ref = lib.Name
+		}
+		c.BOMRef = ref
+		for i := 2; refs[c.BOMRef]; i++ {
+			c.BOMRef = fmt.Sprintf("%s-%d", ref, i)
+		}
+		refs[c.BOMRef] = true
+		if lib.Homepage != "" {
+			c.ExternalReferences = append(c.ExternalReferences, cdxExternalRef{Type: "website", URL: lib.Homepage})
+		}
+		if lib.LicenseURL != "" && lib.LicenseURL != UNKNOWN {
+			c.ExternalReferences = append(c.ExternalReferences, cdxExternalRef{Type: "license", URL: lib.LicenseURL})
+		}
+		bom.Components = append(bom.Components, c)
+		deps = append(deps, c.BOMRef)
+	}
+	if metadata.RootModule != "" {
+		root := &cdxComponent{
+			Type:   "application",
+			BOMRef: "pkg:golang/" + metadata.RootModule,
+			Name:   metadata.RootModule,
+			PURL:   "pkg:golang/" + metadata.RootModule,
+		}
+		bom.Metadata.Component = root
+		bom.Dependencies = []cdxDependency{{Ref: root.BOMRef, DependsOn: deps}}
+	}
+	if outputFormat == "cyclonedx-xml" {
+		if _, err := fmt.Fprint(out, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(out)
+		enc.Indent("", "  ")
+		if err := enc.Encode(bom); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(out)
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+// cdxComponentLicenses returns the licenses of lib: a license with the URL of its text
+// for a single license, or an expression for a combination of licenses.
+func cdxComponentLicenses(lib libraryData) cdxLicenses {
+	license := spdxLicense(lib.LicenseName)
+	switch {
+	case license == spdxNoAssertion:
+		return nil
+	case strings.ContainsAny(license, " ()"):
+		return cdxLicenses{{Expression: license}}
+	}
+	l := &cdxLicense{ID: license}
+	if lib.LicenseURL != UNKNOWN {
+		l.URL = lib.LicenseURL
+	}
+	return cdxLicenses{{License: l}}
+}
+
+// cdxProvenance returns the properties recording whether the go command verifies the
+// module of lib against the checksum database.
+func cdxProvenance(lib libraryData) cdxProperties {
+	ps := cdxProperties{{Name: "go-licenses:origin", Value: lib.Origin}}
+	if lib.ChecksumDatabase != "" {
+		ps = append(ps, cdxProperty{Name: "go-licenses:checksumDatabase", Value: lib.ChecksumDatabase})
+	}
+	if lib.ChecksumExclusion != "" {
+		ps = append(ps, cdxProperty{Name: "go-licenses:checksumExclusion", Value: lib.ChecksumExclusion})
+	}
+	return ps
+}
+
+// libraryPackageURL returns the package URL of lib: the package URL of its module, with
+// the library's directory in the module as subpath.
+func libraryPackageURL(lib libraryData) string {
+	purl := packageURL(lib.module)
+	if lib.module.Version == "" {
+		purl = "pkg:golang/" + lib.module.Path
+	}
+	if sub := strings.TrimPrefix(lib.Name, lib.module.Path+"/"); sub != lib.Name {
+		purl += "#" + sub
+	}
+	return purl
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// dep5Format is the URI identifying the machine-readable debian/copyright format.
+const dep5Format = "https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/"
+
+// reportDEP5 prints libs as a machine-readable debian/copyright file: a Files paragraph
+// per library, the main module's covering "*" and dependencies covering their directory
+// below vendor/, followed by a License paragraph with the text of each license.
+func reportDEP5(metadata runMetadata, libs []libraryData) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "Format: %s\n", dep5Format)
+	if metadata.RootModule != "" {
+		fmt.Fprintf(w, "Upstream-Name: %s\n", metadata.RootModule)
+	}
+	// Main module libraries come first, as later Files paragraphs take precedence.
+	sorted := append([]libraryData(nil), libs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return isMain(sorted[i]) && !isMain(sorted[j]) })
+	texts := make(map[string]string)
+	var names []string
+	for _, lib := range sorted {
+		files := "vendor/" + lib.Name + "/*"
+		if isMain(lib) {
+			files = "*"
+		}
+		name := dep5LicenseName(lib.LicenseName)
+		fmt.Fprintf(w, "\nFiles: %s\n", files)
+		fmt.Fprintf(w, "Copyright:%s\n", dep5Field(copyrights(lib.LicensePath, lib.Notice)))
+		fmt.Fprintf(w, "License: %s\n", name)
+		if _, ok := texts[name]; !ok {
+			names = append(names, name)
+			texts[name] = ""
+		}
+		if texts[name] == "" && lib.LicensePath != "" {
+			if b, err := os.ReadFile(lib.LicensePath); err != nil {
+				klog.Errorf("Error reading license file %q: %v", lib.LicensePath, err)
+			} else {
+				texts[name] = string(b)
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\nLicense: %s\n", name)
+		if text := texts[name]; text != "" {
+			fmt.Fprint(w, dep5Text(text))
+		}
+	}
+	return w.Flush()
+}
+
+// isMain reports whether lib belongs to the main module.
+func isMain(lib libraryData) bool {
+	return lib.module != nil && lib.module.Main
+}
+
+// copyrights returns the copyright statements in the license file at path and in the
+// text of its NOTICE file, or "Unknown" if there are none.
+func copyrights(path, notice string) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	texts := []string{notice}
+	if path != "" {
+		if b, err := os.ReadFile(path); err == nil {
+			texts = append([]string{string(b)}, texts...)
+		}
+	}
+	for _, text := range texts {
+		for _, line := range copyrightStatements(text) {
+			if !seen[line] {
+				seen[line] = true
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return []string{UNKNOWN}
+	}
+	return lines
+}
+
+// dep5Field formats the lines of a multi-line field value after its name and colon.
+func dep5Field(lines []string) string {
+	return " " + strings.Join(lines, "\n ")
+}
+
+// dep5Text formats a license text as the continuation lines of a License field: each
+// line is indented by a space and empty lines are replaced by " .".
+func dep5Text(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			b.WriteString(" .\n")
+			continue
+		}
+		b.WriteString(" " + line + "\n")
+	}
+	return b.String()
+}
+
+// dep5LicenseNames maps SPDX license identifiers to the short names of the debian/copyright
+// format where they differ.
+var dep5LicenseNames = map[string]string{
+	"MIT":               "Expat",
+	"BSD-2-Clause":      "BSD-2-clause",
+	"BSD-3-Clause":      "BSD-3-clause",
+	"BSD-4-Clause":      "BSD-4-clause",
+	"GPL-2.0":           "GPL-2",
+	"GPL-2.0-only":      "GPL-2",
+	"GPL-2.0-or-later":  "GPL-2+",
+	"GPL-3.0":           "GPL-3",
+	"GPL-3.0-only":      "GPL-3",
+	"GPL-3.0-or-later":  "GPL-3+",
+	"LGPL-2.1":          "LGPL-2.1",
+	"LGPL-2.1-only":     "LGPL-2.1",
+	"LGPL-2.1-or-later": "LGPL-2.1+",
+	"LGPL-3.0":          "LGPL-3",
+	"LGPL-3.0-only":     "LGPL-3",
+	"LGPL-3.0-or-later": "LGPL-3+",
+	"AGPL-3.0":          "AGPL-3",
+	"AGPL-3.0-only":     "AGPL-3",
+	"AGPL-3.0-or-later": "AGPL-3+",
+}
+
+// dep5LicenseName returns the debian/copyright short name of the SPDX license expression
+// name. Operators, including the WITH of exceptions, are lowercased, as the format
+// requires.
+func dep5LicenseName(name string) string {
+	var parts []string
+	for _, token := range strings.Fields(name) {
+		switch token {
+		case "AND", "OR", "WITH":
+			parts = append(parts, strings.ToLower(token))
+		default:
+			id := strings.Trim(token, "()")
+			if short, ok := dep5LicenseNames[id]; ok {
+				token = strings.Replace(token, id, short, 1)
+			}
+			parts = append(parts, token)
+		}
+	}
+	return strings.Join(parts, " ")
+}
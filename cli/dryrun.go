@@ -0,0 +1,118 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+// saveDryRunMain saves libs to a temporary directory and prints how savePath would change.
+func saveDryRunMain(classifier licenses.Classifier, libs []*licenses.Library, categories map[licenses.Type]bool) error {
+	tmp, err := os.MkdirTemp("", "go-licenses-save")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := saveLibraries(classifier, libs, categories, tmp); err != nil {
+		return err
+	}
+	want, err := treeHashes(tmp)
+	if err != nil {
+		return err
+	}
+	got, err := treeHashes(savePath)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for p := range want {
+		paths = append(paths, p)
+	}
+	for p := range got {
+		if _, ok := want[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	var created, updated, deleted int
+	for _, p := range paths {
+		w, inWant := want[p]
+		g, inGot := got[p]
+		switch {
+		case !inGot:
+			created++
+			fmt.Fprintf(out, "create %s\n", p)
+		case !inWant:
+			deleted++
+			fmt.Fprintf(out, "delete %s\n", p)
+		case w != g:
+			updated++
+			fmt.Fprintf(out, "update %s\n", p)
+		}
+	}
+	fmt.Fprintf(out, "%d to create, %d to update, %d to delete\n", created, updated, deleted)
+	return nil
+}
+
+// treeHashes returns the SHA-256 of every file below dir, keyed by the slash-separated
+// path relative to dir. It returns an empty map if dir doesn't exist.
+func treeHashes(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return hashes, err
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+var (
+	enrichHelp = "Adds classified license data to the Go components of an existing SBOM."
+)
+
+// newEnrichCmd returns the enrich command.
+func newEnrichCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "enrich <sbom.json>",
+		Short: enrichHelp,
+		Long: enrichHelp + `
+
+The SBOM is an SPDX or CycloneDX document in JSON format, e.g. produced by syft or tern.
+Components with a pkg:golang package URL are downloaded to the module cache and their
+license is classified: CycloneDX components get the license ID or expression with the
+license text, and SPDX packages get the concluded license, as well as the declared
+license if the SBOM has none. All other components and fields are left unchanged. The
+enriched SBOM is printed to stdout.`,
+		Args: cobra.ExactArgs(1),
+		RunE: enrichMain,
+	}
+}
+
+// enrichedLicense is the license classified for a Go component.
+type enrichedLicense struct {
+	name string
+	text string
+}
+
+// enricher classifies the licenses of Go components, once per module version and
+// directory.
+type enricher struct {
+	ctx        context.Context
+	classifier licenses.Classifier
+	cache      map[string]*enrichedLicense
+	components int
+	enriched   int
+}
+
+func enrichMain(_ *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Numbers are kept as written, e.g. the CycloneDX version.
+	dec.UseNumber()
+	var sbom map[string]interface{}
+	if err := dec.Decode(&sbom); err != nil {
+		return fmt.Errorf("parsing SBOM %q: %w", args[0], err)
+	}
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	e := &enricher{
+		ctx:        runContext(),
+		classifier: classifier,
+		cache:      make(map[string]*enrichedLicense),
+	}
+	switch {
+	case sbom["bomFormat"] == "CycloneDX":
+		e.enrichCycloneDX(sbom["components"])
+	case sbom["spdxVersion"] != nil:
+		e.enrichSPDX(sbom["packages"])
+	default:
+		return fmt.Errorf("%q is neither an SPDX nor a CycloneDX JSON document", args[0])
+	}
+	if skipped := e.components - e.enriched; skipped > 0 {
+		diagnosticf(colorYellow, "%d of %d Go components could not be enriched, see the warnings above", skipped, e.components)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sbom)
+}
+
+// enrichCycloneDX sets the licenses of the Go components in the CycloneDX component list
+// components, including nested components.
+func (e *enricher) enrichCycloneDX(components interface{}) {
+	list, _ := components.([]interface{})
+	for _, item := range list {
+		c, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		e.enrichCycloneDX(c["components"])
+		purl, _ := c["purl"].(string)
+		l := e.license(purl)
+		if l == nil {
+			continue
+		}
+		if strings.ContainsAny(l.name, " ()") {
+			c["licenses"] = []interface{}{map[string]interface{}{"expression": l.name}}
+			continue
+		}
+		license := map[string]interface{}{"id": l.name}
+		if l.text != "" {
+			license["text"] = map[string]interface{}{"contentType": "text/plain", "content": l.text}
+		}
+		c["licenses"] = []interface{}{map[string]interface{}{"license": license}}
+	}
+}
+
+// enrichSPDX sets the licenses of the Go packages in the SPDX package list packages,
+// which are identified by a purl external reference.
+func (e *enricher) enrichSPDX(packages interface{}) {
+	list, _ := packages.([]interface{})
+	for _, item := range list {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refs, _ := p["externalRefs"].([]interface{})
+		for _, item := range refs {
+			ref, ok := item.(map[string]interface{})
+			if !ok || ref["referenceType"] != "purl" {
+				continue
+			}
+			purl, _ := ref["referenceLocator"].(string)
+			l := e.license(purl)
+			if l == nil {
+				continue
+			}
+			p["licenseConcluded"] = l.name
+			if declared, _ := p["licenseDeclared"].(string); declared == "" || declared == spdxNoAssertion {
+				p["licenseDeclared"] = l.name
+			}
+			break
+		}
+	}
+}
+
+// license returns the license of the Go component with package URL purl. It returns nil
+// if purl isn't a versioned Go package URL or the license can't be classified.
+func (e *enricher) license(purl string) *enrichedLicense {
+	modulePath, version, subdir, ok := parseGoPackageURL(purl)
+	if !ok {
+		return nil
+	}
+	e.components++
+	key := modulePath + "@" + version + "#" + subdir
+	l, ok := e.cache[key]
+	if !ok {
+		l = e.classify(modulePath, version, subdir)
+		e.cache[key] = l
+	}
+	if l != nil {
+		e.enriched++
+	}
+	return l
+}
+
+func (e *enricher) classify(modulePath, version, subdir string) *enrichedLicense {
+	path, err := licenses.ModuleLicensePath(e.ctx, e.classifier, modulePath, version, subdir)
+	if err != nil {
+		klog.Warningf("Skipping %s@%s: %v", modulePath, version, err)
+		return nil
+	}
+	name, _, err := e.classifier.Identify(path)
+	if err != nil || name == "" {
+		klog.Warningf("Skipping %s@%s: cannot classify license file %q: %v", modulePath, version, path, err)
+		return nil
+	}
+	l := &enrichedLicense{name: name}
+	if text, err := os.ReadFile(path); err == nil {
+		l.text = string(text)
+	}
+	return l
+}
+
+// parseGoPackageURL returns the module path, version and subpath of a pkg:golang package
+// URL. It returns false for other package URLs and Go package URLs without a version.
+func parseGoPackageURL(purl string) (modulePath, version, subpath string, ok bool) {
+	rest := strings.TrimPrefix(purl, "pkg:golang/")
+	if rest == purl {
+		return "", "", "", false
+	}
+	if i := strings.Index(rest, "#"); i >= 0 {
+		rest, subpath = rest[:i], rest[i+1:]
+	}
+	if i := strings.Index(rest, "?"); i >= 0 {
+		rest = rest[:i]
+	}
+	i := strings.LastIndex(rest, "@")
+	if i < 0 {
+		return "", "", "", false
+	}
+	modulePath, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return "", "", "", false
+	}
+	version, err = url.PathUnescape(rest[i+1:])
+	if err != nil || version == "" || version == "(devel)" {
+		return "", "", "", false
+	}
+	subpath, err = url.PathUnescape(subpath)
+	if err != nil {
+		return "", "", "", false
+	}
+	return modulePath, version, subpath, true
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// eventsFormat selects the format of the scan events stream, if any.
+	eventsFormat string
+	// eventsPath is the file that events are written to, stderr if empty.
+	eventsPath string
+
+	// events is the stream of scan events, nil if --events is not set.
+	events *eventWriter
+)
+
+// addEventsFlags adds the events flags shared by all commands to flags.
+func addEventsFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&eventsFormat, "events", "", "Stream scan lifecycle events, e.g. to show the progress of long scans. The only format is ndjson: one JSON object per line.")
+	flags.StringVar(&eventsPath, "events_output", "", "File to write --events to, e.g. /dev/fd/3 for an inherited file descriptor. (default: stderr)")
+}
+
+// Types of scan events.
+const (
+	eventScanStarted   = "scan_started"
+	eventModuleStarted = "module_started"
+	eventClassified    = "classified"
+	eventURLResolved   = "url_resolved"
+	eventWarning       = "warning"
+	eventScanFinished  = "scan_finished"
+)
+
+// event is a scan lifecycle event. Fields that don't apply to its type are omitted.
+type event struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Command is the subcommand run, and Args its arguments, for scan_started.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Module  string   `json:"module,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Library string   `json:"library,omitempty"`
+	// License is the identified license name and LicenseType its type, for classified.
+	License     string `json:"license,omitempty"`
+	LicenseType string `json:"licenseType,omitempty"`
+	URL         string `json:"url,omitempty"`
+	// Severity is the log severity of a warning: warning, error or fatal.
+	Severity string `json:"severity,omitempty"`
+	// Message is the text of a warning, or the error that ended the scan.
+	Message string `json:"message,omitempty"`
+}
+
+// eventWriter writes events as NDJSON. It is safe for concurrent use.
+type eventWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// startEvents opens the --events stream and emits scan_started. It returns a function
+// that closes the stream and restores klog, so that later runs in the process neither
+// write to the closed stream nor lose their logs.
+func startEvents(command string, args []string) (func() error, error) {
+	if eventsFormat == "" {
+		if eventsPath != "" {
+			return nil, fmt.Errorf("--events_output requires --events")
+		}
+		return func() error { return nil }, nil
+	}
+	if eventsFormat != "ndjson" {
+		return nil, fmt.Errorf("unknown events format %q, want ndjson", eventsFormat)
+	}
+	var w io.Writer = os.Stderr
+	closeFile := func() error { return nil }
+	if eventsPath != "" {
+		f, err := os.Create(eventsPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening events output: %w", err)
+		}
+		w = f
+		closeFile = f.Close
+	}
+	events = &eventWriter{w: w, enc: json.NewEncoder(w)}
+	state := klog.CaptureState()
+	// Copy warnings and errors logged with klog to the stream. klog writes messages of
+	// a severity to the outputs of all lower severities, so only the WARNING output
+	// receives each message exactly once. Everything is still logged to stderr.
+	klog.LogToStderr(false)
+	klog.SetOutputBySeverity("INFO", io.Discard)
+	klog.SetOutputBySeverity("WARNING", klogEvents{})
+	klog.SetOutputBySeverity("ERROR", io.Discard)
+	klog.SetOutputBySeverity("FATAL", io.Discard)
+	emit(event{Event: eventScanStarted, Command: command, Args: args})
+	return func() error {
+		state.Restore()
+		events = nil
+		return closeFile()
+	}, nil
+}
+
+// finishEvents emits scan_finished with the error that ended the scan, if any.
+func finishEvents(err error) {
+	e := event{Event: eventScanFinished}
+	if err != nil {
+		e.Message = err.Error()
+	}
+	emit(e)
+}
+
+// emit writes e to the --events stream, if any.
+func emit(e event) {
+	if events == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if err := events.enc.Encode(e); err != nil {
+		// Not logged with klog, which would emit it again.
+		fmt.Fprintf(os.Stderr, "Error writing event: %v\n", err)
+	}
+}
+
+// emitModuleStarted emits module_started for m.
+func emitModuleStarted(m *licenses.Module) {
+	emit(event{Event: eventModuleStarted, Module: m.Path, Version: m.Version})
+}
+
+// emitClassified emits classified for the license of lib.
+func emitClassified(lib *licenses.Library, name string, typ licenses.Type) {
+	emit(event{Event: eventClassified, Library: lib.Name(), License: name, LicenseType: typ.String()})
+}
+
+// klogEvents turns the messages that klog writes into warning events.
+type klogEvents struct{}
+
+func (klogEvents) Write(b []byte) (int, error) {
+	line := strings.TrimRight(string(b), "\n")
+	e := event{Event: eventWarning, Severity: "warning", Message: line}
+	// The klog header is "Lmmdd hh:mm:ss.uuuuuu threadid file:line] ".
+	if i := strings.Index(line, "] "); i >= 0 {
+		e.Message = line[i+2:]
+		switch line[0] {
+		case 'E':
+			e.Severity = "error"
+		case 'F':
+			e.Severity = "fatal"
+		}
+	}
+	emit(e)
+	return len(b), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestCloseEventsRestoresKlog(t *testing.T) {
+	defer klog.CaptureState().Restore()
+	defer func(format, path string) { eventsFormat, eventsPath = format, path }(eventsFormat, eventsPath)
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+	eventsFormat, eventsPath = "ndjson", filepath.Join(t.TempDir(), "events.ndjson")
+
+	closeEvents, err := startEvents("check", []string{"."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	klog.Warning("logged during the run")
+	if err := closeEvents(); err != nil {
+		t.Fatal(err)
+	}
+	if events != nil {
+		t.Errorf("events = %v after closing the stream, want nil", events)
+	}
+	klog.Warning("logged after the run")
+	klog.Flush()
+	if got := logs.String(); strings.Contains(got, "during the run") || !strings.Contains(got, "after the run") {
+		t.Errorf("klog output = %q, want only the warning logged after the run", got)
+	}
+	if b, err := os.ReadFile(eventsPath); err != nil || !strings.Contains(string(b), "logged during the run") || strings.Contains(string(b), "after the run") {
+		t.Errorf("events = (%q, %v), want only the warning logged during the run", b, err)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainHelp = "Prints everything known about the license of one dependency of one or more Go packages."
+)
+
+// newExplainCmd returns the explain command.
+func newExplainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain <module> <package> [package...]",
+		Short: explainHelp,
+		Long: explainHelp + `
+
+<module> is the module path or import path of the dependency to explain. The output
+includes its version and directory, the license files found, the classification
+with confidence, how the license URL was resolved and the import chain through which
+the dependency is used.` + packageHelp,
+		Args: cobra.MinimumNArgs(2),
+		RunE: explainMain,
+	}
+}
+
+func explainMain(_ *cobra.Command, args []string) error {
+	target, pkgs := args[0], args[1:]
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	libs, err := libraries(runContext(), classifier, pkgs)
+	if err != nil {
+		return err
+	}
+	var matched []*licenses.Library
+	for _, lib := range libs {
+		if explainMatches(lib, target) {
+			matched = append(matched, lib)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("%s is not a dependency of %s", target, strings.Join(pkgs, " "))
+	}
+	for i, lib := range matched {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := explainLibrary(out, classifier, lib); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explainMatches reports whether lib belongs to the module or contains the package target.
+func explainMatches(lib *licenses.Library, target string) bool {
+	if m := lib.Module(); m != nil && m.Path == target {
+		return true
+	}
+	for _, pkg := range lib.Packages {
+		if pkg == target {
+			return true
+		}
+	}
+	return lib.Name() == target
+}
+
+func explainLibrary(w io.Writer, classifier licenses.Classifier, lib *licenses.Library) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Library: %s\n", lib.Name())
+	m := lib.Module()
+	if m != nil {
+		fmt.Fprintf(&b, "  Module: %s\n", m.Path)
+		fmt.Fprintf(&b, "  Version: %s\n", valueOr(m.Version, "(none, main module or local replacement)"))
+		fmt.Fprintf(&b, "  Directory: %s\n", valueOr(m.Dir, "(unknown, vendored module)"))
+		if at := requiredAt(lib); at != nil {
+			fmt.Fprintf(&b, "  Required at: %s:%d\n", at.File, at.Line)
+		}
+	} else {
+		fmt.Fprintf(&b, "  Module: (unknown)\n")
+	}
+	fmt.Fprintf(&b, "  Packages:\n")
+	for _, pkg := range lib.Packages {
+		fmt.Fprintf(&b, "    %s\n", pkg)
+	}
+
+	fmt.Fprintf(&b, "License files:\n")
+	if lib.LicensePath == "" {
+		fmt.Fprintf(&b, "  (none found)\n")
+		for _, c := range lib.LicenseCandidates {
+			fmt.Fprintf(&b, "  candidate: %s\n", c)
+		}
+	} else {
+		fmt.Fprintf(&b, "  %s\n", lib.LicensePath)
+	}
+	for _, path := range lib.ReuseLicensePaths {
+		if path != lib.LicensePath {
+			fmt.Fprintf(&b, "  %s\n", path)
+		}
+	}
+
+	fmt.Fprintf(&b, "Classification:\n")
+	if o, ok := licenseOverrideFor(lib); ok {
+		fmt.Fprintf(&b, "  overridden by --license_overrides entry for %s: %s\n", o.Module, o.License)
+		if o.Reason != "" {
+			fmt.Fprintf(&b, "  reason: %s\n", o.Reason)
+		}
+	} else if len(lib.ReuseLicenses) > 0 {
+		fmt.Fprintf(&b, "  declared via REUSE SPDX tags: %s\n", strings.Join(lib.ReuseLicenses, ", "))
+	} else if lib.LicensePath != "" {
+		matches, err := licenses.Matches(classifier, lib.LicensePath)
+		if err != nil {
+			fmt.Fprintf(&b, "  error: %v\n", err)
+		}
+		for _, match := range matches {
+			verdict := "below threshold"
+			if match.Accepted {
+				verdict = "accepted"
+			}
+			fmt.Fprintf(&b, "  %s (%s): confidence %.3f, %s\n", match.Name, match.Type, match.Confidence, verdict)
+		}
+		if hasLicenseChoice(lib) {
+			fmt.Fprintf(&b, "  offered under a choice of: %s\n", strings.Join(lib.LicenseChoices, ", "))
+		}
+	}
+	name, typ := identifyLicense(classifier, lib)
+	fmt.Fprintf(&b, "  result: %s (%s)\n", name, typ)
+	if len(lib.FileLicenses) > 0 {
+		fmt.Fprintf(&b, "File licenses:\n")
+		for _, fl := range lib.FileLicenses {
+			fmt.Fprintf(&b, "  %s: %s\n", pathInModule(lib, fl.Path), fl.License)
+		}
+	}
+
+	if o, ok := licenseOverrideFor(lib); ok && o.URL != "" {
+		fmt.Fprintf(&b, "License URL:\n")
+		fmt.Fprintf(&b, "  url: %s, from the license override\n", o.URL)
+	} else if lib.LicensePath != "" {
+		fmt.Fprintf(&b, "License URL:\n")
+		if m != nil && m.Dir != "" {
+			if rel, err := filepath.Rel(m.Dir, lib.LicensePath); err == nil {
+				fmt.Fprintf(&b, "  path in module: %s\n", filepath.ToSlash(rel))
+			}
+			if m.Version == "" {
+				fmt.Fprintf(&b, "  no module version, the URL points to HEAD of the default branch\n")
+			}
+		}
+		if url, err := lib.FileURL(runContext(), lib.LicensePath); err != nil {
+			fmt.Fprintf(&b, "  error: %v\n", err)
+		} else {
+			fmt.Fprintf(&b, "  url: %s\n", url)
+		}
+	}
+
+	fmt.Fprintf(&b, "Import chain:\n")
+	if len(lib.DependencyPath) > 0 {
+		fmt.Fprintf(&b, "  %s\n", strings.Join(lib.DependencyPath, "\n  -> "))
+	} else {
+		fmt.Fprintf(&b, "  (not found)\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
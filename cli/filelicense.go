@@ -0,0 +1,102 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+// warningFileLicense is emitted for libraries with source files whose SPDX header
+// declares another license than the library's, see --scan_file_licenses.
+const warningFileLicense = licenses.WarningKind("file-license")
+
+// fileLicenseData is a source file whose header declares another license than its
+// library, see --scan_file_licenses.
+type fileLicenseData struct {
+	// Path is the slash-separated path of the file relative to the module root.
+	Path string `json:"path"`
+	// License is the SPDX license expression declared in the file's header.
+	License string `json:"license"`
+	URL     string `json:"url,omitempty"`
+}
+
+// fileLicenseMismatches returns the licenses declared in the headers of the files of lib
+// that libLicenses don't cover, e.g. the GPL tag of a file copied into an MIT-licensed
+// module. All of them are returned for libraries with an unknown license.
+func fileLicenseMismatches(lib *licenses.Library, libLicenses []license) []licenses.FileLicense {
+	var names []string
+	for _, l := range libLicenses {
+		if l.name != UNKNOWN {
+			names = append(names, l.name)
+		}
+	}
+	libExpr := strings.Join(names, " OR ")
+	var mismatches []licenses.FileLicense
+	for _, fl := range lib.FileLicenses {
+		if !licenses.ExpressionCoveredBy(fl.License, libExpr) {
+			mismatches = append(mismatches, fl)
+		}
+	}
+	return mismatches
+}
+
+// fileLicensesData returns the report data of mismatches, the file licenses of lib
+// returned by fileLicenseMismatches, and warns about them.
+func fileLicensesData(ctx context.Context, lib *licenses.Library, licenseName string, mismatches []licenses.FileLicense) []fileLicenseData {
+	if len(mismatches) == 0 {
+		return nil
+	}
+	var data []fileLicenseData
+	for _, fl := range mismatches {
+		f := fileLicenseData{Path: pathInModule(lib, fl.Path), License: fl.License}
+		if url, err := fileURL(ctx, lib, fl.Path); err == nil {
+			f.URL = url
+		}
+		data = append(data, f)
+	}
+	warnf(warningFileLicense, libModulePath(lib), "%s", fileLicenseMessage(lib.Name(), licenseName, data))
+	return data
+}
+
+// pathInModule returns the slash-separated path of the file at path relative to the
+// module root of lib, or path itself if lib has no module directory.
+func pathInModule(lib *licenses.Library, path string) string {
+	if m := lib.Module(); m != nil && m.Dir != "" {
+		if rel, err := filepath.Rel(m.Dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// fileLicenseMessage describes the files of library whose headers declare another
+// license than licenseName.
+func fileLicenseMessage(library, licenseName string, files []fileLicenseData) string {
+	const maxListed = 3
+	var listed []string
+	for i, f := range files {
+		if i == maxListed {
+			listed = append(listed, fmt.Sprintf("and %d more", len(files)-maxListed))
+			break
+		}
+		listed = append(listed, fmt.Sprintf("%s (%s)", f.Path, f.License))
+	}
+	return fmt.Sprintf("%d files of %s declare another license than %s in their SPDX header: %s", len(files), library, licenseName, strings.Join(listed, ", "))
+}
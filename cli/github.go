@@ -0,0 +1,75 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// githubToken authenticates requests to GitHub, see --github_token.
+var githubToken string
+
+// githubHosts are the hosts that requests are authenticated with githubToken for.
+var githubHosts = map[string]bool{
+	"github.com":                true,
+	"api.github.com":            true,
+	"raw.githubusercontent.com": true,
+}
+
+// withGitHubToken authenticates the HTTPS requests of base to GitHub with --github_token,
+// or the GITHUB_TOKEN environment variable if the flag isn't set, so that large runs
+// don't hit the rate limit of unauthenticated requests.
+func withGitHubToken(base http.RoundTripper) http.RoundTripper {
+	token := githubToken
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	return &githubTransport{token: token, base: base}
+}
+
+// githubTransport adds an Authorization header with token to the requests of base to
+// GitHub, and warns once when GitHub rate limits requests.
+type githubTransport struct {
+	token string
+	base  http.RoundTripper
+
+	warnOnce sync.Once
+}
+
+func (t *githubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	github := req.URL.Scheme == "https" && githubHosts[strings.ToLower(req.URL.Hostname())]
+	// An Authorization header set by the config's httpHeaders takes precedence.
+	if github && t.token != "" && req.Header.Get("Authorization") == "" {
+		// RoundTrippers must not modify the request.
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && github && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		t.warnOnce.Do(func() {
+			if t.token == "" {
+				klog.Warningf("GitHub rate limit exceeded for unauthenticated requests. Set GITHUB_TOKEN or --github_token to raise it.")
+			} else {
+				klog.Warningf("GitHub rate limit exceeded for the token of GITHUB_TOKEN or --github_token.")
+			}
+		})
+	}
+	return resp, err
+}
@@ -0,0 +1,214 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+// dependencyGraph is the package import graph printed by report --graph.
+type dependencyGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// graphNode is a package and the license that applies to it.
+type graphNode struct {
+	Package     string `json:"package"`
+	Library     string `json:"library"`
+	LicenseName string `json:"licenseName"`
+	LicenseType string `json:"licenseType"`
+}
+
+// graphEdge is an import of package To by package From.
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// licenseTypeSeverity orders license types from most to least restrictive.
+var licenseTypeSeverity = []licenses.Type{
+	licenses.Forbidden,
+	licenses.Restricted,
+	licenses.Reciprocal,
+	licenses.Unknown,
+	licenses.Notice,
+	licenses.Permissive,
+	licenses.Unencumbered,
+}
+
+// mostRestrictive returns the most restrictive of types, or Unknown if there are none.
+func mostRestrictive(types []licenses.Type) licenses.Type {
+	for _, t := range licenseTypeSeverity {
+		for _, typ := range types {
+			if typ == t {
+				return t
+			}
+		}
+	}
+	return licenses.Unknown
+}
+
+// leastRestrictive returns the least restrictive of types, or Unknown if there are none.
+func leastRestrictive(types []licenses.Type) licenses.Type {
+	for i := len(licenseTypeSeverity) - 1; i >= 0; i-- {
+		for _, typ := range types {
+			if typ == licenseTypeSeverity[i] {
+				return typ
+			}
+		}
+	}
+	return licenses.Unknown
+}
+
+// expressionType returns the type of an SPDX license expression. Licensees may choose
+// the least restrictive license of an OR expression, but must comply with the most
+// restrictive operand of an AND expression.
+func expressionType(expr string) licenses.Type {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr))
+	return tokensType(tokens)
+}
+
+func tokensType(tokens []string) licenses.Type {
+	if terms := splitTokens(tokens, "AND"); len(terms) > 1 {
+		var types []licenses.Type
+		for _, term := range terms {
+			types = append(types, tokensType(term))
+		}
+		return mostRestrictive(types)
+	}
+	if choices := splitTokens(tokens, "OR"); len(choices) > 1 {
+		var types []licenses.Type
+		for _, choice := range choices {
+			types = append(types, tokensType(choice))
+		}
+		return leastRestrictive(types)
+	}
+	if len(tokens) > 1 && tokens[0] == "(" && tokens[len(tokens)-1] == ")" {
+		return tokensType(tokens[1 : len(tokens)-1])
+	}
+	return licenses.LicenseType(strings.Join(tokens, " "))
+}
+
+// splitTokens splits tokens at the operator op outside of parentheses.
+func splitTokens(tokens []string, op string) [][]string {
+	var parts [][]string
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		switch tok {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case op:
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// dotColors are the fill colors of packages in DOT graphs by license type.
+var dotColors = map[licenses.Type]string{
+	licenses.Forbidden:    "red",
+	licenses.Restricted:   "orange",
+	licenses.Reciprocal:   "yellow",
+	licenses.Unknown:      "lightgray",
+	licenses.Notice:       "palegreen",
+	licenses.Permissive:   "palegreen",
+	licenses.Unencumbered: "palegreen",
+}
+
+func reportGraph(classifier licenses.Classifier, libs []*licenses.Library) error {
+	g := newDependencyGraph(classifier, libs)
+	switch graphFormat {
+	case "dot":
+		return writeDOT(out, g)
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	default:
+		return fmt.Errorf("unknown --graph %q, want one of: dot, json", graphFormat)
+	}
+}
+
+// newDependencyGraph returns the import graph of the packages in libs. Imports of
+// packages that are not part of any library, e.g. ignored ones, are left out.
+func newDependencyGraph(classifier licenses.Classifier, libs []*licenses.Library) dependencyGraph {
+	g := dependencyGraph{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	known := make(map[string]bool)
+	for _, lib := range libs {
+		name, typ := identifyLicense(classifier, lib)
+		for _, pkg := range lib.Packages {
+			known[pkg] = true
+			g.Nodes = append(g.Nodes, graphNode{
+				Package:     pkg,
+				Library:     lib.Name(),
+				LicenseName: name,
+				LicenseType: typ.String(),
+			})
+		}
+	}
+	for _, lib := range libs {
+		for _, pkg := range lib.Packages {
+			for _, imp := range lib.Imports[pkg] {
+				if known[imp] {
+					g.Edges = append(g.Edges, graphEdge{From: pkg, To: imp})
+				}
+			}
+		}
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Package < g.Nodes[j].Package })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+func writeDOT(w io.Writer, g dependencyGraph) error {
+	colors := make(map[string]string)
+	for t, c := range dotColors {
+		colors[t.String()] = c
+	}
+	if _, err := fmt.Fprintln(w, "digraph dependencies {\n  node [shape=box, style=filled];"); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		label := n.Package + "\n" + n.LicenseName
+		if _, err := fmt.Fprintf(w, "  %s [label=%s, fillcolor=%s];\n", strconv.Quote(n.Package), strconv.Quote(label), strconv.Quote(colors[n.LicenseType])); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// headerTransport sets the User-Agent configured in the config file on every request of
+// base, and the headers configured for a host on the requests to that host.
+type headerTransport struct {
+	userAgent string
+	// headers are the headers of each host, by lower-case host name.
+	headers map[string]http.Header
+	base    http.RoundTripper
+}
+
+// withHeaders makes the HTTP requests of base carry the config's userAgent, and those to
+// the hosts of httpHeaders the headers configured for them. Header values may refer to
+// environment variables, e.g. "Bearer ${PROXY_TOKEN}", to keep secrets out of the config
+// file.
+func withHeaders(base http.RoundTripper, c config) http.RoundTripper {
+	if c.UserAgent == "" && len(c.HTTPHeaders) == 0 {
+		return base
+	}
+	t := &headerTransport{userAgent: c.UserAgent, headers: make(map[string]http.Header), base: base}
+	for host, headers := range c.HTTPHeaders {
+		h := make(http.Header)
+		for name, value := range headers {
+			h.Set(name, os.ExpandEnv(value))
+		}
+		t.headers[strings.ToLower(host)] = h
+	}
+	return t
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := t.headers[strings.ToLower(req.URL.Host)]
+	if len(headers) > 0 && req.URL.Scheme != "https" {
+		// The headers typically carry credentials, which must not be sent in plain text.
+		return nil, fmt.Errorf("not contacting %s over %s, httpHeaders are only sent over https", req.URL.Host, req.URL.Scheme)
+	}
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// validateHTTPHeaders reports whether the keys of headers are hosts, e.g. proxy.internal
+// or proxy.internal:8443, rather than URLs.
+func validateHTTPHeaders(headers map[string]map[string]string) error {
+	for host := range headers {
+		if host == "" || strings.ContainsAny(host, "/@") {
+			return fmt.Errorf("httpHeaders: %q must be a host name, optionally with a port, without scheme or path", host)
+		}
+	}
+	return nil
+}
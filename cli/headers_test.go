@@ -0,0 +1,93 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"net/http"
+	"testing"
+)
+
+// roundTripFunc is an http.RoundTripper that answers requests with a function.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithHeaders(t *testing.T) {
+	t.Setenv("PROXY_TOKEN", "secret")
+	var got http.Header
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	transport := withHeaders(base, config{
+		UserAgent:   "test",
+		HTTPHeaders: map[string]map[string]string{"Proxy.Internal": {"Authorization": "Bearer ${PROXY_TOKEN}"}},
+	})
+
+	for _, tc := range []struct {
+		url      string
+		wantAuth string
+	}{
+		{"https://proxy.internal/mod/@v/list", "Bearer secret"},
+		{"https://example.org/LICENSE", ""},
+		{"https://proxy.internal:8443/LICENSE", ""},
+	} {
+		got = nil
+		req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip(%s) failed: %v", tc.url, err)
+		}
+		resp.Body.Close()
+		if auth := got.Get("Authorization"); auth != tc.wantAuth {
+			t.Errorf("request to %s has Authorization %q, want %q", tc.url, auth, tc.wantAuth)
+		}
+		if ua := got.Get("User-Agent"); ua != "test" {
+			t.Errorf("request to %s has User-Agent %q, want test", tc.url, ua)
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("RoundTrip(%s) modified the request", tc.url)
+		}
+	}
+
+	got = nil
+	req, err := http.NewRequest(http.MethodGet, "http://proxy.internal/mod/@v/list", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.RoundTrip(req); err == nil || got != nil {
+		t.Errorf("RoundTrip() of a plain http request to a host with httpHeaders = (_, %v), want it refused", err)
+	}
+}
+
+func TestValidateHTTPHeaders(t *testing.T) {
+	for host, wantErr := range map[string]bool{
+		"proxy.internal":          false,
+		"proxy.internal:8443":     false,
+		"https://proxy.internal":  true,
+		"proxy.internal/artifact": true,
+		"":                        true,
+	} {
+		err := validateHTTPHeaders(map[string]map[string]string{host: {"X-Token": "t"}})
+		if (err != nil) != wantErr {
+			t.Errorf("validateHTTPHeaders(%q) = %v, want error: %t", host, err, wantErr)
+		}
+	}
+}
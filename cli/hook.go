@@ -0,0 +1,201 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookHelp = "Checks the licenses of modules added or updated in go.sum, for use as a pre-commit hook."
+)
+
+// newHookCmd returns the hook command.
+func newHookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook [file...]",
+		Short: hookHelp,
+		Long: hookHelp + `
+
+The files are the ones changed by the commit, as passed by pre-commit. Without files,
+the files staged in git are used. Nothing is checked unless go.mod or go.sum changed.
+
+Modules are compared between the go.sum file in the working directory and the one in
+HEAD. Only added or updated modules are classified, using the license file in their
+module root in the module cache like --go_sum_only, and evaluated with the same policy
+as the check command. The hook fails if any of them is denied.`,
+		RunE: hookMain,
+	}
+	cmd.Flags().StringSliceVar(&allowedLicenses, "allowed_licenses", []string{}, "list of allowed license names, can't be used in combination with disallowed_types")
+	cmd.Flags().StringSliceVar(&disallowedTypes, "disallowed_types", []string{}, "list of disallowed license types, can't be used in combination with allowed_licenses (default: forbidden, unknown)")
+	cmd.Flags().StringSliceVar(&disallowedLicenses, "disallowed_licenses", []string{}, "list of disallowed license names, checked in addition to allowed_licenses or disallowed_types")
+
+	return cmd
+}
+
+func hookMain(_ *cobra.Command, files []string) error {
+	if len(files) == 0 {
+		staged, err := gitOutput("diff", "--cached", "--name-only")
+		if err != nil {
+			return fmt.Errorf("listing staged files: %w", err)
+		}
+		files = strings.Fields(staged)
+	}
+	if !modFilesChanged(files) {
+		return nil
+	}
+	policy, err := currentPolicy()
+	if err != nil {
+		return err
+	}
+
+	ctx := runContext()
+	modules, err := licenses.GoSumModules(ctx, ".")
+	if err != nil {
+		return err
+	}
+	old, err := headGoSumModules(modules)
+	if err != nil {
+		return err
+	}
+
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	rules := ignoreRules()
+	denied := 0
+	var lines []string
+	current := make(map[string]bool)
+	for _, m := range modules {
+		current[m.Path] = true
+		oldVersion, existed := old[m.Path]
+		if m.Main || ignoredBy(m.Path, rules) || (existed && oldVersion == m.Version) {
+			continue
+		}
+		lib := licenses.ModuleLibrary(classifier, licenses.Options{SkipSymlinks: !followSymlinks, LocalizedLicenseNames: cfg.LocalizedLicenseNames, Cache: cache}, m)
+		applyLicenseOverrides(classifier, []*licenses.Library{lib})
+		name, typ := identifyLicense(classifier, lib)
+		libLicenses := []license{{name: name, typ: typ}}
+		if hasLicenseChoice(lib) {
+			if libLicenses, err = libraryLicenses(classifier, lib); err != nil {
+				return err
+			}
+		}
+		decision := policyDecision(policy.violations(lib, libLicenses))
+		if decision == policyDenied {
+			denied++
+		}
+		if existed {
+			lines = append(lines, fmt.Sprintf("~ %s %s -> %s  %s  %s", m.Path, oldVersion, m.Version, name, decision))
+		} else {
+			lines = append(lines, fmt.Sprintf("+ %s %s  %s  %s", m.Path, m.Version, name, decision))
+		}
+	}
+	for _, path := range sortedKeys(old) {
+		if !current[path] {
+			lines = append(lines, fmt.Sprintf("- %s %s", path, old[path]))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "go-licenses: dependency changes in go.sum\n")
+	for _, line := range lines {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+	if denied > 0 {
+		return fmt.Errorf("%d added or updated modules have licenses that are not allowed", denied)
+	}
+	return nil
+}
+
+// modFilesChanged reports whether files include a go.mod or go.sum file.
+func modFilesChanged(files []string) bool {
+	for _, f := range files {
+		if base := filepath.Base(f); base == "go.mod" || base == "go.sum" {
+			return true
+		}
+	}
+	return false
+}
+
+// headGoSumModules returns the versions of the modules in the go.sum file committed in
+// HEAD next to the main module of modules. It returns no modules if there is none.
+func headGoSumModules(modules []*licenses.Module) (map[string]string, error) {
+	versions := make(map[string]string)
+	var mainDir string
+	for _, m := range modules {
+		if m.Main {
+			mainDir = m.Dir
+		}
+	}
+	top, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("finding git repository: %w", err)
+	}
+	rel, err := filepath.Rel(top, filepath.Join(mainDir, "go.sum"))
+	if err != nil {
+		return nil, err
+	}
+	sum, err := gitOutput("show", "HEAD:"+filepath.ToSlash(rel))
+	if err != nil {
+		// The go.sum file is new, or there are no commits yet.
+		return versions, nil
+	}
+	old, err := licenses.ParseGoSum([]byte(sum))
+	if err != nil {
+		return nil, fmt.Errorf("go.sum in HEAD: %w", err)
+	}
+	for _, m := range old {
+		versions[m.Path] = strings.TrimSuffix(m.Version, "+incompatible")
+	}
+	return versions, nil
+}
+
+// ignoredBy reports whether one of rules matches modulePath.
+func ignoredBy(modulePath string, rules []licenses.IgnoreRule) bool {
+	for _, rule := range rules {
+		if strings.HasPrefix(modulePath, rule.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// gitOutput runs git with args and returns its trimmed stdout.
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
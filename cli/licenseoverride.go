@@ -0,0 +1,167 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+)
+
+// licenseOverride pins the license of the libraries of a module that the classifier gets
+// wrong or can't identify, e.g. because of a custom license header or a license file
+// missing in the tagged version, see --license_overrides.
+type licenseOverride struct {
+	// Module is the module path, which may contain path.Match wildcards, optionally
+	// followed by "@version".
+	Module string `json:"module"`
+	// License is the SPDX license expression of the libraries.
+	License string `json:"license"`
+	// URL replaces the license URL of the libraries.
+	URL string `json:"url,omitempty"`
+	// LicenseFile is the license text of libraries without a license file. Relative
+	// paths are relative to the directory of the overrides file.
+	LicenseFile string `json:"licenseFile,omitempty"`
+	// Fallback only applies the override if the classifier can't identify the license,
+	// so that the pin doesn't hide a later relicensing.
+	Fallback bool `json:"fallback,omitempty"`
+	// Reason documents why the override is needed, e.g. a link to the upstream issue.
+	Reason string `json:"reason,omitempty"`
+}
+
+// licenseType returns the most restrictive type of the licenses in o.License.
+func (o licenseOverride) licenseType() licenses.Type {
+	var types []licenses.Type
+	for _, id := range licenses.ExpressionLicenseIDs(o.License) {
+		types = append(types, licenses.LicenseType(id))
+	}
+	return mostRestrictive(types)
+}
+
+// licenses returns the licenses in o.License, like libraryLicenses.
+func (o licenseOverride) licenses() []license {
+	var ls []license
+	for _, l := range licenses.ExpressionLicenses(o.License) {
+		ls = append(ls, license{name: l, typ: licenses.LicenseType(l)})
+	}
+	return ls
+}
+
+var (
+	// licenseOverridesPath is the path of the license overrides file, if any.
+	licenseOverridesPath string
+	// licenseOverrides are the overrides loaded from licenseOverridesPath.
+	licenseOverrides []licenseOverride
+	// overriddenLicenses are the overrides applied to libraries by applyLicenseOverrides.
+	overriddenLicenses = make(map[*licenses.Library]licenseOverride)
+)
+
+// addLicenseOverrideFlags adds the flags for overriding licenses to flags.
+func addLicenseOverrideFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&licenseOverridesPath, "license_overrides", "", "Path to a JSON file with an array of license overrides, each pinning the license of a module, given as path or path@version, to an SPDX license expression, optionally with a license URL and text file. Use it for modules that the classifier gets wrong or can't identify, without forking go-licenses.")
+}
+
+// setUpLicenseOverrides loads the overrides from --license_overrides.
+func setUpLicenseOverrides() error {
+	licenseOverrides = nil
+	overriddenLicenses = make(map[*licenses.Library]licenseOverride)
+	if licenseOverridesPath == "" {
+		return nil
+	}
+	var err error
+	licenseOverrides, err = loadLicenseOverrides(licenseOverridesPath)
+	return err
+}
+
+// loadLicenseOverrides reads and validates the license overrides file at path.
+func loadLicenseOverrides(path string) ([]licenseOverride, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading license overrides: %w", err)
+	}
+	var overrides []licenseOverride
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("parsing license overrides %s: %w", path, err)
+	}
+	for i := range overrides {
+		o := &overrides[i]
+		if o.Module == "" {
+			return nil, fmt.Errorf("parsing license overrides %s: override %d has no module", path, i)
+		}
+		if len(licenses.ExpressionLicenseIDs(o.License)) == 0 {
+			return nil, fmt.Errorf("parsing license overrides %s: override for %s has no license", path, o.Module)
+		}
+		if o.LicenseFile == "" {
+			continue
+		}
+		if !filepath.IsAbs(o.LicenseFile) {
+			o.LicenseFile = filepath.Join(filepath.Dir(path), o.LicenseFile)
+		}
+		if _, err := os.Stat(o.LicenseFile); err != nil {
+			return nil, fmt.Errorf("parsing license overrides %s: override for %s: %w", path, o.Module, err)
+		}
+	}
+	return overrides, nil
+}
+
+// applyLicenseOverrides records the override that applies to each of libs, the last one
+// matching its module. Libraries without a license file get the override's license file.
+func applyLicenseOverrides(classifier licenses.Classifier, libs []*licenses.Library) {
+	for _, lib := range libs {
+		m := lib.Module()
+		if m == nil {
+			continue
+		}
+		for i := len(licenseOverrides) - 1; i >= 0; i-- {
+			o := licenseOverrides[i]
+			if !isAllowedModule(m, []string{o.Module}) {
+				continue
+			}
+			if !o.Fallback || !isIdentified(classifier, lib) {
+				overriddenLicenses[lib] = o
+				if lib.LicensePath == "" {
+					lib.LicensePath = o.LicenseFile
+				}
+			}
+			break
+		}
+	}
+}
+
+// isIdentified reports whether the license of lib is declared following the REUSE
+// specification or identified by classifier.
+func isIdentified(classifier licenses.Classifier, lib *licenses.Library) bool {
+	if len(lib.ReuseLicenses) > 0 {
+		return true
+	}
+	if lib.LicensePath == "" {
+		return false
+	}
+	name, _, err := classifier.Identify(lib.LicensePath)
+	return err == nil && name != ""
+}
+
+// licenseOverrideFor returns the override applied to lib, if any.
+func licenseOverrideFor(lib *licenses.Library) (licenseOverride, bool) {
+	o, ok := overriddenLicenses[lib]
+	return o, ok
+}
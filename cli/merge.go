@@ -0,0 +1,183 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeHelp = "Merges JSON reports into one deduplicated report."
+)
+
+// newMergeCmd returns the merge command.
+func newMergeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge <report.json> [report.json...]",
+		Short: mergeHelp,
+		Long: mergeHelp + `
+
+Each argument is a report printed by "report --format=json", e.g. of one service. The
+merged report contains every library version found in any of them once. If the reports
+disagree on the license of a library version, the most restrictive one is kept and the
+others are listed in conflictingLicenseNames.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: mergeMain,
+	}
+}
+
+// mergedReport is the document printed by the merge command.
+type mergedReport struct {
+	// SchemaVersion is the version of the JSON report format of Libraries.
+	SchemaVersion int         `json:"schemaVersion"`
+	Metadata      runMetadata `json:"metadata"`
+	// LicenseExpression is the SPDX expression covering all merged libraries together.
+	LicenseExpression string          `json:"licenseExpression"`
+	Libraries         []mergedLibrary `json:"libraries"`
+}
+
+// mergedLibrary is a library version found in one or more merged reports.
+type mergedLibrary struct {
+	libraryData
+	// Reports are the files of the reports that contain the library version.
+	Reports []string `json:"reports"`
+	// ConflictingLicenseNames are the licenses other reports identified for the library
+	// version, which are less restrictive than LicenseName.
+	ConflictingLicenseNames []string `json:"conflictingLicenseNames,omitempty"`
+}
+
+func mergeMain(cmd *cobra.Command, args []string) error {
+	started := time.Now()
+	var reports []jsonReport
+	for _, path := range args {
+		report, err := readJSONReport(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+	libs := mergeReports(args, reports)
+	merged := mergedReport{
+		SchemaVersion: jsonReportSchemaVersion,
+		Metadata:      newRunMetadata(cmd, started, []string{}),
+		Libraries:     libs,
+	}
+	var data []libraryData
+	for _, lib := range libs {
+		data = append(data, lib.libraryData)
+	}
+	merged.LicenseExpression = aggregateExpression(data)
+	if merged.Libraries == nil {
+		merged.Libraries = []mergedLibrary{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(merged)
+}
+
+func readJSONReport(path string) (jsonReport, error) {
+	var report jsonReport
+	f, err := os.Open(path)
+	if err != nil {
+		return report, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&report); err != nil {
+		return report, fmt.Errorf("parsing report %s: %w", path, err)
+	}
+	// Reports without schemaVersion predate it and are compatible with version 1.
+	if report.SchemaVersion > jsonReportSchemaVersion {
+		return report, fmt.Errorf("report %s has schema version %d, but this version of go-licenses only reads up to %d", path, report.SchemaVersion, jsonReportSchemaVersion)
+	}
+	return report, nil
+}
+
+// mergeReports returns the union of the libraries of reports, read from the files
+// names, sorted by name and version.
+func mergeReports(names []string, reports []jsonReport) []mergedLibrary {
+	byKey := make(map[string]*mergedLibrary)
+	var keys []string
+	for i, report := range reports {
+		for _, lib := range report.Libraries {
+			key := lib.Name + "@" + lib.Version
+			m, ok := byKey[key]
+			if !ok {
+				byKey[key] = &mergedLibrary{libraryData: lib, Reports: []string{names[i]}}
+				keys = append(keys, key)
+				continue
+			}
+			if !containsString(m.Reports, names[i]) {
+				m.Reports = append(m.Reports, names[i])
+			}
+			if lib.LicenseName == m.LicenseName {
+				continue
+			}
+			conflicting := lib.LicenseName
+			if licenseSeverity(lib.LicenseName) < licenseSeverity(m.LicenseName) {
+				conflicting = m.LicenseName
+				m.libraryData = lib
+				// The entry that was kept may have listed the new license as conflicting.
+				m.ConflictingLicenseNames = removeString(m.ConflictingLicenseNames, lib.LicenseName)
+			}
+			if !containsString(m.ConflictingLicenseNames, conflicting) {
+				m.ConflictingLicenseNames = append(m.ConflictingLicenseNames, conflicting)
+			}
+		}
+	}
+	sort.Strings(keys)
+	libs := make([]mergedLibrary, 0, len(keys))
+	for _, key := range keys {
+		libs = append(libs, *byKey[key])
+	}
+	return libs
+}
+
+// licenseSeverity ranks the license expression name by its type as the report
+// classifies it, lower values being more restrictive. Of the licenses of an OR
+// expression, only the least restrictive counts.
+func licenseSeverity(name string) int {
+	typ := expressionType(name)
+	for i, t := range licenseTypeSeverity {
+		if t == typ {
+			return i
+		}
+	}
+	return len(licenseTypeSeverity)
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	var kept []string
+	for _, e := range list {
+		if e != s {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+func TestExpressionType(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		want licenses.Type
+	}{
+		{expr: "MIT", want: licenses.Notice},
+		{expr: "GPL-2.0", want: licenses.Restricted},
+		{expr: "GPL-2.0 OR MIT", want: licenses.Notice},
+		{expr: "GPL-2.0 AND MIT", want: licenses.Restricted},
+		{expr: "(GPL-2.0 OR MIT) AND MPL-2.0", want: licenses.Reciprocal},
+		{expr: "(GPL-2.0 AND AGPL-3.0) OR MPL-2.0", want: licenses.Reciprocal},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0 OR MIT", want: licenses.Notice},
+		{expr: "Unknown", want: licenses.Unknown},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			if got := expressionType(test.expr); got != test.want {
+				t.Errorf("expressionType(%q) = %q, want %q", test.expr, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeReports(t *testing.T) {
+	lib := func(license string) libraryData {
+		return libraryData{Name: "example.com/lib", Version: "v1.0.0", LicenseName: license}
+	}
+	for _, test := range []struct {
+		desc    string
+		reports [][]libraryData
+		want    []mergedLibrary
+	}{
+		{
+			desc: "same license in every report",
+			reports: [][]libraryData{
+				{lib("MIT")},
+				{lib("MIT")},
+			},
+			want: []mergedLibrary{
+				{libraryData: lib("MIT"), Reports: []string{"a.json", "b.json"}},
+			},
+		},
+		{
+			desc: "different versions are kept apart",
+			reports: [][]libraryData{
+				{lib("MIT")},
+				{{Name: "example.com/lib", Version: "v2.0.0", LicenseName: "MIT"}},
+			},
+			want: []mergedLibrary{
+				{libraryData: lib("MIT"), Reports: []string{"a.json"}},
+				{libraryData: libraryData{Name: "example.com/lib", Version: "v2.0.0", LicenseName: "MIT"}, Reports: []string{"b.json"}},
+			},
+		},
+		{
+			desc: "more restrictive license replaces the entry",
+			reports: [][]libraryData{
+				{lib("MIT")},
+				{lib("GPL-2.0")},
+			},
+			want: []mergedLibrary{
+				{libraryData: lib("GPL-2.0"), Reports: []string{"a.json", "b.json"}, ConflictingLicenseNames: []string{"MIT"}},
+			},
+		},
+		{
+			desc: "less restrictive license is listed as conflicting",
+			reports: [][]libraryData{
+				{lib("GPL-2.0")},
+				{lib("MIT")},
+			},
+			want: []mergedLibrary{
+				{libraryData: lib("GPL-2.0"), Reports: []string{"a.json", "b.json"}, ConflictingLicenseNames: []string{"MIT"}},
+			},
+		},
+		{
+			desc: "license choice counts as its least restrictive license",
+			reports: [][]libraryData{
+				{lib("GPL-2.0 OR MIT")},
+				{lib("MPL-2.0")},
+			},
+			want: []mergedLibrary{
+				{libraryData: lib("MPL-2.0"), Reports: []string{"a.json", "b.json"}, ConflictingLicenseNames: []string{"GPL-2.0 OR MIT"}},
+			},
+		},
+		{
+			desc: "conflicting licenses are listed once and never include the kept one",
+			reports: [][]libraryData{
+				{lib("MIT")},
+				{lib("GPL-2.0")},
+				{lib("MIT")},
+				{lib("AGPL-3.0")},
+				{lib("GPL-2.0")},
+			},
+			want: []mergedLibrary{
+				{
+					libraryData:             lib("AGPL-3.0"),
+					Reports:                 []string{"a.json", "b.json", "c.json", "d.json", "e.json"},
+					ConflictingLicenseNames: []string{"MIT", "GPL-2.0"},
+				},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var names []string
+			var reports []jsonReport
+			for i, libs := range test.reports {
+				names = append(names, string(rune('a'+i))+".json")
+				reports = append(reports, jsonReport{Libraries: libs})
+			}
+			got := mergeReports(names, reports)
+			if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(mergedLibrary{}, libraryData{})); diff != "" {
+				t.Errorf("mergeReports(): (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+// runMetadata describes the go-licenses run that produced a report, so that archived
+// reports are self-describing.
+type runMetadata struct {
+	// ToolVersion is the module version of go-licenses, "(devel)" for local builds.
+	ToolVersion string `json:"toolVersion"`
+	// Timestamp is the time the scan started.
+	Timestamp time.Time `json:"timestamp"`
+	// GoVersion is the version of the Go toolchain used to load packages.
+	GoVersion string `json:"goVersion"`
+	// RootModule is the main module of the working directory, if any.
+	RootModule string `json:"rootModule,omitempty"`
+	// Packages are the package arguments that were scanned.
+	Packages []string `json:"packages"`
+	// Flags holds the flags set explicitly on the command line.
+	Flags map[string]string `json:"flags"`
+	// ConfigSHA256 is the digest of the --config file, if any.
+	ConfigSHA256 string `json:"configSHA256,omitempty"`
+}
+
+// secretFlags are the flags whose values are left out of the metadata.
+var secretFlags = map[string]bool{"github_token": true}
+
+// newRunMetadata collects metadata about the current run of cmd on packages.
+// cmd may be nil, e.g. for deprecated aliases, in which case no flags are recorded.
+func newRunMetadata(cmd *cobra.Command, started time.Time, packages []string) runMetadata {
+	md := runMetadata{
+		ToolVersion: "(devel)",
+		Timestamp:   started.UTC(),
+		GoVersion:   runtime.Version(),
+		Packages:    packages,
+		Flags:       map[string]string{},
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		md.ToolVersion = bi.Main.Version
+	}
+	if v, err := goCommandOutput("env", "GOVERSION"); err == nil && v != "" {
+		md.GoVersion = v
+	}
+	if m, err := goCommandOutput("list", "-m"); err == nil {
+		// In workspace mode, there may be several main modules; the first one is the root.
+		md.RootModule = strings.SplitN(m, "\n", 2)[0]
+	}
+	if cmd != nil {
+		cmd.Flags().Visit(func(f *pflag.Flag) {
+			md.Flags[f.Name] = f.Value.String()
+			if secretFlags[f.Name] {
+				md.Flags[f.Name] = "REDACTED"
+			}
+		})
+	}
+	if configPath != "" {
+		if b, err := os.ReadFile(configPath); err == nil {
+			sum := sha256.Sum256(b)
+			md.ConfigSHA256 = hex.EncodeToString(sum[:])
+		} else {
+			klog.Warningf("Cannot hash config file for report metadata: %v", err)
+		}
+	}
+	return md
+}
+
+// goCommandOutput runs the go command with args and returns its trimmed stdout.
+func goCommandOutput(args ...string) (string, error) {
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// noNetwork refuses all network access and lists the endpoints that would have
+	// been contacted instead.
+	noNetwork bool
+	// offline refuses all network access and derives URLs and license texts from the
+	// module cache instead.
+	offline bool
+
+	// preflight records the requests refused because of --no_network.
+	preflight *preflightTransport
+	// httpClient makes the HTTP requests of a run, through the transports selected by
+	// the flags and the config file.
+	httpClient = http.DefaultClient
+)
+
+// addNetworkFlags adds the network flags shared by all commands to flags.
+func addNetworkFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&offline, "offline", false, "Never access the network, e.g. on build machines without internet access. License URLs are derived from the module path or the repository recorded in the module cache, and license texts are read from the module cache. Modules missing from the module cache are not downloaded.")
+	flags.DurationVar(&httpTimeout, "http_timeout", 20*time.Second, "Time after which an attempt of an HTTP request, e.g. to resolve a vanity import path or download a license text, is given up, including reading the response. 0 means no timeout.")
+	flags.IntVar(&httpRetries, "http_retries", 3, "Number of times GET and HEAD requests are retried after transient failures: network errors, timeouts, 429 Too Many Requests and 5xx statuses other than 501.")
+	flags.DurationVar(&httpBackoff, "http_backoff", time.Second, "Delay before the first retry of an HTTP request, doubled for every further retry, of which a random half is waited. Longer if the server asks for it with Retry-After, up to a minute.")
+	flags.StringVar(&githubToken, "github_token", "", "Token that HTTPS requests to GitHub, e.g. downloads of license texts, are authenticated with, so that large runs don't hit the rate limit of unauthenticated requests. Prefer the GITHUB_TOKEN environment variable, which is used if the flag isn't set, to keep the token out of process listings.")
+	flags.BoolVar(&noNetwork, "no_network", false, "Don't access the network. Instead, list the endpoints that the command would contact at the end, e.g. for a security review. Modules missing from the module cache are not downloaded.")
+}
+
+// newHTTPClient returns the client that makes the HTTP requests of a run with the
+// transports selected by the flags and c. They wrap http.DefaultTransport, which is
+// left unchanged, so that running a command doesn't affect the other requests of the
+// process, nor those of later runs.
+func newHTTPClient(c config) (*http.Client, error) {
+	t, err := withRetries(http.DefaultTransport)
+	if err != nil {
+		return nil, err
+	}
+	if t, err = withRecording(t); err != nil {
+		return nil, err
+	}
+	if t, err = withoutNetwork(t); err != nil {
+		return nil, err
+	}
+	t = withGitHubToken(t)
+	t = withHeaders(t, c)
+	return &http.Client{Transport: t}, nil
+}
+
+// withoutNetwork returns a transport that fails all requests instead of base if
+// --no_network or --offline is set. With --no_network, it records them in preflight.
+func withoutNetwork(base http.RoundTripper) (http.RoundTripper, error) {
+	preflight = nil
+	switch {
+	case noNetwork:
+		if recordDir != "" {
+			return nil, errors.New("--no_network and --record can't be used at the same time")
+		}
+		preflight = &preflightTransport{goEndpoints: []string{
+			"GOPROXY=" + goEnvOr("GOPROXY", "https://proxy.golang.org,direct"),
+			"GOSUMDB=" + goEnvOr("GOSUMDB", "sum.golang.org"),
+		}}
+		// --offline refuses requests as well, --no_network also lists them.
+		return preflight, nil
+	case offline:
+		if recordDir != "" {
+			return nil, errors.New("--offline and --record can't be used at the same time")
+		}
+		return offlineTransport{}, nil
+	}
+	return base, nil
+}
+
+// goEnviron returns the environment of the go commands of a run, which keeps them from
+// downloading modules if --no_network or --offline is set, or nil for the environment
+// of the current process.
+func goEnviron() []string {
+	if !noNetwork && !offline {
+		return nil
+	}
+	return append(os.Environ(), "GOPROXY=off")
+}
+
+// runContext returns the context that a run calls the functions of the licenses package
+// with, so that the go commands they run use goEnviron.
+func runContext() context.Context {
+	return licenses.WithGoEnviron(context.Background(), goEnviron())
+}
+
+// offlineTransport fails all requests.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("not contacting %s because of --offline", req.URL.Host)
+}
+
+// goEnvOr returns the go environment variable name, or def if the go command fails.
+func goEnvOr(name, def string) string {
+	if v, err := goCommandOutput("env", name); err == nil && v != "" {
+		return v
+	}
+	return def
+}
+
+// preflightTransport fails all requests and records them.
+type preflightTransport struct {
+	mu       sync.Mutex
+	requests map[string]bool
+	// goEndpoints are the settings that tell where the go command downloads modules.
+	goEndpoints []string
+}
+
+func (t *preflightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.requests == nil {
+		t.requests = make(map[string]bool)
+	}
+	u := *req.URL
+	// Don't print credentials.
+	u.User = nil
+	t.requests[req.Method+" "+u.String()] = true
+	return nil, fmt.Errorf("not contacting %s because of --no_network", req.URL.Host)
+}
+
+// printPreflight prints the endpoints recorded because of --no_network, if set.
+func printPreflight(w io.Writer) error {
+	if preflight == nil {
+		return nil
+	}
+	preflight.mu.Lock()
+	defer preflight.mu.Unlock()
+	var requests []string
+	for r := range preflight.requests {
+		requests = append(requests, r)
+	}
+	sort.Strings(requests)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Network endpoints the command would contact (%d):\n", len(requests))
+	for _, r := range requests {
+		fmt.Fprintf(&b, "  %s\n", r)
+	}
+	b.WriteString("Requests that depend on the responses of these, e.g. downloads from the repository\n" +
+		"that a go-get meta tag points to, can't be listed.\n")
+	fmt.Fprintf(&b, "The go command would download modules missing from the module cache using %s.\n", strings.Join(preflight.goEndpoints, " and "))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClientRetriesOncePerRun(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer func(retries int, backoff time.Duration) { httpRetries, httpBackoff = retries, backoff }(httpRetries, httpBackoff)
+	httpRetries, httpBackoff = 1, 0
+
+	defaultTransport := http.DefaultTransport
+	// Every run builds its own client, earlier runs must not add retries to later ones.
+	for run := 1; run <= 3; run++ {
+		client, err := newHTTPClient(config{UserAgent: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		atomic.StoreInt32(&requests, 0)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("run %d: server got %d requests, want 2", run, got)
+		}
+	}
+	if http.DefaultTransport != defaultTransport {
+		t.Errorf("newHTTPClient() changed http.DefaultTransport")
+	}
+}
+
+func TestNewHTTPClientOffline(t *testing.T) {
+	defer func(v bool) { offline = v }(offline)
+	offline = true
+	goProxy, goProxySet := os.LookupEnv("GOPROXY")
+
+	client, err := newHTTPClient(config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get("https://example.com/LICENSE"); err == nil || !strings.Contains(err.Error(), "--offline") {
+		t.Errorf("client.Get() = (_, %v), want an error mentioning --offline", err)
+	}
+	if v, ok := os.LookupEnv("GOPROXY"); v != goProxy || ok != goProxySet {
+		t.Errorf("GOPROXY = %q, want it unchanged (%q)", v, goProxy)
+	}
+	env := goEnviron()
+	if len(env) == 0 || env[len(env)-1] != "GOPROXY=off" {
+		t.Errorf("goEnviron() doesn't end with GOPROXY=off: %v", env)
+	}
+
+	offline = false
+	if env := goEnviron(); env != nil {
+		t.Errorf("goEnviron() without --offline = %v, want nil", env)
+	}
+}
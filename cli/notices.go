@@ -0,0 +1,116 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noticesHelp = "Prints a THIRD_PARTY_NOTICES.txt file with the license texts of the dependencies of one or more Go packages."
+)
+
+// newNoticesCmd returns the notices command.
+func newNoticesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "notices <package> [package...]",
+		Short: noticesHelp,
+		Long: noticesHelp + `
+
+The file is ready to ship with a binary: it has a section per library with its name,
+version, license name and full license text, read from the license file that was
+classified, followed by its NOTICE file and the other files whose terms apply along
+with the license, e.g. PATENTS. A license text identical to one printed before refers
+to that section instead of repeating it. The libraries of the main module are left
+out.` + packageHelp,
+		Args: cobra.MinimumNArgs(1),
+		RunE: noticesMain,
+	}
+}
+
+func noticesMain(_ *cobra.Command, args []string) error {
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	ctx := licenses.WithWarningHandler(runContext(), recordWarning)
+	libs, err := libraries(ctx, classifier, args)
+	if err != nil {
+		return err
+	}
+	style, err := shortNameStyle()
+	if err != nil {
+		return err
+	}
+	policy, err := currentPolicy()
+	if err != nil {
+		return err
+	}
+	placeholder, err := parsePlaceholder()
+	if err != nil {
+		return err
+	}
+	reporter := libraryReporter{
+		classifier:      classifier,
+		style:           style,
+		withLicenseText: true,
+		policy:          policy,
+		placeholder:     placeholder,
+	}
+	var data []libraryData
+	for _, result := range reporter.reportAll(ctx, libs) {
+		if result.err != nil {
+			return result.err
+		}
+		if result.included && !isMain(result.data) {
+			data = append(data, result.data)
+		}
+	}
+	applyModuleOverrides(data, cfg.ModuleOverrides)
+	if err := writeNotices(data); err != nil {
+		return err
+	}
+	return reportClassifyErrors()
+}
+
+// writeNotices prints a section per library of libs with its license text. Identical
+// texts are printed once, in the section of the first library using them.
+func writeNotices(libs []libraryData) error {
+	w := bufio.NewWriter(out)
+	// printedBy maps the license texts printed so far to the library whose section has it.
+	printedBy := make(map[string]string)
+	for _, lib := range libs {
+		writeAttributionHeader(w, attributionName(lib), "License: "+lib.LicenseName)
+		text := strings.TrimRight(lib.License, "\n")
+		switch {
+		case text != "" && lib.License != UNKNOWN && printedBy[text] != "":
+			fmt.Fprintf(w, "The license text is identical to the one of %s above.\n", printedBy[text])
+		case text != "" && lib.License != UNKNOWN:
+			printedBy[text] = attributionName(lib)
+			fmt.Fprintln(w, text)
+		case lib.LicenseURL != "" && lib.LicenseURL != UNKNOWN:
+			fmt.Fprintf(w, "The license text is not available, see %s.\n", lib.LicenseURL)
+		default:
+			fmt.Fprintln(w, "The license text is not available.")
+		}
+		writeAttributionNotice(w, lib)
+	}
+	return w.Flush()
+}
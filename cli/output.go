@@ -0,0 +1,139 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// outputPath is the file that machine-readable output is written to instead of stdout.
+	outputPath string
+	// colorMode selects whether diagnostics on stderr are colored: auto, always or never.
+	colorMode string
+	noColor   bool
+
+	// silent is set by check --silent to print nothing at all.
+	silent bool
+
+	// out receives all machine-readable output, i.e. reports, graphs and explanations.
+	// Diagnostics are written to stderr.
+	out io.Writer = os.Stdout
+	// diagnostics receives the diagnostics, i.e. stderr unless silenced.
+	diagnostics io.Writer = os.Stderr
+)
+
+// addOutputFlags adds the output flags shared by all commands to flags.
+func addOutputFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&outputPath, "output", "", "Write the output to this file instead of stdout. Diagnostics are always written to stderr.")
+	flags.StringVar(&colorMode, "color", "auto", "Whether to color diagnostics on stderr: auto, always or never. auto colors them if stderr is a terminal and NO_COLOR is not set.")
+	flags.BoolVar(&noColor, "no_color", false, "Do not color diagnostics, same as --color=never.")
+}
+
+// openOutput points out to --output, if set, or to stdout. The returned function closes
+// the file and points out back to stdout, so that later runs in the process don't write
+// to the closed file.
+func openOutput() (func() error, error) {
+	out = os.Stdout
+	if outputPath == "" {
+		return func() error { return nil }, nil
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating output file: %w", err)
+	}
+	out = f
+	return func() error {
+		out = os.Stdout
+		return f.Close()
+	}, nil
+}
+
+// setUpSilent points diagnostics to stderr, or discards all diagnostics and logs if
+// --silent is set. Errors that end the run are left to Main, which exits without logging
+// them. The returned function points diagnostics back to stderr and restores klog, so
+// that later runs in the process log again.
+func setUpSilent() (func(), error) {
+	diagnostics = os.Stderr
+	if !silent {
+		return func() {}, nil
+	}
+	if eventsFormat != "" && eventsPath == "" {
+		return nil, errors.New("--silent requires --events_output with --events")
+	}
+	// Set the klog flag on a flag set of our own, since the process's flag set only has
+	// it if Main registered the klog flags.
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	state := klog.CaptureState()
+	restore := func() {
+		diagnostics = os.Stderr
+		state.Restore()
+	}
+	diagnostics = io.Discard
+	klog.LogToStderr(false)
+	klog.SetOutput(io.Discard)
+	if err := flags.Set("stderrthreshold", "FATAL"); err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+// useColor reports whether diagnostics on stderr should be colored.
+func useColor() (bool, error) {
+	if noColor {
+		return false, nil
+	}
+	switch colorMode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
+		}
+		fi, err := os.Stderr.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("unknown --color %q, want one of: auto, always, never", colorMode)
+	}
+}
+
+const (
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// colored is true if diagnostics are colored, see useColor.
+var colored bool
+
+// diagnosticf prints a diagnostic line to stderr, in color if enabled.
+func diagnosticf(color, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if colored {
+		msg = color + msg + colorReset
+	}
+	fmt.Fprintln(diagnostics, msg)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestOpenOutputRestoresStdout(t *testing.T) {
+	defer func(p string) { outputPath = p }(outputPath)
+	outputPath = filepath.Join(t.TempDir(), "report.csv")
+
+	closeOutput, err := openOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(out, "report")
+	if err := closeOutput(); err != nil {
+		t.Fatal(err)
+	}
+	if out != os.Stdout {
+		t.Errorf("out = %v after closing --output, want os.Stdout", out)
+	}
+	if b, err := os.ReadFile(outputPath); err != nil || string(b) != "report" {
+		t.Errorf("--output file = (%q, %v), want %q", b, err, "report")
+	}
+}
+
+func TestSilentRunRestoresLogs(t *testing.T) {
+	defer klog.CaptureState().Restore()
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+
+	// Both runs fail setting up, after --silent took effect.
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	for _, args := range [][]string{{"--silent"}, nil} {
+		cmd := NewCheckCmd()
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		cmd.SetArgs(append(args, "--no_cache", "--config", missing, "."))
+		if err := cmd.Execute(); err == nil {
+			t.Fatalf("check %v: got no error, want one for the missing config", args)
+		}
+		if diagnostics != io.Writer(os.Stderr) {
+			t.Errorf("after check %v: diagnostics = %v, want os.Stderr", args, diagnostics)
+		}
+		klog.Warning("logged after the run")
+		klog.Flush()
+		if !strings.Contains(logs.String(), "logged after the run") {
+			t.Errorf("after check %v: klog output = %q, want the warning logged after the run", args, logs.String())
+		}
+		logs.Reset()
+	}
+}
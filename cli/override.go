@@ -0,0 +1,75 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import "fmt"
+
+// moduleOverride replaces report fields of the libraries of a module whose upstream
+// metadata is wrong or missing, see config.ModuleOverrides. Empty fields are left alone.
+type moduleOverride struct {
+	// Module is the module path, which may contain path.Match wildcards, optionally
+	// followed by "@version".
+	Module string `json:"module"`
+	// DisplayName replaces the ShortName of the libraries.
+	DisplayName string `json:"displayName,omitempty"`
+	// Attribution replaces the license text reproduced in attributions.
+	Attribution string `json:"attribution,omitempty"`
+	// Homepage is the URL of the project's website.
+	Homepage string `json:"homepage,omitempty"`
+	// Notice replaces the content of the NOTICE file.
+	Notice string `json:"notice,omitempty"`
+}
+
+// validateModuleOverrides returns an error for overrides that match no module or
+// override nothing.
+func validateModuleOverrides(overrides []moduleOverride) error {
+	for i, o := range overrides {
+		if o.Module == "" {
+			return fmt.Errorf("moduleOverrides[%d] has no module", i)
+		}
+		if o == (moduleOverride{Module: o.Module}) {
+			return fmt.Errorf("moduleOverrides[%d] for %s overrides no field", i, o.Module)
+		}
+	}
+	return nil
+}
+
+// applyModuleOverrides applies the overrides matching the module of each library in libs.
+// Later overrides take precedence over earlier ones for the same field.
+func applyModuleOverrides(libs []libraryData, overrides []moduleOverride) {
+	for i := range libs {
+		lib := &libs[i]
+		if lib.module == nil {
+			continue
+		}
+		for _, o := range overrides {
+			if !isAllowedModule(lib.module, []string{o.Module}) {
+				continue
+			}
+			if o.DisplayName != "" {
+				lib.ShortName = o.DisplayName
+			}
+			if o.Attribution != "" {
+				lib.License = o.Attribution
+			}
+			if o.Homepage != "" {
+				lib.Homepage = o.Homepage
+			}
+			if o.Notice != "" {
+				lib.Notice = o.Notice
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Policy decisions for a library, see policyDecision.
+const (
+	policyAllowed     = "allowed"
+	policyDenied      = "denied"
+	policyNeedsReview = "needs-review"
+	// policyException is followed by the ID of the exception that allows the library.
+	policyException = "exception:"
+)
+
+// licensePolicy decides which licenses are allowed, as configured by --allowed_licenses,
+// --disallowed_types, --disallowed_licenses and the config file.
+type licensePolicy struct {
+	allowedNames    []string
+	disallowedTypes []licenses.Type
+	// disallowedNames are denied in addition to the names not allowed by allowedNames
+	// or the types in disallowedTypes.
+	disallowedNames []string
+	// tolerateUnknown is set if unknown license types are reviewed manually rather than
+	// denied, see config.MaxUnknown.
+	tolerateUnknown bool
+	exceptions      []exception
+	// preferredNames are the licenses applied to libraries offering a choice of licenses,
+	// most preferred first, see choose.
+	preferredNames []string
+	// trustedDomains are the domains of modules whose licenses are always allowed, see
+	// --trusted_domains.
+	trustedDomains []string
+}
+
+// exception allows a module to use licenses that the policy doesn't allow otherwise.
+type exception struct {
+	// ID identifies the exception, e.g. the ticket in which it was granted.
+	ID string `json:"id"`
+	// Module is the module path, which may contain path.Match wildcards, optionally
+	// followed by "@version".
+	Module string `json:"module"`
+	// Licenses are the license names the exception applies to. It applies to all
+	// licenses of the module if empty.
+	Licenses []string `json:"licenses,omitempty"`
+	// Reason documents why the exception was granted.
+	Reason string `json:"reason,omitempty"`
+	// Expires is the last day on which the exception applies, as YYYY-MM-DD, if any.
+	Expires string `json:"expires,omitempty"`
+}
+
+// exceptionDateLayout is the layout of exception.Expires.
+const exceptionDateLayout = "2006-01-02"
+
+// expired reports whether e no longer applies at now.
+func (e exception) expired(now time.Time) bool {
+	if e.Expires == "" {
+		return false
+	}
+	day, err := time.ParseInLocation(exceptionDateLayout, e.Expires, now.Location())
+	return err == nil && now.After(day.AddDate(0, 0, 1))
+}
+
+// validateExceptions returns an error for exceptions with an invalid expiry date.
+func validateExceptions(exceptions []exception) error {
+	for i, e := range exceptions {
+		if e.Expires == "" {
+			continue
+		}
+		if _, err := time.Parse(exceptionDateLayout, e.Expires); err != nil {
+			return fmt.Errorf("policyExceptions[%d] expires on %q, want a date like 2006-01-02", i, e.Expires)
+		}
+	}
+	return nil
+}
+
+// violation is a license of a library that the policy doesn't allow.
+type violation struct {
+	license
+	message string
+	// unknown is set if the license type is unknown and tolerated for review.
+	unknown bool
+	// exception is the ID of the exception that allows the license anyway, if any.
+	exception string
+}
+
+// license is a license that applies to a library.
+type license struct {
+	name string
+	typ  licenses.Type
+}
+
+// currentPolicy returns the policy configured by flags and the config file.
+func currentPolicy() (licensePolicy, error) {
+	p := licensePolicy{
+		allowedNames:    getAllowedLicenseNames(),
+		disallowedTypes: getDisallowedLicenseTypes(),
+		disallowedNames: getDisallowedLicenseNames(),
+		tolerateUnknown: cfg.MaxUnknown != nil,
+		exceptions:      cfg.PolicyExceptions,
+		preferredNames:  cfg.PreferredLicenses,
+		trustedDomains:  getTrustedDomains(),
+	}
+	hasLicenseNames := len(p.allowedNames) > 0
+	hasLicenseType := len(p.disallowedTypes) > 0
+	if hasLicenseNames && hasLicenseType {
+		return p, errors.New("allowed_licenses && disallowed_types can't be used at the same time")
+	}
+	if !hasLicenseNames && !hasLicenseType {
+		// fallback to original behaviour to avoid breaking changes
+		p.disallowedTypes = []licenses.Type{licenses.Forbidden, licenses.Unknown}
+	}
+	return p, nil
+}
+
+// libraryLicenses returns the licenses of lib. Every license of a library following the
+// REUSE specification or with a license override is returned, as is every license a
+// library offers a choice of, see hasLicenseChoice.
+func libraryLicenses(classifier licenses.Classifier, lib *licenses.Library) ([]license, error) {
+	defer timePhase(phaseClassification)()
+	if o, ok := licenseOverrideFor(lib); ok {
+		return o.licenses(), nil
+	}
+	if len(lib.ReuseLicenses) > 0 {
+		var libLicenses []license
+		for _, expr := range lib.ReuseLicenses {
+			for _, l := range licenses.ExpressionLicenses(expr) {
+				libLicenses = append(libLicenses, license{name: l, typ: licenses.LicenseType(l)})
+			}
+		}
+		return libLicenses, nil
+	}
+	if hasLicenseChoice(lib) {
+		var libLicenses []license
+		for _, name := range lib.LicenseChoices {
+			libLicenses = append(libLicenses, license{name: name, typ: licenses.LicenseType(name)})
+		}
+		return libLicenses, nil
+	}
+	licenseName, licenseType, err := classifier.Identify(lib.LicensePath)
+	if err != nil {
+		return nil, err
+	}
+	return []license{{name: licenseName, typ: licenseType}}, nil
+}
+
+// hasLicenseChoice reports whether lib lets licensees choose among its licenses, see
+// licenses.Library.LicenseChoices. Licenses declared by a license override or following
+// the REUSE specification take precedence.
+func hasLicenseChoice(lib *licenses.Library) bool {
+	_, overridden := licenseOverrideFor(lib)
+	return len(lib.LicenseChoices) > 1 && !overridden && len(lib.ReuseLicenses) == 0
+}
+
+// violations returns the licenses of lib, as returned by libraryLicenses, that the
+// policy doesn't allow. All licenses of modules under the trusted domains are allowed.
+// Of the licenses of a library offering a choice of them, only the chosen one must be
+// allowed, or all of them are violations if none is allowed.
+func (p licensePolicy) violations(lib *licenses.Library, libLicenses []license) []violation {
+	if m := lib.Module(); m != nil && inDomains(m.Path, p.trustedDomains) {
+		return nil
+	}
+	if hasLicenseChoice(lib) {
+		if l, ok := p.choose(libLicenses); ok {
+			libLicenses = []license{l}
+		}
+	}
+	var vs []violation
+	for _, l := range libLicenses {
+		reason, unknown := p.denial(l)
+		if reason == "" {
+			continue
+		}
+		v := violation{license: l, unknown: unknown, message: fmt.Sprintf("%s found for library %v", reason, lib)}
+		if e, ok := p.exception(lib, l.name); ok {
+			v.exception = e.ID
+		}
+		vs = append(vs, v)
+	}
+	// Licenses declared for single files apply to the library as well.
+	for _, fl := range fileLicenseMismatches(lib, libLicenses) {
+		for _, name := range licenses.ExpressionLicenses(fl.License) {
+			l := license{name: name, typ: licenses.LicenseType(name)}
+			reason, unknown := p.denial(l)
+			if reason == "" {
+				continue
+			}
+			v := violation{license: l, unknown: unknown, message: fmt.Sprintf("%s found in the header of %s of library %v", reason, pathInModule(lib, fl.Path), lib)}
+			if e, ok := p.exception(lib, l.name); ok {
+				v.exception = e.ID
+			}
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// choose returns the license the policy applies to a library offering a choice of
+// libLicenses: the first of preferredNames that it allows, or else the first of
+// libLicenses that it allows. ok is false if it allows none of them.
+func (p licensePolicy) choose(libLicenses []license) (chosen license, ok bool) {
+	for _, name := range p.preferredNames {
+		for _, l := range libLicenses {
+			if l.name == name {
+				if reason, _ := p.denial(l); reason == "" {
+					return l, true
+				}
+			}
+		}
+	}
+	for _, l := range libLicenses {
+		if reason, _ := p.denial(l); reason == "" {
+			return l, true
+		}
+	}
+	return license{}, false
+}
+
+// denial returns why the policy doesn't allow l, e.g. "Disallowed license AGPL-3.0", or
+// "" if it allows it. unknown is set if l is only tolerated for review.
+func (p licensePolicy) denial(l license) (reason string, unknown bool) {
+	switch {
+	case isAllowedLicenseName(l.name, p.disallowedNames):
+		return fmt.Sprintf("Disallowed license %s", l.name), false
+	case p.tolerateUnknown && l.typ == licenses.Unknown:
+		return fmt.Sprintf("Unknown license type %s", l.name), true
+	case len(p.allowedNames) > 0 && !isAllowedLicenseName(l.name, p.allowedNames):
+		return fmt.Sprintf("Not allowed license %s", l.name), false
+	case len(p.disallowedTypes) > 0 && isDisallowedLicenseType(l.typ, p.disallowedTypes):
+		return fmt.Sprintf("%s license type %s", cases.Title(language.English).String(l.typ.String()), l.name), false
+	}
+	return "", false
+}
+
+// exception returns the exception that allows licenseName for lib, if any.
+func (p licensePolicy) exception(lib *licenses.Library, licenseName string) (exception, bool) {
+	m := lib.Module()
+	if m == nil {
+		return exception{}, false
+	}
+	for _, e := range p.exceptions {
+		if !isAllowedModule(m, []string{e.Module}) || e.expired(time.Now()) {
+			continue
+		}
+		if len(e.Licenses) == 0 || isAllowedLicenseName(licenseName, e.Licenses) {
+			return e, true
+		}
+	}
+	return exception{}, false
+}
+
+// policyDecision summarizes the violations of a library: denied if any license is
+// neither allowed by an exception nor tolerated for review, needs-review if a license
+// type is unknown, "exception:<id>" if an exception allows it and allowed otherwise.
+func policyDecision(vs []violation) string {
+	decision := policyAllowed
+	for _, v := range vs {
+		switch {
+		case v.exception != "":
+			if decision == policyAllowed {
+				decision = policyException + v.exception
+			}
+		case v.unknown:
+			decision = policyNeedsReview
+		default:
+			return policyDenied
+		}
+	}
+	return decision
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+func TestDenial(t *testing.T) {
+	mit := license{name: "MIT", typ: licenses.Notice}
+	agpl := license{name: "AGPL-3.0", typ: licenses.Forbidden}
+	unknown := license{name: "", typ: licenses.Unknown}
+	for _, test := range []struct {
+		desc        string
+		policy      licensePolicy
+		license     license
+		wantReason  string
+		wantUnknown bool
+	}{
+		{
+			desc:    "type not disallowed",
+			policy:  licensePolicy{disallowedTypes: []licenses.Type{licenses.Forbidden}},
+			license: mit,
+		},
+		{
+			desc:       "disallowed type",
+			policy:     licensePolicy{disallowedTypes: []licenses.Type{licenses.Forbidden}},
+			license:    agpl,
+			wantReason: "Forbidden license type AGPL-3.0",
+		},
+		{
+			desc:    "allowed name",
+			policy:  licensePolicy{allowedNames: []string{"MIT"}},
+			license: mit,
+		},
+		{
+			desc:       "name not allowed",
+			policy:     licensePolicy{allowedNames: []string{"MIT"}},
+			license:    agpl,
+			wantReason: "Not allowed license AGPL-3.0",
+		},
+		{
+			desc:       "disallowed name wins over allowed name",
+			policy:     licensePolicy{allowedNames: []string{"MIT"}, disallowedNames: []string{"MIT"}},
+			license:    mit,
+			wantReason: "Disallowed license MIT",
+		},
+		{
+			desc:       "unknown type is denied",
+			policy:     licensePolicy{disallowedTypes: []licenses.Type{licenses.Forbidden, licenses.Unknown}},
+			license:    unknown,
+			wantReason: "Unknown license type ",
+		},
+		{
+			desc:        "unknown type is tolerated for review",
+			policy:      licensePolicy{allowedNames: []string{"MIT"}, tolerateUnknown: true},
+			license:     unknown,
+			wantReason:  "Unknown license type ",
+			wantUnknown: true,
+		},
+		{
+			desc:       "disallowed name isn't tolerated for review",
+			policy:     licensePolicy{disallowedNames: []string{"AGPL-3.0"}, tolerateUnknown: true},
+			license:    license{name: "AGPL-3.0", typ: licenses.Unknown},
+			wantReason: "Disallowed license AGPL-3.0",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			reason, unknown := test.policy.denial(test.license)
+			if reason != test.wantReason || unknown != test.wantUnknown {
+				t.Errorf("denial(%v) = (%q, %v), want (%q, %v)", test.license, reason, unknown, test.wantReason, test.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestPolicyDecision(t *testing.T) {
+	denied := violation{license: license{name: "AGPL-3.0"}}
+	review := violation{license: license{name: ""}, unknown: true}
+	excepted := violation{license: license{name: "GPL-2.0"}, exception: "legal-42"}
+	for _, test := range []struct {
+		desc       string
+		violations []violation
+		want       string
+	}{
+		{desc: "no violations", want: policyAllowed},
+		{desc: "denied", violations: []violation{denied}, want: policyDenied},
+		{desc: "unknown", violations: []violation{review}, want: policyNeedsReview},
+		{desc: "exception", violations: []violation{excepted}, want: "exception:legal-42"},
+		{desc: "denied wins over exception", violations: []violation{excepted, denied}, want: policyDenied},
+		{desc: "denied wins over review", violations: []violation{review, denied, excepted}, want: policyDenied},
+		{desc: "review wins over exception", violations: []violation{excepted, review}, want: policyNeedsReview},
+		{desc: "review wins over later exception", violations: []violation{review, excepted}, want: policyNeedsReview},
+		{
+			desc:       "first exception is reported",
+			violations: []violation{excepted, {license: license{name: "MPL-2.0"}, exception: "legal-7"}},
+			want:       "exception:legal-42",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := policyDecision(test.violations); got != test.want {
+				t.Errorf("policyDecision() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of policy imports, see policyImport.Format.
+const (
+	// importAllowlist is a text file with one allowed SPDX license identifier per line.
+	importAllowlist = "spdx-allowlist"
+	// importDenylist is a text file with one disallowed SPDX license identifier per line.
+	importDenylist = "spdx-denylist"
+	// importORT is the license-classifications.yml file of the OSS Review Toolkit.
+	importORT = "ort-license-classifications"
+)
+
+// policyImport is a policy definition of another tool whose licenses are added to the
+// allowed or disallowed licenses of the config file, see config.PolicyImports.
+type policyImport struct {
+	// Path of the file to import, relative to the config file.
+	Path string `json:"path"`
+	// Format of the file, one of: spdx-allowlist, spdx-denylist,
+	// ort-license-classifications.
+	Format string `json:"format"`
+	// AllowedCategories and DisallowedCategories are the ORT license categories whose
+	// licenses are allowed and disallowed, e.g. "permissive" and "copyleft-strong". A
+	// license in both is disallowed.
+	AllowedCategories    []string `json:"allowedCategories,omitempty"`
+	DisallowedCategories []string `json:"disallowedCategories,omitempty"`
+}
+
+// ortLicenseClassifications is the part of an ORT license-classifications.yml file that
+// is imported.
+type ortLicenseClassifications struct {
+	Categories []struct {
+		Name string `yaml:"name"`
+	} `yaml:"categories"`
+	Categorizations []struct {
+		ID         string   `yaml:"id"`
+		Categories []string `yaml:"categories"`
+	} `yaml:"categorizations"`
+}
+
+// importPolicies adds the licenses of c.PolicyImports to c.AllowedLicenses and
+// c.DisallowedLicenses. dir is the directory of the config file.
+func importPolicies(c *config, dir string) error {
+	for i, imp := range c.PolicyImports {
+		path := imp.Path
+		if path == "" {
+			return fmt.Errorf("policyImports[%d] has no path", i)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if imp.Format != importORT && (len(imp.AllowedCategories) > 0 || len(imp.DisallowedCategories) > 0) {
+			return fmt.Errorf("policyImports[%d]: categories only apply to format %s", i, importORT)
+		}
+		switch imp.Format {
+		case importAllowlist, importDenylist:
+			names, err := readList(path, "license list")
+			if err != nil {
+				return err
+			}
+			for j, name := range names {
+				// Allowlists may annotate identifiers, e.g. "MIT # approved 2021".
+				names[j] = strings.TrimSpace(strings.SplitN(name, "#", 2)[0])
+			}
+			if imp.Format == importAllowlist {
+				c.AllowedLicenses = append(c.AllowedLicenses, names...)
+			} else {
+				c.DisallowedLicenses = append(c.DisallowedLicenses, names...)
+			}
+		case importORT:
+			allowed, disallowed, err := importORTClassifications(path, imp.AllowedCategories, imp.DisallowedCategories)
+			if err != nil {
+				return fmt.Errorf("policyImports[%d]: %w", i, err)
+			}
+			c.AllowedLicenses = append(c.AllowedLicenses, allowed...)
+			c.DisallowedLicenses = append(c.DisallowedLicenses, disallowed...)
+		default:
+			return fmt.Errorf("policyImports[%d] has unknown format %q, want one of: %s, %s, %s", i, imp.Format, importAllowlist, importDenylist, importORT)
+		}
+	}
+	return nil
+}
+
+// importORTClassifications returns the licenses of the ORT license classifications at
+// path that are in one of allowedCategories, but none of disallowedCategories, and
+// those in one of disallowedCategories.
+func importORTClassifications(path string, allowedCategories, disallowedCategories []string) (allowed, disallowed []string, err error) {
+	if len(allowedCategories) == 0 && len(disallowedCategories) == 0 {
+		return nil, nil, fmt.Errorf("%s: no allowedCategories or disallowedCategories to import", path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading ORT license classifications: %w", err)
+	}
+	var classifications ortLicenseClassifications
+	if err := yaml.Unmarshal(b, &classifications); err != nil {
+		return nil, nil, fmt.Errorf("parsing ORT license classifications %s: %w", path, err)
+	}
+	known := make(map[string]bool)
+	for _, c := range classifications.Categories {
+		known[c.Name] = true
+	}
+	for _, name := range append(append([]string(nil), allowedCategories...), disallowedCategories...) {
+		if !known[name] {
+			return nil, nil, fmt.Errorf("%s has no license category %q", path, name)
+		}
+	}
+	for _, c := range classifications.Categorizations {
+		switch {
+		case containsAny(c.Categories, disallowedCategories):
+			disallowed = append(disallowed, c.ID)
+		case containsAny(c.Categories, allowedCategories):
+			allowed = append(allowed, c.ID)
+		}
+	}
+	return allowed, disallowed, nil
+}
+
+// containsAny reports whether names contains any of want.
+func containsAny(names, want []string) bool {
+	for _, name := range names {
+		if isAllowedLicenseName(name, want) {
+			return true
+		}
+	}
+	return false
+}
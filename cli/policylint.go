@@ -0,0 +1,300 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var policyLintHelp = "Validates the license policy of the flags, config file and license overrides."
+
+// lintFinding is a problem of the policy found by policy lint.
+type lintFinding struct {
+	// warning is set for findings that don't fail the lint, e.g. because they depend on
+	// the module the policy is used with.
+	warning bool
+	message string
+}
+
+// newPolicyCmd returns the policy command, which groups the commands about the policy.
+func newPolicyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Works with the license policy used by check and report.",
+	}
+	cmd.AddCommand(newPolicyLintCmd())
+	return cmd
+}
+
+// newPolicyLintCmd returns the policy lint command.
+func newPolicyLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: policyLintHelp,
+		Long: policyLintHelp + `
+
+Fails for unknown SPDX license identifiers and license types, licenses both allowed
+and disallowed, exceptions that are expired, incomplete or unreachable because an
+earlier exception matches the same modules and licenses, and malformed module
+patterns. In a module, module patterns that match no module of its build list are
+reported as warnings, as are rules that have no effect.`,
+		Args: cobra.NoArgs,
+		RunE: policyLintMain,
+	}
+	cmd.Flags().StringSliceVar(&allowedLicenses, "allowed_licenses", []string{}, "list of allowed license names, as for check")
+	cmd.Flags().StringSliceVar(&disallowedTypes, "disallowed_types", []string{}, "list of disallowed license types, as for check")
+	cmd.Flags().StringSliceVar(&disallowedLicenses, "disallowed_licenses", []string{}, "list of disallowed license names, as for check")
+	return cmd
+}
+
+func policyLintMain(_ *cobra.Command, _ []string) error {
+	findings := lintPolicy(time.Now())
+	if _, err := os.Stat("go.mod"); err == nil {
+		modules, err := licenses.ListModules(runContext(), ".")
+		if err != nil {
+			return err
+		}
+		findings = append(findings, lintUnmatchedPatterns(modules)...)
+	}
+	errs := 0
+	for _, f := range findings {
+		level := "error"
+		if f.warning {
+			level = "warning"
+		} else {
+			errs++
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", level, f.message); err != nil {
+			return err
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("found %d errors in the policy", errs)
+	}
+	return nil
+}
+
+// lintPolicy returns the problems of the current policy at now that don't depend on the
+// module it is used with.
+func lintPolicy(now time.Time) []lintFinding {
+	var findings []lintFinding
+	fail := func(format string, args ...interface{}) {
+		findings = append(findings, lintFinding{message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(format string, args ...interface{}) {
+		findings = append(findings, lintFinding{warning: true, message: fmt.Sprintf(format, args...)})
+	}
+
+	policy, err := currentPolicy()
+	if err != nil {
+		fail("%v", err)
+	}
+	for _, name := range policy.allowedNames {
+		if !isKnownLicense(name) {
+			fail("allowed license %q is not a known SPDX license identifier", name)
+		}
+		if isAllowedLicenseName(name, policy.disallowedNames) {
+			fail("license %s is both allowed and disallowed", name)
+		}
+	}
+	for _, name := range policy.disallowedNames {
+		if !isKnownLicense(name) {
+			fail("disallowed license %q is not a known SPDX license identifier", name)
+		}
+		if isDisallowedLicenseType(licenses.LicenseType(name), policy.disallowedTypes) {
+			warn("disallowed license %s has no effect, its type %s is disallowed", name, licenses.LicenseType(name))
+		}
+	}
+	types := disallowedTypes
+	if len(types) == 0 {
+		types = cfg.DisallowedTypes
+	}
+	for _, t := range types {
+		if !isLicenseTypeName(t) {
+			fail("disallowed type %q is not a license type, want one of: forbidden, notice, permissive, reciprocal, restricted, unencumbered, unknown", t)
+		}
+	}
+	for _, name := range cfg.PreferredLicenses {
+		if !isKnownLicense(name) {
+			fail("preferred license %q is not a known SPDX license identifier", name)
+		} else if reason, _ := policy.denial(license{name: name, typ: licenses.LicenseType(name)}); reason != "" {
+			warn("preferred license %s has no effect, the policy doesn't allow it", name)
+		}
+	}
+	for name := range cfg.LicenseConfidenceThresholds {
+		if !isKnownLicense(name) {
+			fail("licenseConfidenceThresholds: %q is not a known SPDX license identifier", name)
+		}
+	}
+
+	ids := make(map[string]bool)
+	for i, e := range policy.exceptions {
+		what := fmt.Sprintf("exception %s", e.ID)
+		if e.ID == "" {
+			what = fmt.Sprintf("policyExceptions[%d]", i)
+			fail("%s has no id", what)
+		} else if ids[e.ID] {
+			fail("%s is defined more than once", what)
+		}
+		ids[e.ID] = true
+		if e.Module == "" {
+			fail("%s has no module", what)
+		} else if !isValidModulePattern(e.Module) {
+			fail("%s has a malformed module pattern %q", what, e.Module)
+		}
+		if e.expired(now) {
+			fail("%s expired on %s", what, e.Expires)
+		}
+		for _, name := range e.Licenses {
+			if !isKnownLicense(name) {
+				fail("%s: %q is not a known SPDX license identifier", what, name)
+			} else if reason, _ := policy.denial(license{name: name, typ: licenses.LicenseType(name)}); reason == "" {
+				warn("%s has no effect for %s, the policy allows it", what, name)
+			}
+		}
+		for _, earlier := range policy.exceptions[:i] {
+			if coversPattern(earlier.Module, e.Module) && coversLicenses(earlier.Licenses, e.Licenses) {
+				fail("%s is unreachable, exception %s before it matches the same modules and licenses", what, earlier.ID)
+				break
+			}
+		}
+	}
+
+	for i, o := range licenseOverrides {
+		for _, id := range licenses.ExpressionLicenseIDs(o.License) {
+			if !isKnownLicense(id) {
+				fail("license override for %s: %q is not a known SPDX license identifier", o.Module, id)
+			}
+		}
+		if !isValidModulePattern(o.Module) {
+			fail("license override for %s has a malformed module pattern", o.Module)
+		}
+		for _, later := range licenseOverrides[i+1:] {
+			if coversPattern(later.Module, o.Module) && !later.Fallback {
+				fail("license override for %s is unreachable, the later override for %s applies to the same modules", o.Module, later.Module)
+				break
+			}
+		}
+	}
+	for _, m := range cfg.AllowedModules {
+		if !isValidModulePattern(m) {
+			fail("allowedModules: malformed module pattern %q", m)
+		}
+	}
+	for _, o := range cfg.ModuleOverrides {
+		if !isValidModulePattern(o.Module) {
+			fail("moduleOverrides: malformed module pattern %q", o.Module)
+		}
+	}
+	return findings
+}
+
+// lintUnmatchedPatterns warns about the module patterns of the policy that match none
+// of modules, which are likely outdated or misspelled.
+func lintUnmatchedPatterns(modules []*licenses.Module) []lintFinding {
+	var findings []lintFinding
+	check := func(what, pattern string) {
+		if !isValidModulePattern(pattern) {
+			// Reported by lintPolicy already.
+			return
+		}
+		for _, m := range modules {
+			if isAllowedModule(m, []string{pattern}) {
+				return
+			}
+		}
+		findings = append(findings, lintFinding{warning: true, message: fmt.Sprintf("%s: module pattern %q matches no module in the build list", what, pattern)})
+	}
+	for _, m := range cfg.AllowedModules {
+		check("allowedModules", m)
+	}
+	for _, e := range cfg.PolicyExceptions {
+		check("exception "+e.ID, e.Module)
+	}
+	for _, o := range cfg.ModuleOverrides {
+		check("moduleOverrides", o.Module)
+	}
+	for _, o := range licenseOverrides {
+		check("license overrides", o.Module)
+	}
+	return findings
+}
+
+// isKnownLicense reports whether name is a license identifier known to the classifier,
+// optionally with an exception, or a custom LicenseRef- identifier.
+func isKnownLicense(name string) bool {
+	return strings.HasPrefix(name, "LicenseRef-") || licenses.LicenseType(name) != licenses.Unknown
+}
+
+// isLicenseTypeName reports whether name is a license type accepted by --disallowed_types.
+func isLicenseTypeName(name string) bool {
+	switch strings.TrimSpace(strings.ToLower(name)) {
+	case "forbidden", "notice", "permissive", "reciprocal", "restricted", "unencumbered", "unknown":
+		return true
+	}
+	return false
+}
+
+// isValidModulePattern reports whether the path of the module pattern p, before any
+// "@version", is a well-formed path.Match pattern.
+func isValidModulePattern(p string) bool {
+	p, _ = splitModulePattern(p)
+	_, err := path.Match(p, "")
+	return err == nil
+}
+
+// coversPattern reports whether every module matched by the module pattern b is also
+// matched by a. It only detects the common cases, e.g. a wildcard covering a path.
+func coversPattern(a, b string) bool {
+	aPath, aVersion := splitModulePattern(a)
+	bPath, bVersion := splitModulePattern(b)
+	if aVersion != "" && aVersion != bVersion {
+		return false
+	}
+	ok, _ := path.Match(aPath, bPath)
+	return ok
+}
+
+// splitModulePattern splits a module pattern into its path and version, if any.
+func splitModulePattern(p string) (string, string) {
+	if i := strings.Index(p, "@"); i >= 0 {
+		return p[:i], p[i+1:]
+	}
+	return p, ""
+}
+
+// coversLicenses reports whether the licenses of an exception, a, include all of b. An
+// empty list stands for all licenses.
+func coversLicenses(a, b []string) bool {
+	if len(a) == 0 {
+		return true
+	}
+	if len(b) == 0 {
+		return false
+	}
+	for _, name := range b {
+		if !isAllowedLicenseName(name, a) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+)
+
+// Phases of a run measured by --profile_phases, in the order they are printed.
+const (
+	phaseLoading        = "package loading"
+	phaseClassification = "classification"
+	phaseURLs           = "URL resolution and downloads"
+	phaseRendering      = "rendering"
+)
+
+var phases = []string{phaseLoading, phaseClassification, phaseURLs, phaseRendering}
+
+// phaseProfile is the time spent in each phase of a run.
+type phaseProfile struct {
+	start time.Time
+
+	mu        sync.Mutex
+	durations map[string]time.Duration
+	counts    map[string]int
+}
+
+var (
+	// profilePhases prints the time spent in each phase at the end of a run.
+	profilePhases bool
+	// profile is the profile of this run, nil unless --profile_phases is set.
+	profile *phaseProfile
+)
+
+// addProfileFlags adds the profiling flags shared by all commands to flags.
+func addProfileFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&profilePhases, "profile_phases", false, "Print to stderr how much time was spent loading packages, classifying licenses, resolving URLs and downloading license texts, and rendering the output, with suggestions for flags and caches that would make the run faster.")
+}
+
+// setUpProfile starts profiling the run if --profile_phases is set.
+func setUpProfile() {
+	profile = nil
+	if profilePhases {
+		profile = &phaseProfile{start: time.Now(), durations: make(map[string]time.Duration), counts: make(map[string]int)}
+	}
+}
+
+// timePhase starts measuring a step of phase and returns the function that ends it. Steps
+// may run concurrently, e.g. classifications by the workers of report, in which case
+// their durations add up to more than the time the phase took.
+func timePhase(phase string) func() {
+	p := profile
+	if p == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.durations[phase] += d
+		p.counts[phase]++
+	}
+}
+
+// fileURL returns the URL of the file at path in lib, measured as URL resolution.
+func fileURL(ctx context.Context, lib *licenses.Library, path string) (string, error) {
+	defer timePhase(phaseURLs)()
+	return lib.FileURL(ctx, path)
+}
+
+// printProfile prints the profile of the run to w, if profiling.
+func printProfile(w io.Writer) error {
+	p := profile
+	if p == nil {
+		return nil
+	}
+	total := time.Since(p.start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Run took %v. Time per phase:\n", total.Round(time.Millisecond))
+	for _, phase := range phases {
+		d, n := p.durations[phase], p.counts[phase]
+		steps := "steps"
+		if n == 1 {
+			steps = "step"
+		}
+		fmt.Fprintf(&b, "  %-30s %10v  %3.0f%%  (%d %s)\n", phase, d.Round(time.Millisecond), 100*d.Seconds()/total.Seconds(), n, steps)
+	}
+	b.WriteString("Steps of concurrent phases add up, so a phase may take more than 100%.\n")
+	for _, s := range p.suggestions(total) {
+		fmt.Fprintf(&b, "Suggestion: %s\n", s)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// suggestions returns the flags and caches that would speed up the phases that took more
+// than a quarter of total. p.mu must be held.
+func (p *phaseProfile) suggestions(total time.Duration) []string {
+	slow := func(phase string) bool { return p.durations[phase] > total/4 }
+	var s []string
+	if slow(phaseLoading) {
+		s = append(s, "loading packages is slow. Run \"go mod download\" and keep GOCACHE between runs, or use --modules_only or --go_sum_only, which don't load packages but may report modules that are not imported.")
+	}
+	if slow(phaseClassification) {
+		if cache == nil {
+			s = append(s, "classifying licenses is slow. Leave out --no_cache to reuse the classifications of earlier runs.")
+		} else {
+			s = append(s, "classifying licenses is slow. Keep the cache directory between runs, e.g. in CI, and lower --max_license_file_size if license files are large.")
+		}
+	}
+	if slow(phaseURLs) {
+		switch {
+		case cache == nil:
+			s = append(s, "resolving URLs is slow. Leave out --no_cache to reuse the URLs and license texts of earlier runs.")
+		case !offline:
+			s = append(s, "resolving URLs is slow. Raise --concurrency, set GITHUB_TOKEN to avoid rate limits, or use --offline to derive URLs from the module cache without network access.")
+		}
+		if downloadLicenseTexts {
+			s = append(s, "--download_license_texts downloads every license text, leave it out to read them from the module cache.")
+		}
+	}
+	return s
+}
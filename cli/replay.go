@@ -0,0 +1,130 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+)
+
+var (
+	// recordDir is where the HTTP interactions of a run are recorded to.
+	recordDir string
+	// replayDir is where the HTTP interactions replayed instead of using the network are read from.
+	replayDir string
+)
+
+// addReplayFlags adds the replay flags shared by all commands to flags.
+func addReplayFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&recordDir, "record", "", "Record all HTTP interactions, e.g. module info lookups and license downloads, as fixtures in this directory.")
+	flags.StringVar(&replayDir, "replay", "", "Replay the HTTP interactions recorded with --record from this directory instead of using the network. Requests that were not recorded fail.")
+}
+
+// withRecording records the HTTP interactions of base if --record is set, or replays
+// them instead of using base if --replay is set.
+func withRecording(base http.RoundTripper) (http.RoundTripper, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, errors.New("--record and --replay can't be used at the same time")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			return nil, err
+		}
+		return &recordingTransport{dir: recordDir, base: base}, nil
+	case replayDir != "":
+		return &recordingTransport{dir: replayDir}, nil
+	}
+	return base, nil
+}
+
+// interaction is a recorded HTTP request and its response.
+type interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// recordingTransport records the interactions of base in dir. If base is nil, it replays
+// them from dir instead.
+type recordingTransport struct {
+	dir  string
+	base http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := filepath.Join(t.dir, interactionKey(req)+".json")
+	if t.base == nil {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("no recorded response for %s %s: %w", req.Method, req.URL, err)
+		}
+		var i interaction
+		if err := json.Unmarshal(b, &i); err != nil {
+			return nil, fmt.Errorf("reading recorded response %s: %w", path, err)
+		}
+		return i.response(req), nil
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		// Failures are not recorded, replaying them would hide that the fixtures are incomplete.
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	i := interaction{Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Header: resp.Header, Body: body}
+	b, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return nil, err
+	}
+	return i.response(req), nil
+}
+
+// interactionKey identifies the interaction of req by its method and URL.
+func interactionKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (i interaction) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Header,
+		Body:          io.NopCloser(bytes.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,1141 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+const (
+	UNKNOWN = "Unknown"
+)
+
+var (
+	reportHelp = "Prints report of all licenses that apply to one or more Go packages and their dependencies."
+	// outputFormat selects how the report is printed when no template is used.
+	outputFormat string
+	templateFile string
+	// templateDir holds additional template files, e.g. partials, available to templateFile.
+	templateDir string
+	// htmlTemplate selects html/template instead of text/template to render templateFile.
+	htmlTemplate bool
+	// listIgnored adds an appendix of the packages left out by ignore rules.
+	listIgnored bool
+	// shortNameStyles configure how ShortName is derived from a library's name.
+	shortNameStyles []string
+	// graphFormat selects the format of the dependency graph printed instead of the report.
+	graphFormat string
+	// testsOutputPath is the file that the report of libraries only used by tests is
+	// written to, instead of being part of the main report.
+	testsOutputPath string
+	// filterCategories restricts the report to libraries with these license types, if set.
+	filterCategories []string
+	// failOnClassifyError fails the command if any license file could not be classified.
+	failOnClassifyError bool
+	// downloadLicenseTexts downloads license texts from their URLs instead of reading the
+	// license files.
+	downloadLicenseTexts bool
+	// verifyVanityURLs checks that license URLs on another host than the module path exist.
+	verifyVanityURLs bool
+	// placeholderTemplate renders the license texts that can't be downloaded, and
+	// placeholderHosts are the hosts whose license texts aren't downloaded.
+	placeholderTemplate string
+	placeholderHosts    []string
+	// deadline stops processing libraries after this duration, if set, so that the
+	// libraries processed so far are reported.
+	deadline time.Duration
+	// unprocessedLibraries are the libraries not processed before the deadline, as
+	// name@version.
+	unprocessedLibraries []string
+	// mergeMajorVersions merges libraries that only differ in their module's major version.
+	mergeMajorVersions bool
+
+	// reportConcurrency is the number of libraries processed concurrently.
+	reportConcurrency int
+
+	// classifyErrors are the license files that identifyLicense failed to classify,
+	// guarded by classifyErrorsMu.
+	classifyErrors   []classifyError
+	classifyErrorsMu sync.Mutex
+)
+
+// classifyError is a license file that the classifier failed on.
+type classifyError struct {
+	path string
+	err  error
+}
+
+// newReportCmd returns the report command.
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report <package> [package...]",
+		Short: reportHelp,
+		Long:  reportHelp + packageHelp,
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  reportMain,
+	}
+	cmd.Flags().StringVar(&outputFormat, "format", "csv", "Output format of the report, one of: csv, json, expression, modules, dep5, spdx, spdx-json, sw360, cyclonedx, cyclonedx-xml, attribution. The expression format prints the combined SPDX license expression of all libraries, the modules format prints the paths of the dependency modules, e.g. as baseline for check --fail_on_new_deps, dep5 prints a machine-readable debian/copyright file, and spdx and spdx-json print an SPDX 2.3 document in tag-value or JSON format, sw360 prints the dependencies as SW360 releases, cyclonedx and cyclonedx-xml print a CycloneDX 1.5 bill of materials in JSON or XML format, and attribution prints plain-text attributions to ship with binaries, with the license texts that are verbatim canonical copies printed once per license. Ignored when --template is used.")
+	cmd.Flags().StringVar(&templateFile, "template", "", "Custom Go template file to use for report")
+	cmd.Flags().StringVar(&templateDir, "template_dir", "", "Directory of additional Go template files, with the extension of --template, that it can include by file name or by the names they define")
+	cmd.Flags().BoolVar(&htmlTemplate, "html_template", false, "Render the custom template with html/template, escaping license data for HTML output. Defaults to true for template files ending in .html or .htm.")
+	cmd.Flags().StringSliceVar(&shortNameStyles, "short_name", []string{"strip_host"}, "How to shorten library names for the ShortName field of templates and JSON: full, or any of strip_host and strip_major_version, e.g. --short_name=strip_host,strip_major_version.")
+	cmd.Flags().BoolVar(&listIgnored, "list_ignored", false, "List the packages left out by --ignore and --ignore_subtree together with the rule that matched them, so that audits can verify the rules don't hide third-party code. Included in JSON output, printed to stderr for other formats.")
+	cmd.Flags().StringVar(&graphFormat, "graph", "", "Print the package dependency graph annotated with licenses instead of the report, one of: dot, json. In dot format, packages are colored by license type.")
+
+	cmd.Flags().StringVar(&testsOutputPath, "tests_output", "", "With --include_tests, write the libraries only imported by testing code to this file, in the same format, instead of the main report, so that they can be reviewed separately.")
+
+	cmd.Flags().StringSliceVar(&filterCategories, "filter_category", nil, "Only report libraries with these license types, e.g. restricted,reciprocal, for focused reviews. One or more of: forbidden, restricted, reciprocal, unknown, notice, permissive, unencumbered. (default: all types)")
+
+	// The policy flags of check, so that reports can include its verdict on each library.
+	cmd.Flags().StringSliceVar(&allowedLicenses, "allowed_licenses", []string{}, "list of allowed license names for the policy field, can't be used in combination with disallowed_types")
+	cmd.Flags().StringSliceVar(&disallowedTypes, "disallowed_types", []string{}, "list of disallowed license types for the policy field, can't be used in combination with allowed_licenses (default: forbidden, unknown)")
+	cmd.Flags().StringSliceVar(&disallowedLicenses, "disallowed_licenses", []string{}, "list of disallowed license names for the policy field, checked in addition to allowed_licenses or disallowed_types")
+
+	cmd.Flags().BoolVar(&mergeMajorVersions, "merge_major_versions", false, "Merge libraries whose names only differ in the major version of their module, e.g. foo and foo/v2, into a single entry listing all versions, if they have the same license. Keeps attributions readable. Not supported by the SBOM formats.")
+	cmd.Flags().BoolVar(&verifyVanityURLs, "verify_vanity_urls", false, "Check that license URLs on another host than their module path, e.g. for vanity import paths or moved repositories, can be fetched, and list these mappings after the report.")
+	cmd.Flags().DurationVar(&deadline, "deadline", 0, "Stop processing libraries after this duration, e.g. 5m, print the report of the libraries processed so far and list the others, then fail. Packages must be loaded within the deadline. (default: no deadline)")
+	cmd.Flags().BoolVar(&downloadLicenseTexts, "download_license_texts", false, "Download the license texts of templates and the JSON report from the raw URLs of license files on GitHub, instead of reading the license files that were classified. Libraries whose download fails are left out of the report.")
+	cmd.Flags().StringVar(&placeholderTemplate, "placeholder", "<PLACEHOLDER_{{.LicenseName}}>", "Go template of the license text of templates and the JSON report for libraries whose license text can't be downloaded, with the fields of the library, e.g. \"License text available at {{.LicenseURL}}\".")
+	cmd.Flags().StringSliceVar(&placeholderHosts, "placeholder_hosts", nil, "Hosts of license URLs, e.g. cs.opensource.google, whose license texts are replaced by --placeholder instead of downloaded. License texts on other hosts are downloaded from their license URL, or from the raw file on GitHub. (default: all hosts except github.com)")
+	cmd.Flags().IntVar(&reportConcurrency, "concurrency", runtime.NumCPU(), "Number of libraries whose license files are classified and whose URLs and license texts are resolved concurrently. The report lists libraries in the same order regardless.")
+	cmd.Flags().BoolVar(&failOnClassifyError, "fail_on_classify_error", false, "Fail after printing the report if any license file could not be classified. Such libraries are reported with an Unknown license, and the errors are listed at the end either way.")
+
+	return cmd
+}
+
+type libraryData struct {
+	Name        string `json:"name"`
+	ShortName   string `json:"shortName"`
+	LicenseURL  string `json:"licenseURL"`
+	LicenseName string `json:"licenseName"`
+	Version     string `json:"version"`
+	License     string `json:"license"`
+	// Notice is the content of the library's NOTICE file, which must be reproduced
+	// verbatim in attributions, and NoticeURL where it can be viewed.
+	Notice    string `json:"notice,omitempty"`
+	NoticeURL string `json:"noticeURL,omitempty"`
+	// LicenseFiles are the files whose terms apply along with the license file, e.g.
+	// NOTICE and PATENTS files. The texts of further NOTICE files are part of Notice too.
+	LicenseFiles []licenseFileData `json:"licenseFiles,omitempty"`
+	// LicensePartiallyScanned is true if the license file exceeded --max_license_file_size,
+	// so that only part of it was classified.
+	LicensePartiallyScanned bool `json:"licensePartiallyScanned,omitempty"`
+	// LicenseCandidates are the files considered when no license file was found.
+	LicenseCandidates []licenses.LicenseCandidate `json:"licenseCandidates,omitempty"`
+	// LicenseLanguage is the detected language of a license text that is not in English,
+	// which the classifier cannot identify reliably.
+	LicenseLanguage string `json:"licenseLanguage,omitempty"`
+	// LicenseChoices are the licenses that the library lets licensees choose among, if
+	// any. LicenseName is then their SPDX expression, e.g. "Apache-2.0 OR MIT".
+	LicenseChoices []string `json:"licenseChoices,omitempty"`
+	// LicenseChosen is the license of LicenseChoices that the policy applies, see
+	// preferredLicenses in the config file. It is empty if the policy allows none of them.
+	LicenseChosen string `json:"licenseChosen,omitempty"`
+	// FileLicenseMismatches are the files whose SPDX header declares another license than
+	// the library's, see --scan_file_licenses.
+	FileLicenseMismatches []fileLicenseData `json:"fileLicenseMismatches,omitempty"`
+	// Copyrights are the copyright statements in the license file and, with
+	// --scan_copyrights, in the headers of the library's Go files, without duplicates.
+	Copyrights []copyrightData `json:"copyrights,omitempty"`
+	// LicenseInComment is true if the license was found in the header comment of a Go
+	// file, e.g. doc.go, because the library has no license file.
+	LicenseInComment bool `json:"licenseInComment,omitempty"`
+	// ModuleLevel is true if the library is a module that none of the loaded packages
+	// belongs to, see --include_asset_modules.
+	ModuleLevel bool `json:"moduleLevel,omitempty"`
+	// TestOnly is true if the library is only imported by testing code, see --include_tests.
+	TestOnly bool `json:"testOnly,omitempty"`
+	// Direct is true if the library is a direct dependency of the main module.
+	Direct bool `json:"direct,omitempty"`
+	// RequiredAt is the require directive of the library's module in go.mod, if it is a
+	// direct dependency, with the slash-separated path of go.mod relative to the working
+	// directory, e.g. to annotate that line in code review.
+	RequiredAt *licenses.GoModLocation `json:"requiredAt,omitempty"`
+	// Internal is true if the library's module is under one of --trusted_domains.
+	Internal bool `json:"internal,omitempty"`
+	// Origin is "verified" if the go command verifies the module against the checksum
+	// database and "unverified" otherwise, e.g. for private modules in GONOSUMDB.
+	Origin string `json:"origin,omitempty"`
+	// ChecksumDatabase is the checksum database that verifies the module, e.g.
+	// sum.golang.org, and ChecksumExclusion why the module is not verified otherwise, e.g.
+	// gonosumdb. See licenses.Module.ChecksumExclusion.
+	ChecksumDatabase  string `json:"checksumDatabase,omitempty"`
+	ChecksumExclusion string `json:"checksumExclusion,omitempty"`
+	// Replaces is the module, as path@version, that the library's module replaces if it
+	// is a fork, and UpstreamLicenseName the license of the library in that module.
+	Replaces            string `json:"replaces,omitempty"`
+	UpstreamLicenseName string `json:"upstreamLicenseName,omitempty"`
+	// LicenseDiffersFromUpstream is true if the fork is licensed differently than the
+	// module it replaces.
+	LicenseDiffersFromUpstream bool `json:"licenseDiffersFromUpstream,omitempty"`
+	// BadgeLicenses are the licenses declared by license badges in the README next to the
+	// license file, and LicenseDisagreesWithBadge is true if none of them is LicenseName.
+	BadgeLicenses             []string `json:"badgeLicenses,omitempty"`
+	LicenseDisagreesWithBadge bool     `json:"licenseDisagreesWithBadge,omitempty"`
+	// MergedNames are the names of the libraries merged into this one by
+	// --merge_major_versions, e.g. foo and foo/v2, whose versions Version lists in order.
+	MergedNames []string `json:"mergedNames,omitempty"`
+	// Policy is the verdict of the check policy on the library: allowed, denied,
+	// needs-review if its license type is unknown but tolerated by maxUnknown, or
+	// exception:<id> if a policy exception allows it.
+	Policy string `json:"policy"`
+	// Homepage is the URL of the project's website, if configured in moduleOverrides.
+	Homepage string `json:"homepage,omitempty"`
+	// RepoHost is the host of LicenseURL if it differs from the host of the module path,
+	// e.g. github.com for a vanity import path like gopkg.in/yaml.v2.
+	RepoHost string `json:"repoHost,omitempty"`
+	// ModulePath is the path of the library's module, if known, and LicensePath the
+	// local license file that was classified, if any.
+	ModulePath  string `json:"modulePath,omitempty"`
+	LicensePath string `json:"licensePath,omitempty"`
+
+	// module is the module of the library, if any, for formats that describe modules.
+	module *licenses.Module
+}
+
+// copyrightData is a copyright statement of a library, e.g. "Copyright (c) 2016 Foo".
+type copyrightData struct {
+	Statement string `json:"statement"`
+	// Years are the years as written, e.g. "2016-2018", and Holder the copyright holder,
+	// if the statement names one.
+	Years  string `json:"years"`
+	Holder string `json:"holder,omitempty"`
+	// Path is the slash-separated path of the file the statement was found in, relative
+	// to the module root.
+	Path string `json:"path"`
+}
+
+// licenseFileData is a file whose terms apply along with the license file of a library.
+type licenseFileData struct {
+	// Path is the slash-separated path of the file relative to the license file.
+	Path string `json:"path"`
+	URL  string `json:"url,omitempty"`
+	// Text is the content of the file, in the formats that contain license texts.
+	Text string `json:"text,omitempty"`
+}
+
+// jsonReportSchemaVersion is the version of the JSON report format. It is incremented
+// when fields are removed or change their meaning, but not when fields are added.
+const jsonReportSchemaVersion = 1
+
+// jsonReport is the document printed by --format=json.
+type jsonReport struct {
+	// SchemaVersion is jsonReportSchemaVersion for reports printed by this version.
+	SchemaVersion int         `json:"schemaVersion"`
+	Metadata      runMetadata `json:"metadata"`
+	// Classifier describes the classifier that identified the licenses of all libraries.
+	Classifier licenses.ClassifierInfo `json:"classifier"`
+	// LicenseExpression is the SPDX expression covering all libraries together.
+	LicenseExpression string `json:"licenseExpression"`
+	// IgnoreRules are the rules that left packages out of the report.
+	IgnoreRules []licenses.IgnoreRule `json:"ignoreRules,omitempty"`
+	Libraries   []libraryData         `json:"libraries"`
+	// Ignored lists the packages left out by IgnoreRules, if requested with --list_ignored.
+	Ignored []licenses.IgnoredPackage `json:"ignored,omitempty"`
+	// Unprocessed lists the libraries, as name@version, that are missing because
+	// --deadline was exceeded.
+	Unprocessed []string `json:"unprocessed,omitempty"`
+}
+
+func reportMain(cmd *cobra.Command, args []string) error {
+	if offline && verifyVanityURLs {
+		return errors.New("--verify_vanity_urls can't be used with --offline")
+	}
+	if offline && downloadLicenseTexts {
+		return errors.New("--download_license_texts can't be used with --offline")
+	}
+	if reportConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", reportConcurrency)
+	}
+	placeholder, err := parsePlaceholder()
+	if err != nil {
+		return err
+	}
+	metadata := newRunMetadata(cmd, time.Now(), args)
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+
+	ctx := licenses.WithWarningHandler(runContext(), recordWarning)
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	libs, err := libraries(ctx, classifier, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("--deadline of %v exceeded while loading packages: %w", deadline, err)
+		}
+		return err
+	}
+	if graphFormat != "" {
+		if err := reportGraph(classifier, libs); err != nil {
+			return err
+		}
+		return reportClassifyErrors()
+	}
+	if outputFormat == "modules" && templateFile == "" {
+		// Module paths don't need license data.
+		return reportModules(libs)
+	}
+
+	style, err := shortNameStyle()
+	if err != nil {
+		return err
+	}
+	// Only templates, the JSON and the attribution report contain license texts. Other
+	// formats don't download them, so that the CSV report has the same rows as upstream
+	// go-licenses even if downloads fail.
+	withLicenseText := templateFile != "" || outputFormat == "json" || outputFormat == "attribution"
+	categories, err := reportCategories(filterCategories)
+	if err != nil {
+		return err
+	}
+	policy, err := currentPolicy()
+	if err != nil {
+		return err
+	}
+	reporter := libraryReporter{
+		classifier:      classifier,
+		style:           style,
+		withLicenseText: withLicenseText,
+		categories:      categories,
+		policy:          policy,
+		placeholder:     placeholder,
+	}
+	var reportData []libraryData
+	for i, result := range reporter.reportAll(ctx, libs) {
+		switch {
+		case result.err != nil:
+			return result.err
+		case result.unprocessed:
+			unprocessedLibraries = append(unprocessedLibraries, libraryID(libs[i]))
+		case result.included:
+			reportData = append(reportData, result.data)
+		}
+	}
+	applyModuleOverrides(reportData, cfg.ModuleOverrides)
+	if mergeMajorVersions {
+		if templateFile == "" && sbomFormats[outputFormat] {
+			return fmt.Errorf("--merge_major_versions can't be used with --format=%s, which lists each module version", outputFormat)
+		}
+		reportData = mergeMajorVersionLibraries(reportData)
+	}
+	if listIgnored && (templateFile != "" || outputFormat != "json") {
+		if err := printIgnored(diagnostics, ignoredPackages); err != nil {
+			return err
+		}
+	}
+	if testsOutputPath != "" {
+		if !includeTests {
+			return fmt.Errorf("--tests_output requires --include_tests")
+		}
+		var runtimeData, testData []libraryData
+		for _, lib := range reportData {
+			if lib.TestOnly {
+				testData = append(testData, lib)
+			} else {
+				runtimeData = append(runtimeData, lib)
+			}
+		}
+		if err := renderTestsReport(cmd, metadata, classifier, testData); err != nil {
+			return err
+		}
+		reportData = runtimeData
+	}
+	if err := renderReport(cmd, metadata, classifier, reportData); err != nil {
+		return err
+	}
+	reportVanityURLs()
+	if err := reportClassifyErrors(); err != nil {
+		return err
+	}
+	return reportUnprocessed()
+}
+
+// libraryReporter collects the report data of libraries.
+type libraryReporter struct {
+	classifier licenses.Classifier
+	style      licenses.NameStyle
+	// withLicenseText is set for the formats that contain license texts.
+	withLicenseText bool
+	categories      map[licenses.Type]bool
+	policy          licensePolicy
+	// placeholder renders the license texts of libraries that can't be downloaded.
+	placeholder *template.Template
+}
+
+// libraryResult is the outcome of reporting a library.
+type libraryResult struct {
+	data libraryData
+	// included is false for libraries left out of the report, e.g. because of
+	// --filter_category or because their license text could not be downloaded.
+	included bool
+	// unprocessed is set for libraries that --deadline passed before or while they were
+	// processed.
+	unprocessed bool
+	err         error
+}
+
+// placeholderText renders the placeholder for the license text of lib.
+func (r libraryReporter) placeholderText(lib libraryData) (string, error) {
+	var b strings.Builder
+	if err := r.placeholder.Execute(&b, lib); err != nil {
+		return "", fmt.Errorf("rendering --placeholder for %s: %w", lib.Name, err)
+	}
+	return b.String(), nil
+}
+
+// parsePlaceholder parses --placeholder. It is executed for an empty library as well, so
+// that references to unknown fields fail before any library is processed.
+func parsePlaceholder() (*template.Template, error) {
+	tmpl, err := template.New("placeholder").Parse(placeholderTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --placeholder: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, libraryData{}); err != nil {
+		return nil, fmt.Errorf("parsing --placeholder: %w", err)
+	}
+	return tmpl, nil
+}
+
+// usesPlaceholder reports whether the license text at licenseURL is replaced by a
+// placeholder rather than downloaded from downloadURL: if its host is one of
+// --placeholder_hosts or, if none are set, if downloadURL isn't a raw file on GitHub.
+func usesPlaceholder(licenseURL, downloadURL string) bool {
+	if len(placeholderHosts) == 0 {
+		return !strings.HasPrefix(downloadURL, "https://raw.githubusercontent.com/")
+	}
+	u, err := url.Parse(licenseURL)
+	if err != nil {
+		return true
+	}
+	for _, host := range placeholderHosts {
+		if strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportAll reports libs with --concurrency workers, so that license files are
+// classified and URLs resolved in parallel. The results are in the order of libs.
+func (r libraryReporter) reportAll(ctx context.Context, libs []*licenses.Library) []libraryResult {
+	results := make([]libraryResult, len(libs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < reportConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = r.report(ctx, libs[i])
+			}
+		}()
+	}
+	for i := range libs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// report returns the report data of lib.
+func (r libraryReporter) report(ctx context.Context, lib *licenses.Library) libraryResult {
+	if ctx.Err() != nil {
+		return libraryResult{unprocessed: true}
+	}
+	version := lib.Version()
+	if len(version) == 0 {
+		version = UNKNOWN
+	}
+	libData := libraryData{
+		Name:              lib.Name(),
+		ShortName:         lib.DisplayName(r.style),
+		Version:           version,
+		LicenseURL:        UNKNOWN,
+		LicenseName:       UNKNOWN,
+		License:           UNKNOWN,
+		LicenseCandidates: lib.LicenseCandidates,
+		LicenseInComment:  lib.LicenseInComment(),
+		ModuleLevel:       lib.ModuleLevel,
+		TestOnly:          lib.TestOnly,
+		Direct:            lib.Direct,
+		RequiredAt:        requiredAt(lib),
+		Internal:          isTrusted(lib),
+		LicensePath:       lib.LicensePath,
+	}
+	name, typ := identifyLicense(r.classifier, lib)
+	if r.categories != nil && !r.categories[typ] {
+		return libraryResult{}
+	}
+	libData.LicenseName = name
+	libLicenses := []license{{name: name, typ: typ}}
+	override, overridden := licenseOverrideFor(lib)
+	if len(lib.ReuseLicenses) > 0 || overridden || hasLicenseChoice(lib) {
+		// REUSE, overridden and offered licenses are known already, so this doesn't run
+		// the classifier again.
+		var err error
+		if libLicenses, err = libraryLicenses(r.classifier, lib); err != nil {
+			return libraryResult{err: err}
+		}
+	}
+	if hasLicenseChoice(lib) {
+		libData.LicenseChoices = lib.LicenseChoices
+		if l, ok := r.policy.choose(libLicenses); ok {
+			libData.LicenseChosen = l.name
+		}
+	}
+	libData.Policy = policyDecision(r.policy.violations(lib, libLicenses))
+	libData.FileLicenseMismatches = fileLicensesData(ctx, lib, name, fileLicenseMismatches(lib, libLicenses))
+	libData.Copyrights = copyrightsData(lib)
+	if m := lib.Module(); m != nil {
+		libData.module = m
+		libData.ModulePath = m.Path
+		libData.Origin = "unverified"
+		if m.ChecksumVerified {
+			libData.Origin = "verified"
+		}
+		libData.ChecksumDatabase = m.ChecksumDB
+		libData.ChecksumExclusion = m.ChecksumExclusion
+		if m.IsFork() {
+			libData.Replaces = m.Replaces.Path + "@" + m.Replaces.Version
+		}
+	}
+	if lib.LicensePath != "" {
+		if fi, err := os.Stat(lib.LicensePath); err == nil {
+			libData.LicensePartiallyScanned = licenses.IsOversized(fi.Size(), maxLicenseFileSize)
+		}
+		if lang, err := licenses.LicenseLanguage(lib.LicensePath); err == nil && lang != "" && lang != "en" {
+			warnf(warningLicenseLanguage, libModulePath(lib), "License file %q appears to be in language %q, but the classifier only knows English license texts. Review it manually.", lib.LicensePath, lang)
+			libData.LicenseLanguage = lang
+		}
+		// The upstream modules of internal forks would be downloaded from public services.
+		if libData.Replaces != "" && !libData.Internal {
+			libData.UpstreamLicenseName, libData.LicenseDiffersFromUpstream = compareUpstreamLicense(r.classifier, lib, libData.LicenseName)
+		}
+		libData.BadgeLicenses, libData.LicenseDisagreesWithBadge = compareBadgeLicenses(lib, libData.LicenseName)
+		if lib.NoticePath != "" {
+			if b, err := os.ReadFile(lib.NoticePath); err != nil {
+				klog.Errorf("Error reading NOTICE file %q: %v", lib.NoticePath, err)
+			} else {
+				libData.Notice = string(b)
+			}
+			if url, err := fileURL(ctx, lib, lib.NoticePath); err == nil {
+				libData.NoticeURL = url
+			}
+		}
+		libData.LicenseFiles = r.licenseFiles(ctx, lib, &libData.Notice)
+		url, err := fileURL(ctx, lib, lib.LicensePath)
+		if override.URL != "" {
+			url, err = override.URL, nil
+		}
+		if err == nil {
+			emit(event{Event: eventURLResolved, Library: lib.Name(), URL: url})
+			if host, ok := vanityHost(libData.ModulePath, url); ok {
+				libData.RepoHost = host
+				if verifyVanityURLs {
+					verifyVanityURL(ctx, libData.ModulePath, url)
+				}
+			}
+		}
+		// License texts are read from the license file that was classified, unless
+		// they are to be downloaded or the file can't be read.
+		localText := false
+		if r.withLicenseText && !downloadLicenseTexts {
+			if b, rerr := os.ReadFile(lib.LicensePath); rerr != nil {
+				klog.Errorf("Error reading license file %q: %v", lib.LicensePath, rerr)
+			} else {
+				libData.License = string(b)
+				localText = true
+			}
+		}
+		if err == nil && (!r.withLicenseText || localText || offline) {
+			libData.LicenseURL = url
+		} else if err == nil {
+			libData.LicenseURL = url
+			// Only URLs on github.com, not e.g. on a Sourcegraph instance mirroring
+			// GitHub, have a raw counterpart to download the license text from.
+			if strings.HasPrefix(url, "https://github.com/") {
+				url = strings.Replace(url, "github.com", "raw.githubusercontent.com", 1)
+				url = strings.Replace(url, "blob/", "", 1)
+			}
+			if text, ok := cachedLicenseText(lib, url); ok {
+				libData.License = text
+			} else if !usesPlaceholder(libData.LicenseURL, url) {
+				resp, err := getURL(ctx, url)
+				if err != nil {
+					if ctx.Err() != nil {
+						return libraryResult{unprocessed: true}
+					}
+					if isTransient(err) {
+						klog.Errorf("Transient error downloading license file from: %s, err: %v. Try again later or with more --http_retries.", url, err)
+					} else {
+						klog.Errorf("Error downloading license file from: %s, err: %v", url, err)
+					}
+					return libraryResult{}
+				}
+				b, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					klog.Errorf("Error reading response body: %s, err: %v", url, err)
+					return libraryResult{}
+				}
+				libData.License = string(b)
+				if resp.StatusCode == http.StatusOK {
+					cacheLicenseText(lib, url, libData.License)
+				}
+			} else {
+				placeholder, err := r.placeholderText(libData)
+				if err != nil {
+					return libraryResult{err: err}
+				}
+				klog.Errorf("Could not download license file."+
+					" Go to\n %s \n and replace: %s for lib %s", url, placeholder, libData.ShortName)
+				libData.License = placeholder
+			}
+		} else if isTransient(err) {
+			warnf(warningLicenseURL, libModulePath(lib), "Transient error discovering license URL: %s. Try again later or with more --http_retries.", err)
+		} else {
+			warnf(warningLicenseURL, libModulePath(lib), "Error discovering license URL: %s", err)
+		}
+	} else if override.URL != "" {
+		libData.LicenseURL = override.URL
+	}
+	if ctx.Err() != nil {
+		// The deadline passed while resolving URLs, which may have failed because of it.
+		return libraryResult{unprocessed: true}
+	}
+	return libraryResult{data: libData, included: true}
+}
+
+// licenseFiles returns the files of lib.LicenseFiles other than its license file. The
+// texts of NOTICE files other than lib.NoticePath are appended to notice, each after its
+// path, since every NOTICE must be reproduced.
+func (r libraryReporter) licenseFiles(ctx context.Context, lib *licenses.Library, notice *string) []licenseFileData {
+	var files []licenseFileData
+	dir := filepath.Dir(lib.LicensePath)
+	for _, path := range lib.LicenseFiles {
+		if path == lib.LicensePath {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		f := licenseFileData{Path: filepath.ToSlash(rel)}
+		if url, err := fileURL(ctx, lib, path); err == nil {
+			f.URL = url
+		}
+		isNotice := licenses.IsNoticeFile(filepath.Base(path)) && path != lib.NoticePath
+		if r.withLicenseText || isNotice {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				klog.Errorf("Error reading license file %q: %v", path, err)
+				continue
+			}
+			if r.withLicenseText {
+				f.Text = string(b)
+			}
+			if isNotice {
+				if *notice != "" {
+					*notice += "\n\n"
+				}
+				*notice += fmt.Sprintf("%s:\n\n%s", f.Path, b)
+			}
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// requiredAt returns lib.RequiredAt with the slash-separated path of the go.mod file
+// relative to the working directory, or nil if lib has no require directive.
+func requiredAt(lib *licenses.Library) *licenses.GoModLocation {
+	if lib.RequiredAt == nil {
+		return nil
+	}
+	at := *lib.RequiredAt
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, at.File); err == nil && !strings.HasPrefix(rel, "..") {
+			at.File = rel
+		}
+	}
+	at.File = filepath.ToSlash(at.File)
+	return &at
+}
+
+// reportUnprocessed lists the libraries that were not processed before --deadline and
+// fails if there are any.
+func reportUnprocessed() error {
+	if len(unprocessedLibraries) == 0 {
+		return nil
+	}
+	diagnosticf(colorYellow, "--deadline of %v exceeded, %d libraries were not processed and are missing from the report:", deadline, len(unprocessedLibraries))
+	for _, id := range unprocessedLibraries {
+		diagnosticf(colorYellow, "  %s", id)
+	}
+	return fmt.Errorf("--deadline of %v exceeded, the report is incomplete", deadline)
+}
+
+// libraryID identifies lib as name@version, or by name if its version is unknown.
+func libraryID(lib *licenses.Library) string {
+	if v := lib.Version(); v != "" {
+		return lib.Name() + "@" + v
+	}
+	return lib.Name()
+}
+
+// getURL gets u, canceling the request when ctx is done. Statuses that remained transient
+// after --http_retries are returned as errors.
+func getURL(ctx context.Context, u string) (*http.Response, error) {
+	defer timePhase(phaseURLs)()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if transientStatus(resp.StatusCode) {
+		resp.Body.Close()
+		return nil, &transientError{attempts: httpRetries + 1, err: errors.New(resp.Status)}
+	}
+	return resp, nil
+}
+
+// sbomFormats are the output formats that describe each module version separately.
+var sbomFormats = map[string]bool{"spdx": true, "spdx-json": true, "sw360": true, "cyclonedx": true, "cyclonedx-xml": true}
+
+// mergeMajorVersionLibraries merges libraries whose names only differ in the major
+// version of their module, e.g. foo and foo/v2, and that have the same license and policy
+// decision. The merged library takes the name without major version and lists the
+// versions comma-separated, in the order of libs.
+func mergeMajorVersionLibraries(libs []libraryData) []libraryData {
+	var merged []libraryData
+	index := make(map[string]int)
+	for _, lib := range libs {
+		name := lib.Name
+		if lib.module != nil {
+			name = licenses.DisplayName(lib.Name, lib.module.Path, licenses.NameStyle{StripMajorVersion: true})
+		}
+		key := name + "\x00" + lib.LicenseName + "\x00" + lib.Policy
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, lib)
+			continue
+		}
+		m := &merged[i]
+		if len(m.MergedNames) == 0 {
+			m.MergedNames = []string{m.Name}
+		}
+		m.Name = name
+		if lib.Name == name {
+			m.ShortName = lib.ShortName
+		}
+		m.MergedNames = append(m.MergedNames, lib.Name)
+		m.Version += ", " + lib.Version
+		if lib.Notice != "" && !strings.Contains(m.Notice, lib.Notice) {
+			if m.Notice != "" {
+				m.Notice += "\n\n"
+			}
+			m.Notice += lib.Notice
+		}
+		m.TestOnly = m.TestOnly && lib.TestOnly
+		m.Direct = m.Direct || lib.Direct
+	}
+	return merged
+}
+
+// reportCategories parses --filter_category. It returns nil if no categories are given.
+func reportCategories(names []string) (map[licenses.Type]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	categories := make(map[licenses.Type]bool)
+	for _, name := range names {
+		// Types are matched by their printed names, which are lower case except for
+		// FORBIDDEN and "unknown" for the empty Unknown type.
+		found := false
+		for _, t := range licenseTypeSeverity {
+			if strings.EqualFold(t.String(), strings.TrimSpace(name)) {
+				categories[t] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown license type %q in --filter_category, want one of: forbidden, restricted, reciprocal, unknown, notice, permissive, unencumbered", name)
+		}
+	}
+	return categories, nil
+}
+
+// reportClassifyErrors lists all license files that could not be classified on stderr.
+// It returns an error if there were any and --fail_on_classify_error is set.
+func reportClassifyErrors() error {
+	if len(classifyErrors) == 0 {
+		return nil
+	}
+	// Libraries are classified concurrently, so the errors are collected in no particular order.
+	sort.Slice(classifyErrors, func(i, j int) bool { return classifyErrors[i].path < classifyErrors[j].path })
+	diagnosticf(colorYellow, "%d license files could not be classified and are reported as %s:", len(classifyErrors), UNKNOWN)
+	for _, e := range classifyErrors {
+		diagnosticf(colorYellow, "  %s: %v", e.path, e.err)
+	}
+	if failOnClassifyError {
+		return fmt.Errorf("%d license files could not be classified", len(classifyErrors))
+	}
+	return nil
+}
+
+// renderTestsReport renders the report of the libraries only imported by tests to
+// --tests_output.
+func renderTestsReport(cmd *cobra.Command, metadata runMetadata, classifier licenses.Classifier, reportData []libraryData) error {
+	f, err := os.Create(testsOutputPath)
+	if err != nil {
+		return fmt.Errorf("creating tests output file: %w", err)
+	}
+	mainOut := out
+	out = f
+	defer func() { out = mainOut }()
+	if err := renderReport(cmd, metadata, classifier, reportData); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// renderReport prints reportData to out, using the template or format set by flags.
+func renderReport(cmd *cobra.Command, metadata runMetadata, classifier licenses.Classifier, reportData []libraryData) error {
+	defer timePhase(phaseRendering)()
+	if templateFile != "" {
+		return reportTemplate(cmd, reportData)
+	}
+	if templateDir != "" {
+		return fmt.Errorf("--template_dir requires --template to select the template to execute")
+	}
+	switch outputFormat {
+	case "csv":
+		return reportCSV(reportData)
+	case "json":
+		return reportJSON(metadata, classifier, reportData)
+	case "expression":
+		_, err := fmt.Fprintln(out, aggregateExpression(reportData))
+		return err
+	case "dep5":
+		return reportDEP5(metadata, reportData)
+	case "spdx", "spdx-json":
+		return reportSPDX(metadata, reportData)
+	case "sw360":
+		return reportSW360(reportData)
+	case "cyclonedx", "cyclonedx-xml":
+		return reportCycloneDX(metadata, reportData)
+	case "attribution":
+		return reportAttribution(reportData)
+	default:
+		return fmt.Errorf("unknown --format %q, want one of: csv, json, expression, modules, dep5, spdx, spdx-json, sw360, cyclonedx, cyclonedx-xml, attribution", outputFormat)
+	}
+}
+
+// shortNameStyle returns the style configured with --short_name.
+func shortNameStyle() (licenses.NameStyle, error) {
+	var style licenses.NameStyle
+	for _, s := range shortNameStyles {
+		switch strings.TrimSpace(s) {
+		case "full":
+		case "strip_host":
+			style.StripHost = true
+		case "strip_major_version":
+			style.StripMajorVersion = true
+		default:
+			return style, fmt.Errorf("unknown --short_name %q, want full or any of: strip_host, strip_major_version", s)
+		}
+	}
+	return style, nil
+}
+
+// identifyLicense returns the license name and type of lib, or those of the license
+// override applied to it. The name is UNKNOWN if lib has no license file or its license
+// could not be identified.
+func identifyLicense(classifier licenses.Classifier, lib *licenses.Library) (string, licenses.Type) {
+	defer timePhase(phaseClassification)()
+	if o, ok := licenseOverrideFor(lib); ok {
+		typ := o.licenseType()
+		emitClassified(lib, o.License, typ)
+		return o.License, typ
+	}
+	if lib.LicensePath == "" {
+		emitClassified(lib, UNKNOWN, licenses.Unknown)
+		return UNKNOWN, licenses.Unknown
+	}
+	if hasLicenseChoice(lib) {
+		// Licensees may choose the least restrictive of the offered licenses.
+		name := strings.Join(lib.LicenseChoices, " OR ")
+		typ := expressionType(name)
+		emitClassified(lib, name, typ)
+		return name, typ
+	}
+	if len(lib.ReuseLicenses) > 0 {
+		// Licenses declared following the REUSE specification are authoritative.
+		var types []licenses.Type
+		for _, expr := range lib.ReuseLicenses {
+			for _, id := range licenses.ExpressionLicenseIDs(expr) {
+				types = append(types, licenses.LicenseType(id))
+			}
+		}
+		name, typ := licenses.AggregateExpression(lib.ReuseLicenses), mostRestrictive(types)
+		emitClassified(lib, name, typ)
+		return name, typ
+	}
+	name, typ, err := classifier.Identify(lib.LicensePath)
+	if err != nil {
+		klog.Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+		classifyErrorsMu.Lock()
+		classifyErrors = append(classifyErrors, classifyError{path: lib.LicensePath, err: err})
+		classifyErrorsMu.Unlock()
+		name, typ = UNKNOWN, licenses.Unknown
+	}
+	emitClassified(lib, name, typ)
+	return name, typ
+}
+
+// compareUpstreamLicense identifies the license of lib in the module that its module, a
+// fork, replaces and reports whether it differs from the fork's license name.
+func compareUpstreamLicense(classifier licenses.Classifier, lib *licenses.Library, name string) (string, bool) {
+	path, err := lib.UpstreamLicensePath(runContext(), classifier)
+	if err != nil {
+		warnf(warningUpstreamLicense, libModulePath(lib), "Error finding license of %s in upstream module %s: %v", lib.Name(), lib.Module().Replaces.Path, err)
+		return UNKNOWN, false
+	}
+	upstream, _, err := classifier.Identify(path)
+	if err != nil {
+		warnf(warningUpstreamLicense, libModulePath(lib), "Error identifying license in %q: %v", path, err)
+		return UNKNOWN, false
+	}
+	if upstream != name {
+		warnf(warningUpstreamLicense, libModulePath(lib), "%s is a fork of %s licensed under %s, but the upstream module is licensed under %s", lib.Name(), lib.Module().Replaces.Path, name, upstream)
+		return upstream, true
+	}
+	return upstream, false
+}
+
+// compareBadgeLicenses returns the licenses declared by badges in the README next to the
+// license file of lib and reports whether none of them agrees with licenseName, e.g.
+// because the license file wasn't updated when the project was relicensed.
+func compareBadgeLicenses(lib *licenses.Library, licenseName string) ([]string, bool) {
+	badges, err := licenses.BadgeLicenses(filepath.Dir(lib.LicensePath))
+	if err != nil {
+		klog.Warningf("Error reading license badges of %s: %v", lib.Name(), err)
+		return nil, false
+	}
+	if len(badges) == 0 || licenseName == UNKNOWN {
+		return badges, false
+	}
+	for _, name := range licenses.ExpressionLicenses(licenseName) {
+		for _, badge := range badges {
+			if licenses.MatchesBadge(name, badge) {
+				return badges, false
+			}
+		}
+	}
+	warnf(warningLicenseBadge, libModulePath(lib), "The README of %s has a license badge for %s, but its license file is classified as %s. The license file may be outdated, e.g. after a relicensing.", lib.Name(), strings.Join(badges, " and "), licenseName)
+	return badges, true
+}
+
+func reportCSV(libs []libraryData) error {
+	writer := csv.NewWriter(out)
+	for _, lib := range libs {
+		if err := writer.Write([]string{lib.Name, lib.LicenseURL, lib.LicenseName}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// reportModules prints the sorted paths of the dependency modules of libs, one per line.
+func reportModules(libs []*licenses.Library) error {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, lib := range libs {
+		if m := lib.Module(); m != nil && !m.Main && !seen[m.Path] {
+			seen[m.Path] = true
+			paths = append(paths, m.Path)
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if _, err := fmt.Fprintln(out, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reportJSON(metadata runMetadata, classifier licenses.Classifier, libs []libraryData) error {
+	report := jsonReport{
+		SchemaVersion:     jsonReportSchemaVersion,
+		Metadata:          metadata,
+		Classifier:        licenses.DescribeClassifier(classifier),
+		LicenseExpression: aggregateExpression(libs),
+		IgnoreRules:       ignoreRules(),
+		Libraries:         libs,
+	}
+	report.Unprocessed = unprocessedLibraries
+	if listIgnored {
+		report.Ignored = ignoredPackages
+	}
+	if report.Libraries == nil {
+		report.Libraries = []libraryData{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// printIgnored prints an appendix of the ignored packages and the rules that matched them.
+func printIgnored(w io.Writer, ignored []licenses.IgnoredPackage) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Ignored packages (%d):\n", len(ignored))
+	for _, p := range ignored {
+		module := p.Module
+		if module == "" {
+			module = UNKNOWN
+		}
+		fmt.Fprintf(&b, "  %s (module %s): matched prefix %s, mode %s\n", p.Package, module, p.Rule.Prefix, p.Rule.Mode)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// aggregateExpression returns the SPDX expression combining the licenses of all libs.
+func aggregateExpression(libs []libraryData) string {
+	var names []string
+	for _, lib := range libs {
+		names = append(names, lib.LicenseName)
+	}
+	return licenses.AggregateExpression(names)
+}
+
+func reportTemplate(cmd *cobra.Command, libs []libraryData) error {
+	files, err := templateFiles()
+	if err != nil {
+		return err
+	}
+	// Templates parsed from files are named after the file's base name, so this
+	// makes templateFile the one that is executed.
+	name := filepath.Base(templateFile)
+	if !isHTMLTemplate(cmd) {
+		tmpl, err := template.New(name).ParseFiles(files...)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(out, libs)
+	}
+	// html/template escapes data according to the HTML context it is rendered in.
+	tmpl, err := htmltemplate.New(name).ParseFiles(files...)
+	if err != nil {
+		return err
+	}
+	for i := range libs {
+		libs[i].License = sanitizeText(libs[i].License)
+	}
+	return tmpl.Execute(out, libs)
+}
+
+// templateFiles returns the files in templateDir followed by templateFile, which is
+// parsed last so that it takes precedence over a template file with the same name.
+// Only files with the extension of templateFile, or .tpl if it has none, are
+// included; dotfiles, READMEs and editor backups are skipped.
+func templateFiles() ([]string, error) {
+	if templateDir == "" {
+		return []string{templateFile}, nil
+	}
+	mainPath, err := filepath.Abs(templateFile)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(templateDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading template dir: %w", err)
+	}
+	ext := filepath.Ext(templateFile)
+	if ext == "" {
+		ext = ".tpl"
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != ext {
+			continue
+		}
+		path := filepath.Join(templateDir, e.Name())
+		if abs, err := filepath.Abs(path); err == nil && abs == mainPath {
+			continue
+		}
+		files = append(files, path)
+	}
+	return append(files, templateFile), nil
+}
+
+// isHTMLTemplate reports whether the custom template should be rendered with html/template.
+// An explicit --html_template flag wins over detection by file extension.
+func isHTMLTemplate(cmd *cobra.Command) bool {
+	if cmd != nil && cmd.Flags().Changed("html_template") {
+		return htmlTemplate
+	}
+	switch strings.ToLower(filepath.Ext(templateFile)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// sanitizeText replaces invalid UTF-8 and drops control characters other than
+// whitespace, which cannot be represented in HTML documents even when escaped.
+func sanitizeText(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
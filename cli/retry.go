@@ -0,0 +1,210 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// httpTimeout is the time after which an attempt of an HTTP request is given up.
+	httpTimeout time.Duration
+	// httpRetries is the number of times a request that failed transiently is retried.
+	httpRetries int
+	// httpBackoff is the delay before the first retry, which doubles for every further one.
+	httpBackoff time.Duration
+)
+
+// withRetries makes the HTTP requests of base time out after --http_timeout and retries
+// them after transient failures. It must wrap base before the transports of other flags
+// do, so that those see a request only once and retries only apply to network requests.
+func withRetries(base http.RoundTripper) (http.RoundTripper, error) {
+	if httpRetries < 0 {
+		return nil, fmt.Errorf("--http_retries must not be negative, got %d", httpRetries)
+	}
+	return &retryTransport{
+		base:    base,
+		timeout: httpTimeout,
+		retries: httpRetries,
+		backoff: httpBackoff,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// transientError is a request failure that may not occur when the request is made again
+// later, e.g. a timeout or 503 Service Unavailable, after all retries failed as well.
+type transientError struct {
+	attempts int
+	err      error
+}
+
+func (e *transientError) Error() string {
+	return fmt.Sprintf("%v (transient failure, gave up after %d attempts)", e.err, e.attempts)
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// isTransient reports whether err is a transient failure of an HTTP request.
+func isTransient(err error) bool {
+	var t *transientError
+	return errors.As(err, &t)
+}
+
+// transientStatus reports whether an HTTP response with status code may succeed when
+// the request is retried.
+func transientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500 && code != http.StatusNotImplemented
+}
+
+// transientErr reports whether a request that failed with err may succeed when retried.
+// Hosts that don't exist and invalid certificates fail the same way every time.
+func transientErr(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var (
+		authorityErr   x509.UnknownAuthorityError
+		certificateErr x509.CertificateInvalidError
+		hostnameErr    x509.HostnameError
+	)
+	return !errors.As(err, &authorityErr) && !errors.As(err, &certificateErr) && !errors.As(err, &hostnameErr)
+}
+
+// retryTransport retries GET and HEAD requests of base that fail transiently, with
+// exponential backoff and jitter. Each attempt times out after timeout, unless it is 0.
+type retryTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.attempt(req)
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req)
+		if ctx.Err() != nil {
+			// The caller gave up, not the server.
+			return resp, err
+		}
+		var failure error
+		switch {
+		case err != nil && transientErr(err):
+			failure = err
+		case err == nil && transientStatus(resp.StatusCode):
+			failure = errors.New(resp.Status)
+		default:
+			return resp, err
+		}
+		if attempt > t.retries {
+			if err != nil {
+				return nil, &transientError{attempts: attempt, err: err}
+			}
+			// Callers decide what a status means, e.g. a 404 may be expected.
+			return resp, nil
+		}
+		delay := t.delay(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		klog.Infof("Retrying %s %s in %v after transient failure %d of %d: %v", req.Method, req.URL.Redacted(), delay.Round(time.Millisecond), attempt, t.retries+1, failure)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt makes req once, timing it out after t.timeout.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.Clone(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			err = fmt.Errorf("no response within --http_timeout of %v: %w", t.timeout, err)
+		}
+		return nil, err
+	}
+	// The timeout covers reading the body, so it is canceled once the body is closed.
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// maxRetryAfter limits how long a Retry-After header of a server can delay a retry.
+const maxRetryAfter = time.Minute
+
+// delay returns the time to wait before retrying after attempt failed: the backoff
+// doubled for each previous attempt, of which a random half is waited to spread out
+// the retries of concurrent requests, or the Retry-After time the server asked for if
+// that is longer, up to maxRetryAfter.
+func (t *retryTransport) delay(attempt int, resp *http.Response) time.Duration {
+	d := t.backoff << (attempt - 1)
+	if d > 0 {
+		t.mu.Lock()
+		d = d/2 + time.Duration(t.rand.Int63n(int64(d/2)+1))
+		t.mu.Unlock()
+	}
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if after := time.Duration(s) * time.Second; after > d {
+				d = after
+			}
+		}
+		if d > maxRetryAfter {
+			d = maxRetryAfter
+		}
+	}
+	return d
+}
+
+// cancelOnClose cancels the context of a response when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
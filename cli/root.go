@@ -0,0 +1,455 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// closeOutput closes the --output file after a command ran.
+	closeOutput = func() error { return nil }
+	// closeEvents closes the --events stream after a command ran.
+	closeEvents = func() error { return nil }
+	// restoreLogs restores diagnostics and klog after a command ran with --silent.
+	restoreLogs = func() {}
+
+	// Flags shared between subcommands
+	confidenceThreshold float64
+	maxLicenseFileSize  int64
+	includeTests        bool
+	includeStdLib       bool
+	includeAssetModules bool
+	scanFileLicenses    bool
+	scanCopyrights      bool
+	goCachePackages     string
+	ignore              []string
+	ignoreSubtree       []string
+	followSymlinks      bool
+	debugURLs           bool
+	sourcegraphURL      string
+	goSumOnly           bool
+	modulesOnly         bool
+	directOnly          bool
+	modMode             string
+	packageHelp         = `
+
+Typically, specify the Go package that builds your Go binary.
+go-licenses expects the same package argument format as "go build".
+For example:
+* A rooted import path like "github.com/nilsbeck/go-licenses" or "github.com/nilsbeck/go-licenses/licenses".
+* A relative path that denotes the package in that directory, like "." or "./cmd/some-command".
+To learn more about Go package argument, run "go help packages".`
+)
+
+// NewRootCmd returns the go-licenses command with all its subcommands, as run by the
+// go-licenses binary.
+//
+// Running a command doesn't change the state of the process: HTTP requests are made
+// with a client of the run rather than http.DefaultTransport, go commands get the
+// environment of the run rather than changing the process's, failures are returned as
+// errors rather than exiting, and what --output, --events and --silent redirect is
+// pointed back to stdout, stderr and klog when the command ends. The commands keep their
+// flag values in package variables though, so commands of this package must run one
+// after another.
+func NewRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "go-licenses",
+		Short: "go-licenses helps you work with licenses of your go project's dependencies.",
+		Long: `go-licenses helps you work with licenses of your go project's dependencies.
+
+Prerequisites:
+1. Go v1.16 or later.
+2. Change directory to your go project.
+3. Run "go mod download".`,
+	}
+	addPersistentFlags(cmd.PersistentFlags())
+	cmd.AddCommand(
+		newAboutCmd(),
+		newCheckCmd(),
+		newCSVCmd(),
+		newEnrichCmd(),
+		newExplainCmd(),
+		newWhyCmd(),
+		newHookCmd(),
+		newMergeCmd(),
+		newNoticesCmd(),
+		newPolicyCmd(),
+		newReportCmd(),
+		newSaveCmd(),
+	)
+	setUpRuns(cmd)
+	return cmd
+}
+
+// NewReportCmd returns the report command, to be mounted under the root command of
+// another CLI. It has the flags that go-licenses shares between its commands, e.g.
+// --config and --output.
+func NewReportCmd() *cobra.Command {
+	return standalone(newReportCmd())
+}
+
+// NewCheckCmd returns the check command, to be mounted under the root command of another
+// CLI. It has the flags that go-licenses shares between its commands, e.g. --config and
+// --output. If a library is not allowed, it returns an *ExitError with Code 1, for which
+// the go-licenses binary exits with status 1.
+func NewCheckCmd() *cobra.Command {
+	return standalone(newCheckCmd())
+}
+
+// NewSaveCmd returns the save command, to be mounted under the root command of another
+// CLI. It has the flags that go-licenses shares between its commands, e.g. --config and
+// --output.
+func NewSaveCmd() *cobra.Command {
+	return standalone(newSaveCmd())
+}
+
+// standalone gives cmd what the go-licenses root command provides to its subcommands:
+// the shared flags, setting them up before cmd runs and cleaning up afterwards. cobra
+// only runs the nearest persistent pre-run hook, so the hooks of the command that cmd is
+// mounted under don't run for cmd.
+func standalone(cmd *cobra.Command) *cobra.Command {
+	addPersistentFlags(cmd.PersistentFlags())
+	setUpRuns(cmd)
+	return cmd
+}
+
+// setUpRuns sets up the shared flags before cmd or any of its subcommands runs, and
+// concludes every run with endRun, also when setting up fails.
+func setUpRuns(cmd *cobra.Command) {
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := setUp(cmd, args); err != nil {
+			return endRun(err)
+		}
+		return nil
+	}
+	endRuns(cmd)
+}
+
+// endRuns makes cmd and its subcommands call endRun after they ran.
+func endRuns(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return endRun(run(cmd, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		endRuns(sub)
+	}
+}
+
+// endRun closes --output and concludes a run that ended with err with finish.
+func endRun(err error) error {
+	if cerr := closeOutput(); err == nil {
+		err = cerr
+	}
+	closeOutput = func() error { return nil }
+	return finish(err)
+}
+
+// addPersistentFlags adds the flags shared by all commands to flags.
+func addPersistentFlags(flags *pflag.FlagSet) {
+	flags.Float64Var(&confidenceThreshold, "confidence_threshold", 0.9, "Minimum confidence required in order to positively identify a license.")
+	flags.Int64Var(&maxLicenseFileSize, "max_license_file_size", licenses.DefaultMaxLicenseFileSize, "Number of bytes of a license file that the classifier scans. Larger files are reported as partially scanned. Use 0 for no limit.")
+	flags.BoolVar(&includeTests, "include_tests", false, "Include packages only imported by testing code.")
+	flags.BoolVar(&includeStdLib, "include_stdlib", false, "Include the Go standard library as a single library named \"std\", licensed by the Go toolchain's LICENSE file and versioned by the Go version.")
+	flags.BoolVar(&includeAssetModules, "include_asset_modules", false, "Include modules required directly by go.mod that none of the loaded packages belongs to, e.g. modules required only for assets or tools, licensed by the license file in their module root.")
+	flags.BoolVar(&scanFileLicenses, "scan_file_licenses", false, "Read the SPDX-License-Identifier headers of the Go files of each package, report files whose license differs from their library's and check their licenses too. Files are filtered by deepScanExclude and deepScanSkipGenerated of the config file.")
+	flags.BoolVar(&scanCopyrights, "scan_copyrights", false, "Read the copyright statements in the headers of the Go files of each package and report them along with those of the license file, e.g. for attribution templates. Files are filtered like for --scan_file_licenses.")
+	flags.StringVar(&goCachePackages, "gocache_packages", string(licenses.GoCacheResolve), "How to treat packages whose files are all in the build cache (GOCACHE), e.g. Go files generated by cgo: resolve attributes them to the directory of their import path in their module, skip leaves them out and error fails. Test binaries of --include_tests are always left out.")
+	flags.BoolVar(&followSymlinks, "follow_symlinks", true, "Follow symlinked files and directories when searching for license files and saving them. Symlinks in module paths, e.g. a symlinked GOMODCACHE, are always resolved.")
+	flags.BoolVar(&debugURLs, "debug_urls", false, "Log every step of resolving license URLs: host rules applied, meta tags fetched, versions mapped to tags and fallbacks taken.")
+	flags.StringVar(&sourcegraphURL, "sourcegraph_url", "", "Link license files on this Sourcegraph instance, e.g. https://sg.example.com, instead of on the code host of their repository.")
+	flags.StringVar(&modMode, "mod", "", "Set to vendor to load packages from the vendor directory and identify vendored modules by vendor/modules.txt, so that their license URLs point to their upstream repositories rather than into the vendor directory.")
+	flags.BoolVar(&goSumOnly, "go_sum_only", false, "Fast mode for pre-commit hooks: report a library per module in the go.sum file of the module in the working directory, licensed by the license file in its root in the module cache, without loading packages. Package arguments are ignored. Less accurate, since go.sum may list modules that are not imported.")
+	flags.BoolVar(&modulesOnly, "modules_only", false, "Report a library per module in the build list of the module in the working directory, as listed by \"go list -m all\", licensed by the license file in its root, without loading packages. Works for modules that don't compile. Package arguments are ignored. Less accurate, since the build list may contain modules that are not imported.")
+	flags.BoolVar(&directOnly, "direct_only", false, "Only include the direct dependencies of the main module, i.e. libraries with a package imported by one of its packages, and the libraries of the main module itself. With --go_sum_only and --modules_only, direct dependencies are the modules that go.mod requires without an // indirect comment.")
+	flags.StringSliceVar(&trustedDomains, "trusted_domains", nil, "Domains of your organization's own modules, e.g. example.com,corp.local, including their subdomains. Modules under them are allowed by the license policy, their license URLs are resolved without sending their paths to public services, and they are tagged as internal in the report.")
+	flags.StringSliceVar(&ignore, "ignore", nil, "Package path prefixes to be ignored. Dependencies from the ignored packages are still checked. Can be specified multiple times.")
+	flags.StringSliceVar(&ignoreSubtree, "ignore_subtree", nil, "Package path prefixes to be ignored together with their dependencies, unless these are also imported by other packages. Can be specified multiple times.")
+	addCacheFlags(flags)
+	addConfigFlags(flags)
+	addEventsFlags(flags)
+	addLicenseOverrideFlags(flags)
+	addNetworkFlags(flags)
+	addOutputFlags(flags)
+	addProfileFlags(flags)
+	addReplayFlags(flags)
+	addWarningFlags(flags)
+}
+
+// setUp prepares running a command with the shared flags.
+func setUp(cmd *cobra.Command, args []string) error {
+	var err error
+	// Drop the results of earlier runs in the process, e.g. of a command embedded in
+	// another CLI.
+	checkFindings, classifyErrors, vanityURLs, unprocessedLibraries = nil, nil, nil, nil
+	restore, err := setUpSilent()
+	if err != nil {
+		return err
+	}
+	restoreLogs = restore
+	closeStream, err := startEvents(cmd.Name(), args)
+	if err != nil {
+		return err
+	}
+	closeEvents = closeStream
+	setUpProfile()
+	if colored, err = useColor(); err != nil {
+		return err
+	}
+	closeFile, err := openOutput()
+	if err != nil {
+		return err
+	}
+	closeOutput = closeFile
+	setUpCache()
+	if err := setUpLicenseOverrides(); err != nil {
+		return err
+	}
+	cfg = config{}
+	if configPath != "" {
+		if cfg, err = loadConfig(configPath); err != nil {
+			return err
+		}
+	}
+	if httpClient, err = newHTTPClient(cfg); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Main runs the go-licenses binary and exits if the command fails.
+func Main() {
+	// Change klog default log level to INFO.
+	klog.InitFlags(nil)
+	err := flag.Set("logtostderr", "true")
+	if err != nil {
+		klog.Error(err)
+		os.Exit(1)
+	}
+	err = flag.Set("stderrthreshold", "INFO")
+	if err != nil {
+		klog.Error(err)
+		os.Exit(1)
+	}
+	// Some dependencies log with the standard log package. Route them through klog, so
+	// that all diagnostics go to stderr with the same prefixes.
+	klog.CopyStandardLogTo("INFO")
+
+	flag.Parse()
+	rootCmd := NewRootCmd()
+	rootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
+	rootCmd.SilenceErrors = true // to avoid duplicate error output
+	rootCmd.SilenceUsage = true  // to avoid usage/help output on error
+
+	if err := rootCmd.Execute(); err != nil {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		if silent {
+			os.Exit(1)
+		}
+		klog.Exit(err)
+	}
+}
+
+// ExitError is returned by a command that failed after reporting why, e.g. check after
+// printing the libraries that are not allowed. The go-licenses binary exits with status
+// Code without printing Err.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// finish concludes a run that ended with err, also when a command exits by itself. It
+// returns err or any error that occurred finishing.
+func finish(err error) error {
+	saveCache()
+	finishEvents(err)
+	if werr := printWarnings(diagnostics); err == nil {
+		err = werr
+	}
+	if perr := printPreflight(diagnostics); err == nil {
+		err = perr
+	}
+	if perr := printProfile(diagnostics); err == nil {
+		err = perr
+	}
+	if cerr := closeEvents(); err == nil {
+		err = cerr
+	}
+	closeEvents = func() error { return nil }
+	restoreLogs()
+	restoreLogs = func() {}
+	return err
+}
+
+// newClassifier creates the license classifier shared by all subcommands from the global
+// flags and config.
+func newClassifier() (licenses.Classifier, error) {
+	defer timePhase(phaseClassification)()
+	opts := []licenses.ClassifierOption{
+		licenses.WithLicenseThresholds(cfg.LicenseConfidenceThresholds),
+		licenses.WithMaxFileSize(maxLicenseFileSize),
+	}
+	if cache != nil {
+		opts = append(opts, licenses.WithCache(cache))
+	}
+	return licenses.NewClassifier(confidenceThreshold, opts...)
+}
+
+// ignoredPackages are the packages left out by ignore rules in the last call of libraries.
+var ignoredPackages []licenses.IgnoredPackage
+
+// libraries returns the libraries used by the given packages, applying the global flags.
+func libraries(ctx context.Context, classifier licenses.Classifier, args []string) ([]*licenses.Library, error) {
+	defer timePhase(phaseLoading)()
+	ignoredPackages = nil
+	ctx = licenses.WithWarningHandler(ctx, recordWarning)
+	opts, err := libraryOptions()
+	if err != nil {
+		return nil, err
+	}
+	if goSumOnly && modulesOnly {
+		return nil, errors.New("--go_sum_only and --modules_only can't be used at the same time")
+	}
+	var libs []*licenses.Library
+	switch {
+	case goSumOnly:
+		libs, err = licenses.GoSumLibraries(ctx, classifier, opts, ".")
+	case modulesOnly:
+		if opts.Vendor {
+			return nil, errors.New("--modules_only doesn't support --mod=vendor, it finds licenses in the module cache")
+		}
+		libs, err = licenses.ModuleListLibraries(ctx, classifier, opts, ".")
+	default:
+		libs, err = licenses.LibrariesWithOptions(ctx, classifier, opts, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if directOnly {
+		libs = directLibraries(libs)
+	}
+	applyLicenseOverrides(classifier, libs)
+	return libs, nil
+}
+
+// libraryOptions returns the options of the licenses package set by the flags and the
+// config file.
+func libraryOptions() (licenses.Options, error) {
+	// Attempts of requests time out after --http_timeout in the transport of httpClient,
+	// so resolvers don't time them out as a whole, which would cut off retries.
+	var resolver licenses.SourceResolver
+	switch {
+	case sourcegraphURL != "":
+		resolver = licenses.NewSourcegraphResolverWithClient(sourcegraphURL, httpClient, cfg.CodeHosts...)
+	case offline:
+		resolver = licenses.NewOfflineResolver(goEnvOr("GOMODCACHE", ""), cfg.CodeHosts...)
+	default:
+		resolver = licenses.NewPkgsiteResolverWithClient(httpClient, cfg.CodeHosts...)
+		if domains := getTrustedDomains(); len(domains) > 0 {
+			resolver = trustedResolver{
+				domains: domains,
+				trusted: licenses.NewOfflineResolver(goEnvOr("GOMODCACHE", ""), cfg.CodeHosts...),
+				public:  resolver,
+			}
+		}
+	}
+	opts := licenses.Options{
+		IncludeTests: includeTests,
+		IgnoreRules:  ignoreRules(),
+		OnIgnored: func(p licenses.IgnoredPackage) {
+			ignoredPackages = append(ignoredPackages, p)
+		},
+		SkipSymlinks:          !followSymlinks,
+		DeepScanExcludes:      cfg.DeepScanExclude,
+		DeepScanSkipGenerated: cfg.DeepScanSkipGenerated,
+		LocalizedLicenseNames: cfg.LocalizedLicenseNames,
+		OnModule:              emitModuleStarted,
+		TraceURLs:             debugURLs,
+		IncludeStdLib:         includeStdLib,
+		IncludeAssetModules:   includeAssetModules,
+		ScanFileLicenses:      scanFileLicenses,
+		ScanCopyrights:        scanCopyrights,
+		SourceResolver:        resolver,
+		Cache:                 cache,
+	}
+	goCacheMode, err := licenses.ParseGoCacheMode(goCachePackages)
+	if err != nil {
+		return licenses.Options{}, fmt.Errorf("--gocache_packages: %w", err)
+	}
+	opts.GoCacheMode = goCacheMode
+	switch modMode {
+	case "":
+	case "vendor":
+		opts.Vendor = true
+	default:
+		return licenses.Options{}, fmt.Errorf("--mod=%s is not supported, only --mod=vendor; set GOFLAGS=-mod=%s for other modes", modMode, modMode)
+	}
+	return opts, nil
+}
+
+// directLibraries returns the libraries of libs that are direct dependencies or belong to
+// the main module, see --direct_only.
+func directLibraries(libs []*licenses.Library) []*licenses.Library {
+	var direct []*licenses.Library
+	for _, lib := range libs {
+		if m := lib.Module(); lib.Direct || (m != nil && m.Main) {
+			direct = append(direct, lib)
+		}
+	}
+	return direct
+}
+
+// ignoreRules returns the rules set by --ignore and --ignore_subtree.
+func ignoreRules() []licenses.IgnoreRule {
+	var rules []licenses.IgnoreRule
+	for _, p := range ignore {
+		rules = append(rules, licenses.IgnoreRule{Prefix: p, Mode: licenses.IgnoreHide})
+	}
+	for _, p := range ignoreSubtree {
+		rules = append(rules, licenses.IgnoreRule{Prefix: p, Mode: licenses.IgnoreSkipSubtree})
+	}
+	return rules
+}
+
+// Unvendor removes the "*/vendor/" prefix from the given import path, if present.
+func unvendor(importPath string) string {
+	if vendorerAndVendoree := strings.SplitN(importPath, "/vendor/", 2); len(vendorerAndVendoree) == 2 {
+		return vendorerAndVendoree[1]
+	}
+	return importPath
+}
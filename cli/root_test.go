@@ -0,0 +1,85 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runRoot runs the go-licenses command with args and returns what it printed to stdout
+// and stderr.
+func runRoot(t *testing.T, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	defer func(stdout, stderr *os.File) { os.Stdout, os.Stderr = stdout, stderr }(os.Stdout, os.Stderr)
+	dir := t.TempDir()
+	outFile, ferr := os.Create(filepath.Join(dir, "stdout"))
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	defer outFile.Close()
+	errFile, ferr := os.Create(filepath.Join(dir, "stderr"))
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	defer errFile.Close()
+	os.Stdout, os.Stderr = outFile, errFile
+
+	cmd := NewRootCmd()
+	cmd.SilenceErrors, cmd.SilenceUsage = true, true
+	cmd.SetArgs(args)
+	err = cmd.Execute()
+
+	o, ferr := os.ReadFile(outFile.Name())
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	e, ferr := os.ReadFile(errFile.Name())
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	return string(o), string(e), err
+}
+
+func TestRootCmdRunsBackToBack(t *testing.T) {
+	report := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(report, []byte(`{"schemaVersion": 1, "libraries": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runRoot(t, "policy", "lint", "--no_cache", "--allowed_licenses=NotALicense", "--events=ndjson")
+	if err == nil || !strings.Contains(err.Error(), "found 1 errors") {
+		t.Errorf("policy lint: got error %v, want one for the unknown license", err)
+	}
+	if !strings.Contains(stdout, `error: allowed license "NotALicense"`) {
+		t.Errorf("policy lint: stdout = %q, want the unknown license", stdout)
+	}
+	if !strings.Contains(stderr, `"event":"scan_finished"`) {
+		t.Errorf("policy lint: stderr = %q, want the events", stderr)
+	}
+
+	stdout, stderr, err = runRoot(t, "merge", "--no_cache", report)
+	if err != nil {
+		t.Errorf("merge: got error %v, want none", err)
+	}
+	if !strings.Contains(stdout, `"libraries": []`) {
+		t.Errorf("merge: stdout = %q, want the merged report", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("merge: stderr = %q, want nothing", stderr)
+	}
+}
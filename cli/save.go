@@ -0,0 +1,170 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+var (
+	saveHelp = "Saves licenses, copyright notices and source code, as required by a Go package's dependencies, to a directory."
+	// savePath is where the output of the command is written to.
+	savePath string
+	// overwriteSavePath controls behaviour when the directory indicated by savePath already exists.
+	// If true, the directory will be replaced. If false, the command will fail.
+	overwriteSavePath bool
+	// sourceMode is how the source code of libraries with reciprocal or restricted licenses
+	// is saved: "copy" copies the source directory, "archive" writes it to a tarball.
+	sourceMode string
+	// saveLayout is the directory layout below savePath: "path" uses the library path,
+	// "versioned" additionally includes the module version, e.g. example.com/mod@v1.2.3/pkg.
+	saveLayout string
+	// onlyCategories restricts saving to libraries with these license types, if set.
+	onlyCategories []string
+	// saveDryRun prints the changes to savePath instead of making them.
+	saveDryRun bool
+	// saveParallelism is the number of libraries saved concurrently.
+	saveParallelism int
+)
+
+// newSaveCmd returns the save command.
+func newSaveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save <package> [package...]",
+		Short: saveHelp,
+		Long:  saveHelp + packageHelp,
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  saveMain,
+	}
+	cmd.Flags().StringVar(&savePath, "save_path", "", "Directory into which files should be saved that are required by license terms")
+	if err := cmd.MarkFlagRequired("save_path"); err != nil {
+		klog.Fatal(err)
+	}
+	if err := cmd.MarkFlagFilename("save_path"); err != nil {
+		klog.Fatal(err)
+	}
+
+	cmd.Flags().BoolVar(&overwriteSavePath, "force", false, "Delete the destination directory if it already exists.")
+
+	cmd.Flags().StringVar(&sourceMode, "source", "copy", "How to save the source code of libraries with reciprocal or restricted licenses: copy (a directory tree) or archive (a "+licenses.SourceArchiveName+" next to the license file).")
+
+	cmd.Flags().StringVar(&saveLayout, "layout", "path", "Directory layout of the saved files: path (by library path) or versioned (by module path and version, e.g. github.com/foo/bar@v1.2.3/LICENSE).")
+
+	cmd.Flags().StringSliceVar(&onlyCategories, "only_categories", nil, "Only save files for libraries with these license types, e.g. notice,reciprocal,restricted. Libraries with forbidden or unknown licenses still fail the command. (default: all types)")
+
+	cmd.Flags().IntVar(&saveParallelism, "parallelism", runtime.NumCPU(), "Number of libraries to save concurrently. Higher values speed up saving to network storage.")
+
+	cmd.Flags().BoolVar(&saveDryRun, "dry_run", false, "Print which files would be created, updated or deleted in the save path, without changing it.")
+
+	return cmd
+}
+
+func saveMain(_ *cobra.Command, args []string) error {
+	// On Windows, the os package only supports paths longer than MAX_PATH (260 characters)
+	// when they are absolute, which deeply nested module paths easily exceed.
+	absSavePath, err := filepath.Abs(savePath)
+	if err != nil {
+		return err
+	}
+	savePath = absSavePath
+
+	if sourceMode != "copy" && sourceMode != "archive" {
+		return fmt.Errorf("unknown --source %q, want one of: copy, archive", sourceMode)
+	}
+	if saveLayout != "path" && saveLayout != "versioned" {
+		return fmt.Errorf("unknown --layout %q, want one of: path, versioned", saveLayout)
+	}
+	categories, err := saveCategories(onlyCategories)
+	if err != nil {
+		return err
+	}
+	if saveParallelism < 1 {
+		return fmt.Errorf("--parallelism must be at least 1, got %d", saveParallelism)
+	}
+
+	if overwriteSavePath && !saveDryRun {
+		if err := os.RemoveAll(savePath); err != nil {
+			return err
+		}
+	}
+
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+
+	libs, err := libraries(runContext(), classifier, args)
+	if err != nil {
+		return err
+	}
+
+	if saveDryRun {
+		return saveDryRunMain(classifier, libs, categories)
+	}
+
+	// Check that the save path doesn't exist, otherwise it'd end up with a mix of
+	// existing files and the output of this command.
+	if d, err := os.Open(savePath); err == nil {
+		d.Close()
+		return fmt.Errorf("%s already exists", savePath)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return saveLibraries(classifier, libs, categories, savePath)
+}
+
+// saveLibraries saves the files required by the licenses of libs to dir with
+// licenses.SaveLibraries, configured by the flags of the save command and the license
+// overrides.
+func saveLibraries(classifier licenses.Classifier, libs []*licenses.Library, categories map[licenses.Type]bool, dir string) error {
+	return licenses.SaveLibraries(classifier, libs, dir, licenses.SaveOptions{
+		Archive:      sourceMode == "archive",
+		Versioned:    saveLayout == "versioned",
+		Categories:   categories,
+		Parallelism:  saveParallelism,
+		SkipSymlinks: !followSymlinks,
+		Override: func(lib *licenses.Library) (licenses.Type, string, bool) {
+			o, ok := licenseOverrideFor(lib)
+			if !ok {
+				return "", "", false
+			}
+			return o.licenseType(), o.LicenseFile, true
+		},
+	})
+}
+
+// saveCategories parses --only_categories. It returns nil if no categories are given.
+func saveCategories(names []string) (map[licenses.Type]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	categories := make(map[licenses.Type]bool)
+	for _, name := range names {
+		t := licenses.Type(strings.TrimSpace(strings.ToLower(name)))
+		if !licenses.CanSave(t) {
+			return nil, fmt.Errorf("unknown license type %q in --only_categories, want one of: notice, permissive, reciprocal, restricted, unencumbered", name)
+		}
+		categories[t] = true
+	}
+	return categories, nil
+}
@@ -0,0 +1,387 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"k8s.io/klog/v2"
+)
+
+// spdxNoAssertion is the SPDX value for information that was not determined.
+const spdxNoAssertion = "NOASSERTION"
+
+// spdxIDInvalidChars matches the characters that are not allowed in SPDX identifiers.
+var spdxIDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// spdxDocument is an SPDX 2.3 document, with the field names of its JSON format.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string `json:"name"`
+	SPDXID           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo,omitempty"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	VerificationCode *struct {
+		Value string `json:"packageVerificationCodeValue"`
+	} `json:"packageVerificationCode,omitempty"`
+	Homepage         string            `json:"homepage,omitempty"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	HasFiles         []string          `json:"hasFiles,omitempty"`
+}
+
+type spdxExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+type spdxFile struct {
+	FileName           string         `json:"fileName"`
+	SPDXID             string         `json:"SPDXID"`
+	Checksums          []spdxChecksum `json:"checksums"`
+	LicenseConcluded   string         `json:"licenseConcluded"`
+	LicenseInfoInFiles []string       `json:"licenseInfoInFiles"`
+	CopyrightText      string         `json:"copyrightText"`
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// reportSPDX prints libs as an SPDX 2.3 document, in tag-value format for --format=spdx
+// and in JSON format for --format=spdx-json. Each library becomes a package containing
+// its license file, and the main module's packages depend on all others.
+func reportSPDX(metadata runMetadata, libs []libraryData) error {
+	doc, err := newSPDXDocument(metadata, libs)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "spdx-json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+	return writeSPDXTagValue(doc)
+}
+
+// newSPDXDocument builds the SPDX document describing libs.
+func newSPDXDocument(metadata runMetadata, libs []libraryData) (*spdxDocument, error) {
+	name := metadata.RootModule
+	if name == "" {
+		name = "go-licenses-report"
+	}
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("generating SPDX document namespace: %w", err)
+	}
+	doc := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + spdxIDInvalidChars.ReplaceAllString(name, "-") + "-" + uuid,
+		CreationInfo: spdxCreationInfo{
+			Created:  metadata.Timestamp.Format("2006-01-02T15:04:05Z"),
+			Creators: []string{"Tool: go-licenses-" + metadata.ToolVersion},
+		},
+	}
+	ids := make(map[string]bool)
+	var mains, deps []string
+	for _, lib := range libs {
+		pkg := spdxPackage{
+			Name:             lib.Name,
+			SPDXID:           spdxID(ids, "SPDXRef-Package-"+lib.Name),
+			DownloadLocation: spdxDownloadLocation(lib.module),
+			Homepage:         lib.Homepage,
+			SourceInfo:       spdxSourceInfo(lib),
+			LicenseConcluded: spdxLicense(lib.LicenseName),
+			LicenseDeclared:  spdxLicense(lib.LicenseName),
+			CopyrightText:    spdxNoAssertion,
+		}
+		if lib.module != nil && lib.module.Version != "" {
+			pkg.VersionInfo = spdxModuleVersion(lib.module)
+			pkg.ExternalRefs = []spdxExternalRef{{
+				Category: "PACKAGE-MANAGER",
+				Type:     "purl",
+				Locator:  packageURL(lib.module),
+			}}
+		}
+		if file, ok := spdxLicenseFile(ids, lib); ok {
+			pkg.FilesAnalyzed = true
+			pkg.VerificationCode = &struct {
+				Value string `json:"packageVerificationCodeValue"`
+			}{spdxVerificationCode(file)}
+			pkg.HasFiles = []string{file.SPDXID}
+			doc.Files = append(doc.Files, file)
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		if isMain(lib) {
+			mains = append(mains, pkg.SPDXID)
+		} else {
+			deps = append(deps, pkg.SPDXID)
+		}
+	}
+	described := mains
+	if len(mains) == 0 {
+		described = deps
+	}
+	for _, id := range described {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{doc.SPDXID, "DESCRIBES", id})
+	}
+	for _, main := range mains {
+		for _, dep := range deps {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{main, "DEPENDS_ON", dep})
+		}
+	}
+	return doc, nil
+}
+
+// spdxLicenseFile describes the license file of lib relative to its module directory.
+// It returns false if lib has no license file that can be read.
+func spdxLicenseFile(ids map[string]bool, lib libraryData) (spdxFile, bool) {
+	if lib.LicensePath == "" {
+		return spdxFile{}, false
+	}
+	b, err := os.ReadFile(lib.LicensePath)
+	if err != nil {
+		klog.Errorf("Error reading license file %q: %v", lib.LicensePath, err)
+		return spdxFile{}, false
+	}
+	name := filepath.Base(lib.LicensePath)
+	if lib.module != nil && lib.module.Dir != "" {
+		if rel, err := filepath.Rel(lib.module.Dir, lib.LicensePath); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
+		}
+	}
+	sha1Sum := sha1.Sum(b)
+	sha256Sum := sha256.Sum256(b)
+	license := spdxLicense(lib.LicenseName)
+	return spdxFile{
+		FileName: "./" + name,
+		SPDXID:   spdxID(ids, "SPDXRef-File-"+lib.Name+"-"+name),
+		Checksums: []spdxChecksum{
+			{Algorithm: "SHA1", Value: hex.EncodeToString(sha1Sum[:])},
+			{Algorithm: "SHA256", Value: hex.EncodeToString(sha256Sum[:])},
+		},
+		LicenseConcluded:   license,
+		LicenseInfoInFiles: spdxLicenseIDs(license),
+		CopyrightText:      spdxNoAssertion,
+	}, true
+}
+
+// spdxVerificationCode returns the package verification code of a package that contains
+// only file: the SHA1 of the concatenated, sorted SHA1 checksums of its files.
+func spdxVerificationCode(file spdxFile) string {
+	var sums []string
+	for _, c := range file.Checksums {
+		if c.Algorithm == "SHA1" {
+			sums = append(sums, c.Value)
+		}
+	}
+	sort.Strings(sums)
+	sum := sha1.Sum([]byte(strings.Join(sums, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// spdxID returns a unique SPDX identifier based on id, with invalid characters replaced.
+func spdxID(ids map[string]bool, id string) string {
+	id = spdxIDInvalidChars.ReplaceAllString(id, "-")
+	unique := id
+	for i := 2; ids[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	ids[unique] = true
+	return unique
+}
+
+// spdxLicense returns the SPDX license expression for the license name of a library.
+func spdxLicense(name string) string {
+	if name == "" || name == UNKNOWN {
+		return spdxNoAssertion
+	}
+	return name
+}
+
+// spdxLicenseIDs returns the license identifiers in the SPDX expression license.
+func spdxLicenseIDs(license string) []string {
+	if license == spdxNoAssertion {
+		return []string{spdxNoAssertion}
+	}
+	return licenses.ExpressionLicenseIDs(license)
+}
+
+// spdxSourceInfo describes whether the go command verifies the module of lib against
+// the checksum database, or "" if lib has no module.
+func spdxSourceInfo(lib libraryData) string {
+	switch {
+	case lib.module == nil:
+		return ""
+	case lib.ChecksumDatabase != "":
+		return "Verified against the checksum database " + lib.ChecksumDatabase + "."
+	}
+	return "Not verified against the checksum database: " + lib.ChecksumExclusion + "."
+}
+
+// spdxModuleVersion returns the version of m as the go command knows it, restoring the
+// +incompatible suffix that Module trims.
+func spdxModuleVersion(m *licenses.Module) string {
+	version := m.Version
+	if _, pathMajor, ok := module.SplitPathVersion(m.Path); ok && pathMajor == "" {
+		if major := semver.Major(version); major != "" && major != "v0" && major != "v1" && semver.Build(version) == "" {
+			version += "+incompatible"
+		}
+	}
+	return version
+}
+
+// packageURL returns the pkg:golang package URL of m.
+func packageURL(m *licenses.Module) string {
+	return "pkg:golang/" + m.Path + "@" + url.PathEscape(spdxModuleVersion(m))
+}
+
+// spdxDownloadLocation returns where the module m can be downloaded from. Only modules
+// verified against the checksum database are known to be served by the public proxy.
+func spdxDownloadLocation(m *licenses.Module) string {
+	if m == nil || m.Main || m.Version == "" || !m.ChecksumVerified {
+		return spdxNoAssertion
+	}
+	path, err := module.EscapePath(m.Path)
+	if err != nil {
+		return spdxNoAssertion
+	}
+	version, err := module.EscapeVersion(spdxModuleVersion(m))
+	if err != nil {
+		return spdxNoAssertion
+	}
+	return "https://proxy.golang.org/" + path + "/@v/" + version + ".zip"
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// writeSPDXTagValue prints doc in the SPDX tag-value format. Files follow the package
+// that contains them.
+func writeSPDXTagValue(doc *spdxDocument) error {
+	files := make(map[string]spdxFile)
+	for _, f := range doc.Files {
+		files[f.SPDXID] = f
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "SPDXVersion: %s\n", doc.SPDXVersion)
+	fmt.Fprintf(w, "DataLicense: %s\n", doc.DataLicense)
+	fmt.Fprintf(w, "SPDXID: %s\n", doc.SPDXID)
+	fmt.Fprintf(w, "DocumentName: %s\n", doc.Name)
+	fmt.Fprintf(w, "DocumentNamespace: %s\n", doc.DocumentNamespace)
+	for _, c := range doc.CreationInfo.Creators {
+		fmt.Fprintf(w, "Creator: %s\n", c)
+	}
+	fmt.Fprintf(w, "Created: %s\n", doc.CreationInfo.Created)
+	for _, p := range doc.Packages {
+		fmt.Fprintf(w, "\nPackageName: %s\n", p.Name)
+		fmt.Fprintf(w, "SPDXID: %s\n", p.SPDXID)
+		if p.VersionInfo != "" {
+			fmt.Fprintf(w, "PackageVersion: %s\n", p.VersionInfo)
+		}
+		fmt.Fprintf(w, "PackageDownloadLocation: %s\n", p.DownloadLocation)
+		fmt.Fprintf(w, "FilesAnalyzed: %t\n", p.FilesAnalyzed)
+		if p.VerificationCode != nil {
+			fmt.Fprintf(w, "PackageVerificationCode: %s\n", p.VerificationCode.Value)
+		}
+		if p.Homepage != "" {
+			fmt.Fprintf(w, "PackageHomePage: %s\n", p.Homepage)
+		}
+		if p.SourceInfo != "" {
+			fmt.Fprintf(w, "PackageSourceInfo: <text>%s</text>\n", p.SourceInfo)
+		}
+		fmt.Fprintf(w, "PackageLicenseConcluded: %s\n", p.LicenseConcluded)
+		fmt.Fprintf(w, "PackageLicenseDeclared: %s\n", p.LicenseDeclared)
+		fmt.Fprintf(w, "PackageCopyrightText: %s\n", p.CopyrightText)
+		for _, ref := range p.ExternalRefs {
+			fmt.Fprintf(w, "ExternalRef: %s %s %s\n", ref.Category, ref.Type, ref.Locator)
+		}
+		for _, id := range p.HasFiles {
+			f := files[id]
+			fmt.Fprintf(w, "\nFileName: %s\n", f.FileName)
+			fmt.Fprintf(w, "SPDXID: %s\n", f.SPDXID)
+			for _, c := range f.Checksums {
+				fmt.Fprintf(w, "FileChecksum: %s: %s\n", c.Algorithm, c.Value)
+			}
+			fmt.Fprintf(w, "LicenseConcluded: %s\n", f.LicenseConcluded)
+			for _, l := range f.LicenseInfoInFiles {
+				fmt.Fprintf(w, "LicenseInfoInFile: %s\n", l)
+			}
+			fmt.Fprintf(w, "FileCopyrightText: %s\n", f.CopyrightText)
+		}
+	}
+	if len(doc.Relationships) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, r := range doc.Relationships {
+		fmt.Fprintf(w, "Relationship: %s %s %s\n", r.Element, r.Type, r.Related)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+)
+
+// sw360Release is a release of an open source component, with the fields of the SW360
+// REST API. Its source URL can also be passed to FOSSology to upload the source code.
+type sw360Release struct {
+	Name          string            `json:"name"`
+	Version       string            `json:"version"`
+	ComponentType string            `json:"componentType"`
+	MainLicenses  []string          `json:"mainLicenseIds,omitempty"`
+	SourceURL     string            `json:"sourceCodeDownloadurl,omitempty"`
+	ExternalIDs   map[string]string `json:"externalIds,omitempty"`
+}
+
+// reportSW360 prints the dependencies in libs as a JSON array of SW360 releases, one per
+// library. Libraries of the main module are left out, as they are not third-party components.
+func reportSW360(libs []libraryData) error {
+	releases := []sw360Release{}
+	for _, lib := range libs {
+		if isMain(lib) {
+			continue
+		}
+		r := sw360Release{
+			Name:          lib.Name,
+			Version:       lib.Version,
+			ComponentType: "OSS",
+		}
+		if license := spdxLicense(lib.LicenseName); license != spdxNoAssertion {
+			r.MainLicenses = spdxLicenseIDs(license)
+		}
+		if location := spdxDownloadLocation(lib.module); location != spdxNoAssertion {
+			r.SourceURL = location
+		}
+		if lib.module != nil && lib.module.Version != "" {
+			r.Version = spdxModuleVersion(lib.module)
+			r.ExternalIDs = map[string]string{"package-url": packageURL(lib.module)}
+		}
+		releases = append(releases, r)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(releases)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+)
+
+// trustedDomains are the domains of the organization's own modules, see --trusted_domains.
+var trustedDomains []string
+
+// getTrustedDomains returns the domains set by --trusted_domains or, if the flag is not
+// set, in the config file.
+func getTrustedDomains() []string {
+	if len(trustedDomains) == 0 {
+		return trimmedNames(cfg.TrustedDomains)
+	}
+	return trimmedNames(trustedDomains)
+}
+
+// inDomains reports whether the host of modulePath is one of domains or a subdomain of one.
+func inDomains(modulePath string, domains []string) bool {
+	host := modulePath
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	for _, d := range domains {
+		d = strings.TrimPrefix(d, ".")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrusted reports whether lib belongs to a module under the trusted domains.
+func isTrusted(lib *licenses.Library) bool {
+	m := lib.Module()
+	return m != nil && inDomains(m.Path, getTrustedDomains())
+}
+
+// trustedResolver resolves the modules under domains with trusted, which doesn't send
+// their paths to public services, and all other modules with public.
+type trustedResolver struct {
+	domains []string
+	trusted licenses.SourceResolver
+	public  licenses.SourceResolver
+}
+
+func (r trustedResolver) ModuleInfo(ctx context.Context, modulePath, version string) (licenses.SourceRepo, error) {
+	if inDomains(modulePath, r.domains) {
+		return r.trusted.ModuleInfo(ctx, modulePath, version)
+	}
+	return r.public.ModuleInfo(ctx, modulePath, version)
+}
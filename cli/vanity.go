@@ -0,0 +1,123 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// vanityURL is a license URL on another host than the module path of its library, e.g.
+// because the module uses a vanity import path or its repository was moved.
+type vanityURL struct {
+	modulePath string
+	url        string
+	// err is why the URL could not be verified, or nil if it is reachable.
+	err error
+}
+
+var (
+	// vanityURLs are the license URLs checked with --verify_vanity_urls, one per module,
+	// guarded by vanityURLsMu.
+	vanityURLs   []vanityURL
+	vanityURLsMu sync.Mutex
+)
+
+// vanityHost returns the host of fileURL if it differs from the host of modulePath,
+// i.e. the first element of the path.
+func vanityHost(modulePath, fileURL string) (string, bool) {
+	u, err := url.Parse(fileURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	moduleHost := modulePath
+	if i := strings.Index(moduleHost, "/"); i >= 0 {
+		moduleHost = moduleHost[:i]
+	}
+	if strings.EqualFold(u.Host, moduleHost) {
+		return "", false
+	}
+	return u.Host, true
+}
+
+// verifyVanityURL checks once per module that the license URL resolved for a module on
+// another host can be fetched, and warns at the end of the report if it can't.
+func verifyVanityURL(ctx context.Context, modulePath, fileURL string) {
+	vanityURLsMu.Lock()
+	defer vanityURLsMu.Unlock()
+	for _, v := range vanityURLs {
+		if v.modulePath == modulePath {
+			return
+		}
+	}
+	vanityURLs = append(vanityURLs, vanityURL{
+		modulePath: modulePath,
+		url:        fileURL,
+		err:        checkURL(ctx, fileURL),
+	})
+}
+
+// checkURL returns an error unless a HEAD request, or a GET request for servers that
+// don't allow HEAD, of u succeeds.
+func checkURL(ctx context.Context, u string) error {
+	var resp *http.Response
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			break
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// reportVanityURLs prints the module paths whose license URLs are on another host,
+// in yellow if the URL could not be fetched.
+func reportVanityURLs() {
+	if len(vanityURLs) == 0 {
+		return
+	}
+	// Libraries are processed concurrently, so the URLs are checked in no particular order.
+	sort.Slice(vanityURLs, func(i, j int) bool { return vanityURLs[i].modulePath < vanityURLs[j].modulePath })
+	var broken int
+	for _, v := range vanityURLs {
+		if v.err != nil {
+			broken++
+		}
+	}
+	diagnosticf("", "%d modules have license URLs on another host than their module path, %d of them unreachable:", len(vanityURLs), broken)
+	for _, v := range vanityURLs {
+		if v.err != nil {
+			diagnosticf(colorYellow, "  %s -> %s: %v", v.modulePath, v.url, v.err)
+		} else {
+			diagnosticf("", "  %s -> %s: ok", v.modulePath, v.url)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+// Kinds of the warnings of this package, in addition to those of the licenses package.
+const (
+	warningLicenseURL      = licenses.WarningKind("license-url")
+	warningUpstreamLicense = licenses.WarningKind("upstream-license")
+	warningLicenseBadge    = licenses.WarningKind("license-badge")
+	warningLicenseLanguage = licenses.WarningKind("license-language")
+)
+
+// warningTitles describe the kinds of warnings in the summary.
+var warningTitles = map[licenses.WarningKind]string{
+	licenses.WarningEmptyVersion:        "Modules without a version, license URLs point to HEAD",
+	licenses.WarningVendoredModule:      "Vendored modules attributed to the vendoring module",
+	licenses.WarningNonGoCode:           "Packages with non-Go code whose dependencies can't be inspected",
+	licenses.WarningLicenseInComment:    "Licenses found in header comments instead of license files",
+	licenses.WarningNotInModuleCache:    "Modules missing from the module cache",
+	licenses.WarningUnidentifiedLicense: "Packages whose nearest license file couldn't be identified",
+	warningLicenseURL:                   "License URLs that couldn't be discovered",
+	warningUpstreamLicense:              "Forks whose upstream license differs or is unknown",
+	warningLicenseBadge:                 "README license badges that disagree with the license file",
+	warningLicenseLanguage:              "License files not in English",
+	warningFileLicense:                  "Files whose SPDX header declares another license than their library",
+}
+
+// maxSummaryModules is the number of affected modules listed per kind of warning.
+const maxSummaryModules = 10
+
+var (
+	summarizeWarnings bool
+
+	warningsMu sync.Mutex
+	// warnings are the warnings of the current command by kind, see --summarize_warnings.
+	warnings = make(map[licenses.WarningKind]*warningGroup)
+)
+
+// warningGroup counts the warnings of a kind and the modules they are about.
+type warningGroup struct {
+	count   int
+	modules map[string]bool
+}
+
+// addWarningFlags adds the flags that control how warnings are printed to flags.
+func addWarningFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&summarizeWarnings, "summarize_warnings", true, "Print warnings about libraries, e.g. license URLs that couldn't be discovered, as a summary grouped by cause at the end of the run rather than one by one. Set to false to see each warning when it occurs.")
+}
+
+// warnf adds a warning about module, which may be empty, to the summary if
+// --summarize_warnings is set, or logs it.
+func warnf(kind licenses.WarningKind, module string, format string, args ...interface{}) {
+	w := licenses.Warning{Kind: kind, Module: module, Message: fmt.Sprintf(format, args...)}
+	if !summarizeWarnings {
+		klog.WarningDepth(1, w.Message)
+		return
+	}
+	addWarning(w)
+}
+
+// recordWarning handles the warnings of the licenses package like warnf.
+func recordWarning(w licenses.Warning) {
+	if !summarizeWarnings {
+		// Log the line of the licenses package that warns, not the one of its warnf.
+		klog.WarningDepth(2, w.Message)
+		return
+	}
+	addWarning(w)
+}
+
+// addWarning adds w to the summary.
+func addWarning(w licenses.Warning) {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	g, ok := warnings[w.Kind]
+	if !ok {
+		g = &warningGroup{modules: make(map[string]bool)}
+		warnings[w.Kind] = g
+	}
+	g.count++
+	if w.Module != "" {
+		g.modules[w.Module] = true
+	}
+}
+
+// printWarnings prints the summary of the warnings recorded since the last call, if any.
+func printWarnings(w io.Writer) error {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	if len(warnings) == 0 {
+		return nil
+	}
+	var kinds []licenses.WarningKind
+	total := 0
+	for kind, g := range warnings {
+		kinds = append(kinds, kind)
+		total += g.count
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	var b strings.Builder
+	fmt.Fprintf(&b, "Warnings (%d), rerun with --summarize_warnings=false for details:\n", total)
+	for _, kind := range kinds {
+		g := warnings[kind]
+		title, ok := warningTitles[kind]
+		if !ok {
+			title = string(kind)
+		}
+		fmt.Fprintf(&b, "  %s (%d)", title, g.count)
+		var modules []string
+		for m := range g.modules {
+			modules = append(modules, m)
+		}
+		sort.Strings(modules)
+		if len(modules) > maxSummaryModules {
+			modules = append(modules[:maxSummaryModules], fmt.Sprintf("and %d more", len(modules)-maxSummaryModules))
+		}
+		if len(modules) > 0 {
+			fmt.Fprintf(&b, ": %s", strings.Join(modules, ", "))
+		}
+		b.WriteString("\n")
+	}
+	warnings = make(map[licenses.WarningKind]*warningGroup)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// libModulePath returns the path of the module of lib, or its name if the module is
+// unknown.
+func libModulePath(lib *licenses.Library) string {
+	if m := lib.Module(); m != nil && m.Path != "" {
+		return m.Path
+	}
+	return lib.Name()
+}
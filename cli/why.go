@@ -0,0 +1,82 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	whyHelp = "Prints the shortest import chain through which one or more Go packages use a dependency."
+)
+
+// newWhyCmd returns the why command.
+func newWhyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "why <module> <package> [package...]",
+		Short: whyHelp,
+		Long: whyHelp + `
+
+<module> is the module path or import path of the dependency, like for explain. For
+each library of the dependency, the chain is printed like "go mod why" does: a line
+"# <library>" followed by one import path per line, from one of the packages to a
+package of the library.` + packageHelp,
+		Args: cobra.MinimumNArgs(2),
+		RunE: whyMain,
+	}
+}
+
+func whyMain(_ *cobra.Command, args []string) error {
+	if goSumOnly || modulesOnly {
+		return errors.New("why needs the imports of packages, it can't be used with --go_sum_only or --modules_only")
+	}
+	target, pkgs := args[0], args[1:]
+	classifier, err := newClassifier()
+	if err != nil {
+		return err
+	}
+	libs, err := libraries(runContext(), classifier, pkgs)
+	if err != nil {
+		return err
+	}
+	var matched []*licenses.Library
+	for _, lib := range libs {
+		if explainMatches(lib, target) {
+			matched = append(matched, lib)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("%s is not a dependency of %s", target, strings.Join(pkgs, " "))
+	}
+	var b strings.Builder
+	for i, lib := range matched {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %s\n", lib.Name())
+		if len(lib.DependencyPath) == 0 {
+			b.WriteString("(no import chain found)\n")
+			continue
+		}
+		b.WriteString(strings.Join(lib.DependencyPath, "\n") + "\n")
+	}
+	_, err = fmt.Fprint(out, b.String())
+	return err
+}
@@ -42,6 +42,13 @@ func TestReportCommandE2E(t *testing.T) {
 
 		{"testdata/modules/hello01", []string{"--template", "licenses.tpl"}, "licenses.md"},
 		{"testdata/modules/template01", []string{"--template", "licenses.tpl"}, "licenses.md"},
+		{"testdata/modules/hello01", []string{"--template", "notices.tpl", "--template_dir", "partials"}, "notices.md"},
+		{"testdata/modules/hello01", []string{"--template", "report.html"}, "licenses.html"},
+
+		{"testdata/modules/hello01", []string{"--format", "spdx"}, "licenses.spdx"},
+		{"testdata/modules/hello01", []string{"--format", "spdx-json"}, "licenses.spdx.json"},
+		{"testdata/modules/hello01", []string{"--format", "cyclonedx"}, "licenses.cdx.json"},
+		{"testdata/modules/hello01", []string{"--format", "cyclonedx-xml"}, "licenses.cdx.xml"},
 	}
 
 	originalWorkDir, err := os.Getwd()
@@ -57,7 +64,8 @@ func TestReportCommandE2E(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 	goLicensesPath := filepath.Join(tempDir, "go-licenses")
-	cmd := exec.Command("go", "build", "-o", goLicensesPath)
+	// Without VCS stamping the tool version in SBOMs is always "(devel)".
+	cmd := exec.Command("go", "build", "-buildvcs=false", "-o", goLicensesPath)
 	_, err = cmd.Output()
 	if err != nil {
 		t.Fatal(err)
@@ -86,9 +94,9 @@ func TestReportCommandE2E(t *testing.T) {
 				t.Logf("\n=== start of log ===\n%s=== end of log ===\n\n\n", stderr.String())
 				t.Fatalf("running go-licenses report: %s. Full log shown above.", err)
 			}
-			got := string(output)
+			got := filterSBOM(string(output))
 			if *update {
-				err := os.WriteFile(tt.goldenFilePath, output, 0600)
+				err := os.WriteFile(tt.goldenFilePath, []byte(got), 0600)
 				if err != nil {
 					t.Fatalf("writing golden file: %s", err)
 				}
@@ -126,6 +134,8 @@ func TestCheckCommandE2E(t *testing.T) {
 		{"testdata/modules/cli02", []string{"--allowed_licenses=Apache-2.0"}, "output-check-license-names-1.txt", 1},
 		{"testdata/modules/cli02", []string{"--allowed_licenses=Apache-2.0,MIT"}, "output-check-license-names-2.txt", 1},
 		{"testdata/modules/cli02", []string{"--allowed_licenses= Apache-2.0, MIT"}, "output-check-license-names-2.txt", 1},
+		{"testdata/modules/cli02", []string{"--config=allowed-modules.json"}, "output-check-allowed-modules.txt", 1},
+		{"testdata/modules/cli02", []string{"--fail_on_new_deps", "--baseline=baseline.txt", "--approvals=approvals.txt"}, "output-check-new-deps.txt", 1},
 	}
 
 	originalWorkDir, err := os.Getwd()
@@ -213,8 +223,24 @@ func filterOutput(output string) string {
 	output = regexp.MustCompile(`(?m)W\d+.*\n`).
 		ReplaceAllString(output, "")
 
+	// Like the logged warnings, drop the summary of them printed at the end.
+	output = regexp.MustCompile(`(?m)^Warnings \(\d+\).*\n(  .*\n)*`).
+		ReplaceAllString(output, "")
+
 	output = regexp.MustCompile(`(?m)^/.*\n`).
 		ReplaceAllString(output, "")
 
 	return output
 }
+
+// filterSBOM replaces the parts of SBOMs that change with every run, the random
+// document namespaces and serial numbers and the creation time, with fixed values.
+func filterSBOM(output string) string {
+	output = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`).
+		ReplaceAllString(output, "00000000-0000-0000-0000-000000000000")
+
+	output = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`).
+		ReplaceAllString(output, "2006-01-02T15:04:05Z")
+
+	return output
+}
@@ -49,6 +49,7 @@ func fetchMeta(ctx context.Context, client *Client, importPath string) (_ *sourc
 
 	resp, err := client.doURL(ctx, "GET", "https://"+uri, true)
 	if err != nil {
+		tracef(ctx, "fetching https://%s failed (%v), falling back to http", uri, err)
 		resp, err = client.doURL(ctx, "GET", "http://"+uri, false)
 		if err != nil {
 			return nil, err
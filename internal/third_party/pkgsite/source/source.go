@@ -209,6 +209,8 @@ type Client struct {
 	// client used for HTTP requests. It is mutable for testing purposes.
 	// If nil, then moduleInfoDynamic will return nil, nil; also for testing.
 	httpClient *http.Client
+	// hostRules map repositories on self-hosted code hosts, see WithHostRules.
+	hostRules []HostRule
 }
 
 // New constructs a *Client using the provided timeout.
@@ -273,8 +275,12 @@ func ModuleInfo(ctx context.Context, client *Client, modulePath, v string) (info
 		return newStdlibInfo(v)
 	}
 
-	repo, relativeModulePath, templates, transformCommit, err := matchStatic(modulePath)
+	repo, relativeModulePath, templates, transformCommit, err := client.matchHostRules(modulePath)
 	if err != nil {
+		repo, relativeModulePath, templates, transformCommit, err = matchStatic(modulePath)
+	}
+	if err != nil {
+		tracef(ctx, "%s: no static host rule matches, resolving go-import/go-source meta tags", modulePath)
 		info, err = moduleInfoDynamic(ctx, client, modulePath, v)
 		if err != nil {
 			return nil, err
@@ -284,6 +290,7 @@ func ModuleInfo(ctx context.Context, client *Client, modulePath, v string) (info
 		if transformCommit != nil {
 			commit = transformCommit(commit, isHash)
 		}
+		tracef(ctx, "%s: static host rule matched repo %q, module dir %q, version %q maps to commit %q", modulePath, repo, relativeModulePath, v, commit)
 		info = &Info{
 			repoURL:   trimVCSSuffix("https://" + repo),
 			moduleDir: relativeModulePath,
@@ -291,11 +298,20 @@ func ModuleInfo(ctx context.Context, client *Client, modulePath, v string) (info
 			templates: templates,
 		}
 	}
+	if info == nil && client.httpClient == nil && strings.HasPrefix(modulePath, "golang.org/") {
+		// Offline clients can't fetch the meta tags of golang.org, but its repositories
+		// are known.
+		info = offlineGoRepoInfo(modulePath, v)
+	}
 	if info != nil {
 		adjustVersionedModuleDirectory(ctx, client, info)
 	}
-	if strings.HasPrefix(modulePath, "golang.org/") {
+	if info != nil && strings.HasPrefix(modulePath, "golang.org/") {
 		adjustGoRepoInfo(info, modulePath, version.IsPseudo(v))
+		tracef(ctx, "%s: applied golang.org repo adjustments", modulePath)
+	}
+	if info != nil {
+		tracef(ctx, "%s: resolved to repo %q, module dir %q, commit %q", modulePath, info.repoURL, info.moduleDir, info.commit)
 	}
 	return info, nil
 	// TODO(golang/go#39627): support launchpad.net, including the special case
@@ -469,6 +485,7 @@ func moduleInfoDynamic(ctx context.Context, client *Client, modulePath, version
 	if err != nil {
 		return nil, err
 	}
+	tracef(ctx, "%s: meta tags declare repo root %q, repo URL %q, dir template %q, file template %q", modulePath, sourceMeta.repoRootPrefix, sourceMeta.repoURL, sourceMeta.dirTemplate, sourceMeta.fileTemplate)
 	// Don't check that the tag information at the repo root prefix is the same
 	// as in the module path. It was done for us by the proxy and/or go command.
 	// (This lets us merge information from the go-import and go-source tags.)
@@ -497,6 +514,7 @@ func moduleInfoDynamic(ctx context.Context, client *Client, modulePath, version
 		repo, _, templates, transformCommit, _ = matchStatic(removeHTTPScheme(sourceMeta.dirTemplate))
 		if templates == (urlTemplates{}) {
 			if err == nil {
+				tracef(ctx, "%s: no host rule matches the repo URL or dir template, trying legacy templates", modulePath)
 				templates, transformCommit = matchLegacyTemplates(ctx, sourceMeta)
 				repoURL = strings.TrimSuffix(repoURL, ".git")
 			} else {
@@ -505,7 +523,15 @@ func moduleInfoDynamic(ctx context.Context, client *Client, modulePath, version
 		} else {
 			// Use the repo from the template, not the original one.
 			repoURL = "https://" + repo
+			tracef(ctx, "%s: host rule matched the dir template, using repo %q", modulePath, repoURL)
 		}
+	} else {
+		tracef(ctx, "%s: host rule matched the repo URL from meta tags", modulePath)
+	}
+	if rule, ok := client.hostRule(removeHTTPScheme(repoURL)); ok {
+		repoURL = strings.TrimSuffix(repoURL, ".git")
+		templates, transformCommit = rule.templates()
+		tracef(ctx, "%s: host rule for %q matched the repo URL from meta tags", modulePath, rule.Host)
 	}
 	dir := strings.TrimPrefix(strings.TrimPrefix(modulePath, sourceMeta.repoRootPrefix), "/")
 	commit, isHash := commitFromVersion(version, dir)
@@ -594,8 +620,10 @@ func adjustVersionedModuleDirectory(ctx context.Context, client *Client, info *I
 	res, err := client.doURL(ctx, "HEAD", info.FileURL("go.mod"), true)
 	// On any failure, assume that the right directory is the one without the version.
 	if err != nil {
+		tracef(ctx, "versioned module dir %q has no go.mod (%v), using %q", info.moduleDir, err, dirWithoutVersion)
 		info.moduleDir = dirWithoutVersion
 	} else {
+		tracef(ctx, "versioned module dir %q has a go.mod", info.moduleDir)
 		res.Body.Close()
 	}
 }
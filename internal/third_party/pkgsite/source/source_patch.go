@@ -14,6 +14,16 @@
 
 package source
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/derrors"
+)
+
 // This file includes all local additions to source package for google/go-licenses use-cases.
 
 // SetCommit overrides commit to a specified commit. Usually, you should pass your version to
@@ -31,3 +41,199 @@ func (i *Info) SetCommit(commit string) {
 	}
 	i.commit = commit
 }
+
+// NewOfflineClient returns a Client that makes no HTTP requests. Module paths that no
+// static host rule matches resolve to a nil *Info, and versioned module directories are
+// assumed to follow the major branch convention, i.e. not to exist in the repository.
+func NewOfflineClient() *Client {
+	return &Client{}
+}
+
+// offlineGoRepoInfo returns the info of a module in one of the Go project's repositories
+// on golang.org without fetching its meta tags, or nil if the repository is unknown. The
+// repository URL and templates are set by adjustGoRepoInfo.
+func offlineGoRepoInfo(modulePath, v string) *Info {
+	parts := strings.SplitN(strings.TrimPrefix(modulePath, "golang.org/"), "/", 3)
+	repo := parts[0]
+	if repo == "x" && len(parts) > 1 {
+		repo = "x/" + parts[1]
+	}
+	if !csXRepos[repo] && !csNonXRepos[repo] {
+		return nil
+	}
+	relativeModulePath := strings.TrimPrefix(strings.TrimPrefix(modulePath, "golang.org/"+repo), "/")
+	commit, _ := commitFromVersion(v, relativeModulePath)
+	return &Info{moduleDir: relativeModulePath, commit: commit}
+}
+
+// Repo returns the URL of the repository containing the module. Unlike RepoURL, it is not
+// expanded with the host's URL templates.
+func (i *Info) Repo() string {
+	if i == nil {
+		return ""
+	}
+	return i.repoURL
+}
+
+// Commit returns the tag or ID of the commit corresponding to the module version.
+func (i *Info) Commit() string {
+	if i == nil {
+		return ""
+	}
+	return i.commit
+}
+
+// ModuleDir returns the directory of the module relative to the repository root.
+func (i *Info) ModuleDir() string {
+	if i == nil {
+		return ""
+	}
+	return i.moduleDir
+}
+
+type tracefKey struct{}
+
+// WithTracef returns a context that makes ModuleInfo log every step of resolving module
+// info with tracef, e.g. which host rule matched, the meta tags found and the fallbacks
+// applied. This helps to find out why a wrong URL was constructed.
+func WithTracef(ctx context.Context, tracef func(format string, args ...interface{})) context.Context {
+	return context.WithValue(ctx, tracefKey{}, tracef)
+}
+
+func tracef(ctx context.Context, format string, args ...interface{}) {
+	if f, ok := ctx.Value(tracefKey{}).(func(format string, args ...interface{})); ok {
+		f(format, args...)
+	}
+}
+
+// HostRule maps the repositories on a code host that the static host rules don't know, e.g. a
+// self-hosted GitLab, Gitea or Azure DevOps instance, to URL templates.
+type HostRule struct {
+	// Host is the host name of module paths and repository URLs on the code host, e.g.
+	// git.example.com.
+	Host string
+	// RepoDepth is the number of path elements after Host that name a repository, e.g. 2 for
+	// git.example.com/group/project. If it is 0, modules are mapped to repositories by their
+	// go-import meta tags, and only the templates of the rule apply.
+	RepoDepth int
+	// Kind is the code hosting software, one of HostKinds, whose URL templates are used.
+	Kind string
+	// File and Raw override the templates of Kind for viewing a file and its raw content,
+	// e.g. "{repo}/src/{commit}/{file}". {repo}, {commit} and {file} expand to the
+	// repository URL, the commit and the path of the file relative to the repository root.
+	File string
+	Raw  string
+}
+
+// hostKinds are the URL templates of code hosting software that can be self-hosted.
+var hostKinds = map[string]struct {
+	templates       urlTemplates
+	transformCommit transformCommitFunc
+}{
+	"github": {templates: githubURLTemplates},
+	"gitlab": {templates: gitlab2URLTemplates},
+	"gitea":  {templates: giteaURLTemplates, transformCommit: giteaTransformCommit},
+	// Gogs links commits without their type, see the gogs pattern in patterns.
+	"gogs":      {templates: giteaURLTemplates},
+	"bitbucket": {templates: bitbucketURLTemplates},
+	"azure":     {templates: azureURLTemplates, transformCommit: azureTransformCommit},
+}
+
+// HostKinds returns the values of HostRule.Kind, in alphabetical order.
+func HostKinds() []string {
+	kinds := make([]string, 0, len(hostKinds))
+	for k := range hostKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// azureURLTemplates are the templates of Azure DevOps Services and Server, which select
+// files and versions with query parameters. Azure DevOps has no URLs for raw content
+// that work without authentication.
+var azureURLTemplates = urlTemplates{
+	Directory: "{repo}?path=/{dir}&version={commit}",
+	File:      "{repo}?path=/{file}&version={commit}",
+	Line:      "{repo}?path=/{file}&version={commit}&line={line}&lineEnd={line}&lineStartColumn=1&lineEndColumn=1",
+}
+
+// azureTransformCommit transforms commits for Azure DevOps, which prefixes tags with "GT"
+// and commit IDs with "GC".
+func azureTransformCommit(commit string, isHash bool) string {
+	if isHash {
+		return "GC" + commit
+	}
+	return "GT" + commit
+}
+
+// Validate reports whether r is complete and its Kind is known.
+func (r HostRule) Validate() error {
+	switch {
+	case r.Host == "" || strings.ContainsAny(r.Host, "/:"):
+		return fmt.Errorf("host %q must be a host name without scheme or path", r.Host)
+	case r.RepoDepth < 0:
+		return fmt.Errorf("host %s: repo depth %d must not be negative", r.Host, r.RepoDepth)
+	case r.Kind == "" && r.File == "":
+		return fmt.Errorf("host %s: kind or file template is required", r.Host)
+	}
+	if _, ok := hostKinds[r.Kind]; r.Kind != "" && !ok {
+		return fmt.Errorf("host %s: unknown kind %q, want one of %s", r.Host, r.Kind, strings.Join(HostKinds(), ", "))
+	}
+	return nil
+}
+
+func (r HostRule) templates() (urlTemplates, transformCommitFunc) {
+	kind := hostKinds[r.Kind]
+	templates := kind.templates
+	if r.File != "" {
+		templates.File = r.File
+	}
+	if r.Raw != "" {
+		templates.Raw = r.Raw
+	}
+	return templates, kind.transformCommit
+}
+
+// WithHostRules returns a copy of c that maps module paths and repository URLs on the
+// hosts of rules before the static host rules, so that modules on self-hosted code hosts
+// resolve to URLs on them. Rules are expected to be valid, see HostRule.Validate.
+func (c *Client) WithHostRules(rules []HostRule) *Client {
+	withRules := *c
+	withRules.hostRules = rules
+	return &withRules
+}
+
+// hostRule returns the first host rule whose host matches the host of moduleOrRepoPath.
+func (c *Client) hostRule(moduleOrRepoPath string) (HostRule, bool) {
+	host := strings.SplitN(moduleOrRepoPath, "/", 2)[0]
+	for _, r := range c.hostRules {
+		if strings.EqualFold(r.Host, host) {
+			return r, true
+		}
+	}
+	return HostRule{}, false
+}
+
+// matchHostRules is like matchStatic for the host rules of c with a RepoDepth, which map
+// module paths to repositories without fetching their meta tags.
+func (c *Client) matchHostRules(modulePath string) (repo, relativeModulePath string, _ urlTemplates, transformCommit transformCommitFunc, _ error) {
+	r, ok := c.hostRule(modulePath)
+	if !ok || r.RepoDepth == 0 {
+		return "", "", urlTemplates{}, nil, derrors.NotFound
+	}
+	parts := strings.Split(modulePath, "/")
+	if len(parts) <= r.RepoDepth {
+		return "", "", urlTemplates{}, nil, fmt.Errorf("module path %q has less than %d path elements after host %s: %w", modulePath, r.RepoDepth, r.Host, derrors.NotFound)
+	}
+	repo = strings.TrimSuffix(strings.Join(parts[:r.RepoDepth+1], "/"), ".git")
+	relativeModulePath = strings.Join(parts[r.RepoDepth+1:], "/")
+	templates, transformCommit := r.templates()
+	return repo, relativeModulePath, templates, transformCommit, nil
+}
+
+// NewClientWithHTTPClient returns a Client that makes its requests with httpClient, e.g.
+// to send them through a transport of the caller rather than http.DefaultTransport.
+func NewClientWithHTTPClient(httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithTracef(t *testing.T) {
+	var steps []string
+	ctx := WithTracef(context.Background(), func(format string, args ...interface{}) {
+		steps = append(steps, fmt.Sprintf(format, args...))
+	})
+	if _, err := ModuleInfo(ctx, NewClientForTesting(), "github.com/google/licenseclassifier", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(steps, "\n")
+	for _, want := range []string{"static host rule matched", `commit "v1.0.0"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("traced steps %q, want them to contain %q", got, want)
+		}
+	}
+}
+
+func TestWithHostRules(t *testing.T) {
+	client := NewOfflineClient().WithHostRules([]HostRule{
+		{Host: "git.example.org", RepoDepth: 2, Kind: "gitlab"},
+		{Host: "gitlab.example.org", RepoDepth: 3, Kind: "gitlab"},
+		{Host: "gitea.example.org", RepoDepth: 2, Kind: "gitea", Raw: "{repo}/raw/{commit}/{file}?download"},
+		{Host: "dev.azure.example.org", RepoDepth: 4, Kind: "azure"},
+		{Host: "src.example.org", RepoDepth: 1, File: "{repo}/files/{file}?rev={commit}"},
+	})
+	for _, test := range []struct {
+		modulePath, version string
+		wantFile, wantRaw   string
+	}{
+		{
+			"git.example.org/group/project/sub", "v1.2.3",
+			"https://git.example.org/group/project/-/blob/sub/v1.2.3/sub/LICENSE",
+			"https://git.example.org/group/project/-/raw/sub/v1.2.3/sub/LICENSE",
+		},
+		{
+			"gitea.example.org/owner/repo.git", "v0.0.0-20200101000000-0123456789ab",
+			"https://gitea.example.org/owner/repo/src/commit/0123456789ab/LICENSE",
+			"https://gitea.example.org/owner/repo/raw/commit/0123456789ab/LICENSE?download",
+		},
+		{
+			"dev.azure.example.org/org/project/_git/repo", "v1.0.0",
+			"https://dev.azure.example.org/org/project/_git/repo?path=/LICENSE&version=GTv1.0.0",
+			"",
+		},
+		{
+			"src.example.org/tool", "v1.0.0",
+			"https://src.example.org/tool/files/LICENSE?rev=v1.0.0",
+			"",
+		},
+		{
+			// Rules take precedence over the static host rule for gitlab.* hosts.
+			"gitlab.example.org/group/subgroup/project", "v1.0.0",
+			"https://gitlab.example.org/group/subgroup/project/-/blob/v1.0.0/LICENSE",
+			"https://gitlab.example.org/group/subgroup/project/-/raw/v1.0.0/LICENSE",
+		},
+	} {
+		t.Run(test.modulePath, func(t *testing.T) {
+			info, err := ModuleInfo(context.Background(), client, test.modulePath, test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.FileURL("LICENSE"); got != test.wantFile {
+				t.Errorf("FileURL() = %q, want %q", got, test.wantFile)
+			}
+			if got := info.RawURL("LICENSE"); got != test.wantRaw {
+				t.Errorf("RawURL() = %q, want %q", got, test.wantRaw)
+			}
+		})
+	}
+}
+
+func TestHostRuleValidate(t *testing.T) {
+	for _, test := range []struct {
+		rule    HostRule
+		wantErr string
+	}{
+		{HostRule{Host: "git.example.org", Kind: "gitlab"}, ""},
+		{HostRule{Host: "git.example.org", File: "{repo}/{file}"}, ""},
+		{HostRule{Host: "https://git.example.org", Kind: "gitlab"}, "without scheme"},
+		{HostRule{Host: "git.example.org", Kind: "gitlab", RepoDepth: -1}, "must not be negative"},
+		{HostRule{Host: "git.example.org"}, "kind or file template is required"},
+		{HostRule{Host: "git.example.org", Kind: "fossil"}, "unknown kind"},
+	} {
+		err := test.rule.Validate()
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v.Validate() = %v, want nil", test.rule, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%+v.Validate() = %v, want an error containing %q", test.rule, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SourceArchiveName is the name of the archive that SaveLibraries writes the source code
+// to if SaveOptions.Archive is set.
+const SourceArchiveName = "source.tar.gz"
+
+// archiveSrc writes the files below src to a gzipped tarball in dest. Like copySrc, it
+// skips .git directories. Symlinks to files are archived as the files they point to
+// unless skipSymlinks is set.
+func archiveSrc(src, dest string, skipSymlinks bool) (err error) {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dest, SourceArchiveName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if skipSymlinks {
+				return nil
+			}
+			if info, err = os.Stat(p); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				// Symlinked directories are not walked.
+				return nil
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		return copyFileTo(tw, p)
+	})
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", src, err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/google/licenseclassifier"
+)
+
+// copyrightRegexp matches copyright statements, e.g. "Copyright (c) 2016 Foo" or
+// "© 2016 Foo".
+var copyrightRegexp = regexp.MustCompile(`(?i)^\s*(copyright\s*(\(c\)|©)?|\(c\)|©)\s*\d{4}`)
+
+// IsCopyrightStatement reports whether line is a copyright statement with a year, e.g.
+// "Copyright (c) 2016 Foo".
+func IsCopyrightStatement(line string) bool {
+	return copyrightRegexp.MatchString(line)
+}
+
+// CanonicalText returns the canonical text of the license with the SPDX identifier name
+// from the classifier's dataset, e.g. the MIT permission notice without a copyright
+// statement. It returns false for licenses the dataset has no text of.
+func CanonicalText(name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\ `) {
+		return "", false
+	}
+	b, err := licenseclassifier.ReadLicenseFile(name + ".txt")
+	if err != nil {
+		return "", false
+	}
+	// Some texts of the dataset escape characters like apostrophes as HTML entities.
+	return html.UnescapeString(string(b)), true
+}
+
+// IsCanonicalCopy reports whether text is a verbatim copy of the canonical text of the
+// license name, see CanonicalText. The copy may add copyright statements, lines like "All
+// rights reserved." and a title, e.g. "The MIT License (MIT)", and differ in whitespace.
+func IsCanonicalCopy(text, name string) bool {
+	canonical, ok := CanonicalText(name)
+	if !ok {
+		return false
+	}
+	want := strings.Join(strings.Fields(canonical), " ")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if IsCopyrightStatement(line) || strings.EqualFold(strings.TrimSuffix(line, "."), "all rights reserved") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	got := strings.Join(strings.Fields(strings.Join(lines, "\n")), " ")
+	if got == want {
+		return true
+	}
+	// A title precedes the license text, unless it is part of the canonical text.
+	title := titleRegexp.FindString(got)
+	return title != "" && strings.TrimPrefix(got, title) == want
+}
+
+// titleRegexp matches the title of a license text, e.g. "The MIT License (MIT) ".
+var titleRegexp = regexp.MustCompile(`(?i)^(the )?[\w.-]+( [\w.-]+)?( license)( \([\w.-]+\))? `)
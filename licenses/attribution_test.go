@@ -0,0 +1,104 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsCanonicalCopy(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		path string
+		text string
+		name string
+		want bool
+	}{
+		{
+			desc: "MIT with copyright statement",
+			path: "testdata/MIT/LICENSE.MIT",
+			name: "MIT",
+			want: true,
+		},
+		{
+			desc: "modified MIT",
+			path: "testdata/modified-mit/LICENSE",
+			name: "MIT",
+			want: false,
+		},
+		{
+			desc: "Apache-2.0",
+			path: "testdata/license-apache-2.0/LICENSE-APACHE-2.0.txt",
+			name: "Apache-2.0",
+			want: true,
+		},
+		{
+			desc: "title and all rights reserved",
+			text: "The ISC License\n\nCopyright (c) 2019, Foo\nAll rights reserved.\n\n" + canonicalText(t, "ISC"),
+			name: "ISC",
+			want: true,
+		},
+		{
+			desc: "text of another license",
+			path: "testdata/MIT/LICENSE.MIT",
+			name: "ISC",
+			want: false,
+		},
+		{
+			desc: "license without canonical text",
+			text: "Some license",
+			name: "Unknown",
+			want: false,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			text := test.text
+			if test.path != "" {
+				b, err := os.ReadFile(test.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				text = string(b)
+			}
+			if got := IsCanonicalCopy(text, test.name); got != test.want {
+				t.Errorf("IsCanonicalCopy(_, %q) = %v, want %v", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsCopyrightStatement(t *testing.T) {
+	for line, want := range map[string]bool{
+		"Copyright (c) 2016 Foo": true,
+		"  © 2016 Foo":           true,
+		"(c) 2016-2020 Foo":      true,
+		"Copyright Foo":          false,
+		"The above copyright notice and this permission notice shall be included": false,
+	} {
+		if got := IsCopyrightStatement(line); got != want {
+			t.Errorf("IsCopyrightStatement(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func canonicalText(t *testing.T, name string) string {
+	t.Helper()
+	text, ok := CanonicalText(name)
+	if !ok {
+		t.Fatalf("CanonicalText(%q) = (_, false), want (_, true)", name)
+	}
+	return text
+}
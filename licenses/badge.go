@@ -0,0 +1,169 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxReadmeSize is the number of bytes of a README that are searched for badges, which
+// are usually at its top.
+const maxReadmeSize = 64 * 1024
+
+var (
+	// shieldsBadgeRegexp matches static shields.io badges, e.g.
+	// https://img.shields.io/badge/License-Apache_2.0-blue.svg, whose path is
+	// label-message-color.
+	shieldsBadgeRegexp = regexp.MustCompile(`img\.shields\.io/badge/([^)\s"'>?]+)`)
+	// badgeAltTextRegexp matches the alt text of Markdown images like
+	// [![License: MIT](...)](...).
+	badgeAltTextRegexp = regexp.MustCompile(`(?i)!\[licen[cs]e\s*[:\-]?\s*([^\]]*)\]`)
+	// licenseLinkRegexp matches links to license texts on opensource.org, which badges
+	// usually link to.
+	licenseLinkRegexp = regexp.MustCompile(`opensource\.org/licenses?/([A-Za-z0-9.\-]+)`)
+	// badgeVersionRegexp matches the "v" in version numbers like v2 or v2.1.
+	badgeVersionRegexp = regexp.MustCompile(`v(\d)`)
+)
+
+// badgeNames maps the names that badges commonly use for licenses, normalized by
+// badgeKey, to license names of the classifier.
+var badgeNames = map[string]string{
+	"mit":        "MIT",
+	"apache2":    "Apache-2.0",
+	"bsd":        "BSD",
+	"bsd2clause": "BSD-2-Clause",
+	"bsd3clause": "BSD-3-Clause",
+	"isc":        "ISC",
+	"mpl2":       "MPL-2.0",
+	"gpl":        "GPL",
+	"gpl2":       "GPL-2.0",
+	"gpl3":       "GPL-3.0",
+	"lgpl2.1":    "LGPL-2.1",
+	"lgpl3":      "LGPL-3.0",
+	"agpl3":      "AGPL-3.0",
+	"epl2":       "EPL-2.0",
+	"unlicense":  "Unlicense",
+	"cc0":        "CC0-1.0",
+	"cc01":       "CC0-1.0",
+	"zlib":       "Zlib",
+	"bsl1":       "BSL-1.0",
+}
+
+// BadgeLicenses returns the licenses that the license badges in the README of dir
+// declare, e.g. shields.io badges like "License: MIT". Badges are maintained by hand and
+// may be stale, so they are a hint to corroborate or question the license file, never a
+// classification. Unrecognized badges are left out; a README without license badges
+// results in no licenses.
+func BadgeLicenses(dir string) ([]string, error) {
+	text, err := readReadme(dir)
+	if err != nil || text == "" {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	for _, m := range shieldsBadgeRegexp.FindAllStringSubmatch(text, -1) {
+		label, message, ok := shieldsLabelMessage(m[1])
+		if ok && strings.Contains(strings.ToLower(label), "licen") {
+			found[badgeLicense(message)] = true
+		}
+	}
+	for _, m := range badgeAltTextRegexp.FindAllStringSubmatch(text, -1) {
+		found[badgeLicense(m[1])] = true
+	}
+	for _, m := range licenseLinkRegexp.FindAllStringSubmatch(text, -1) {
+		found[badgeLicense(strings.TrimSuffix(m[1], ".php"))] = true
+	}
+	delete(found, "")
+	var names []string
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MatchesBadge reports whether licenseName, as identified by the classifier, agrees with
+// a license returned by BadgeLicenses. Badges without a version or variant, e.g. "BSD",
+// agree with every variant of the license.
+func MatchesBadge(licenseName, badge string) bool {
+	return licenseName == badge || strings.HasPrefix(licenseName, badge+"-") || strings.HasPrefix(licenseName, badge+" ")
+}
+
+// readReadme returns the beginning of the first README file in dir, or "" if there is none.
+func readReadme(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(strings.ToLower(e.Name()), "readme") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		b, err := io.ReadAll(io.LimitReader(f, maxReadmeSize))
+		return string(b), err
+	}
+	return "", nil
+}
+
+// shieldsLabelMessage splits the path of a static shields.io badge into its label and
+// message. Dashes separate the parts, "--" is a literal dash and "_" a space.
+func shieldsLabelMessage(badgePath string) (label, message string, ok bool) {
+	badgePath = strings.TrimSuffix(badgePath, filepath.Ext(badgePath))
+	parts := strings.Split(strings.ReplaceAll(badgePath, "--", "\x00"), "-")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	unescape := func(s string) string {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, "\x00", "-"), "_", " ")
+		if u, err := url.PathUnescape(s); err == nil {
+			return u
+		}
+		return s
+	}
+	return unescape(parts[0]), unescape(parts[1]), true
+}
+
+// badgeLicense returns the license name for the license text of a badge, e.g. "Apache
+// 2.0" or "GPLv3", or "" if it is not known.
+func badgeLicense(text string) string {
+	return badgeNames[badgeKey(text)]
+}
+
+// badgeKey normalizes the spellings of a license in badges, e.g. "Apache License 2.0",
+// "Apache-2.0" and "apache v2" all result in "apache2".
+func badgeKey(text string) string {
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(text))
+	if key != "unlicense" {
+		key = strings.NewReplacer("license", "", "licence", "").Replace(key)
+	}
+	key = strings.TrimSuffix(strings.TrimSuffix(key, "only"), "orlater")
+	key = badgeVersionRegexp.ReplaceAllString(key, "$1")
+	return strings.TrimSuffix(key, ".0")
+}
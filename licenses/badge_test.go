@@ -0,0 +1,115 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBadgeLicenses(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		readme string
+		want   []string
+	}{
+		{
+			desc:   "shields.io badge",
+			readme: "[![License](https://img.shields.io/badge/License-Apache_2.0-blue.svg)](https://opensource.org/licenses/Apache-2.0)",
+			want:   []string{"Apache-2.0"},
+		},
+		{
+			desc:   "Alt text",
+			readme: "# foo\n[![License: MIT](https://example.com/badge.svg)](LICENSE)\n",
+			want:   []string{"MIT"},
+		},
+		{
+			desc:   "Escaped dashes and spaces",
+			readme: "![badge](https://img.shields.io/badge/licence-GPL--3.0--or--later-green)",
+			want:   []string{"GPL-3.0"},
+		},
+		{
+			desc:   "Dual license",
+			readme: "![License: MIT](a.svg) ![license](https://img.shields.io/badge/license-Apache%202.0-blue)",
+			want:   []string{"Apache-2.0", "MIT"},
+		},
+		{
+			desc:   "Other badges",
+			readme: "![build](https://img.shields.io/badge/build-passing-green) ![go](https://img.shields.io/badge/go-1.21-blue)",
+		},
+		{
+			desc:   "Unknown license",
+			readme: "![License: Proprietary](a.svg)",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(test.readme), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := BadgeLicenses(dir)
+			if err != nil {
+				t.Fatalf("BadgeLicenses() = (_, %q), want (_, nil)", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("BadgeLicenses(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBadgeLicensesWithoutReadme(t *testing.T) {
+	got, err := BadgeLicenses(t.TempDir())
+	if err != nil || got != nil {
+		t.Errorf("BadgeLicenses() = (%q, %v), want (nil, nil)", got, err)
+	}
+}
+
+func TestBadgeKey(t *testing.T) {
+	for text, want := range map[string]string{
+		"Apache License 2.0": "apache2",
+		"apache v2":          "apache2",
+		"BSD 3-Clause":       "bsd3clause",
+		"GPLv3":              "gpl3",
+		"LGPL v2.1":          "lgpl2.1",
+		"MPL-2.0":            "mpl2",
+		"Unlicense":          "unlicense",
+	} {
+		if got := badgeKey(text); got != want {
+			t.Errorf("badgeKey(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestMatchesBadge(t *testing.T) {
+	for _, test := range []struct {
+		licenseName, badge string
+		want               bool
+	}{
+		{"MIT", "MIT", true},
+		{"BSD-3-Clause", "BSD", true},
+		{"GPL-2.0 WITH Classpath-exception-2.0", "GPL-2.0", true},
+		{"Apache-2.0", "MIT", false},
+		{"GPL-2.0", "GPL-3.0", false},
+		{"LGPL-2.1", "GPL", false},
+	} {
+		if got := MatchesBadge(test.licenseName, test.badge); got != test.want {
+			t.Errorf("MatchesBadge(%q, %q) = %v, want %v", test.licenseName, test.badge, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/packages"
+)
+
+// BuildInfoLibraries returns the library of each dependency module recorded in the build
+// information bi of a binary, e.g. from runtime/debug.ReadBuildInfo, sorted by name.
+// Binaries don't contain license files, so like in GoSumLibraries, each library is
+// licensed by the license file in the root of its module's directory in the local module
+// cache. Modules missing from it are downloaded if opts.DownloadModules is set, and have
+// no license otherwise. Of opts, the options of ModuleLibrary apply too, and ignore rules
+// match module paths.
+func BuildInfoLibraries(ctx context.Context, classifier Classifier, opts Options, bi *debug.BuildInfo) ([]*Library, error) {
+	cfg := &packages.Config{Context: ctx, Env: goEnviron(ctx)}
+	modCache, err := goEnv(cfg, "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+	modules, err := buildInfoModules(ctx, bi, modCache, opts.DownloadModules)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := loadChecksumPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var libraries []*Library
+	rules := opts.ignoreRules()
+	for _, m := range modules {
+		if ignoredModule(m.Path, rules) {
+			continue
+		}
+		policy.apply(m)
+		libraries = append(libraries, ModuleLibrary(classifier, opts, m))
+	}
+	sort.Slice(libraries, func(i, j int) bool {
+		return libraries[i].Name() < libraries[j].Name()
+	})
+	return libraries, nil
+}
+
+// buildInfoModules returns the dependency modules of bi with their directories in the
+// module cache modCache, downloading missing ones if download is set. Modules replaced by another module version are that version.
+// Modules replaced by a local directory keep their path and have that directory if it
+// is absolute and exists, since relative ones are relative to the main module.
+func buildInfoModules(ctx context.Context, bi *debug.BuildInfo, modCache string, download bool) ([]*Module, error) {
+	var modules []*Module
+	for _, dep := range bi.Deps {
+		m := &Module{Path: dep.Path, Version: dep.Version}
+		if r := dep.Replace; r != nil {
+			m.Replaces = &Module{Path: dep.Path, Version: strings.TrimSuffix(dep.Version, "+incompatible")}
+			if r.Version == "" {
+				m.Version = ""
+				if filepath.IsAbs(r.Path) {
+					if fi, err := os.Stat(r.Path); err == nil && fi.IsDir() {
+						m.Dir = r.Path
+					}
+				}
+				if m.Dir == "" {
+					warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s is replaced by directory %s, which was not found, so its license can't be found", m.Path, r.Path)
+				}
+				modules = append(modules, m)
+				continue
+			}
+			m.Path, m.Version = r.Path, r.Version
+		}
+		escPath, err := module.EscapePath(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		escVersion, err := module.EscapeVersion(m.Version)
+		if err != nil {
+			return nil, err
+		}
+		m.Dir = filepath.Join(modCache, escPath+"@"+escVersion)
+		if _, err := os.Stat(m.Dir); err != nil && download {
+			m.Dir, err = downloadModule(ctx, m.Path, m.Version)
+			if err != nil {
+				warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s@%s is not in the module cache and could not be downloaded: %v", m.Path, m.Version, err)
+			}
+		} else if err != nil {
+			warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s@%s is not in the module cache, run \"go mod download %s@%s\" to find its license", m.Path, m.Version, m.Path, m.Version)
+			m.Dir = ""
+		}
+		// The +incompatible suffix is part of the directory, but not of the module version.
+		m.Version = strings.TrimSuffix(m.Version, "+incompatible")
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildInfoModules(t *testing.T) {
+	modCache := t.TempDir()
+	local := t.TempDir()
+	for _, dir := range []string{"example.com/!upper@v1.0.0", "example.com/fork@v1.1.0", "example.com/old@v2.0.0+incompatible"} {
+		if err := os.MkdirAll(filepath.Join(modCache, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bi := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "example.com/Upper", Version: "v1.0.0"},
+			{Path: "example.com/orig", Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.1.0"}},
+			{Path: "example.com/old", Version: "v2.0.0+incompatible"},
+			{Path: "example.com/local", Version: "v1.0.0", Replace: &debug.Module{Path: local}},
+			{Path: "example.com/relative", Version: "v1.0.0", Replace: &debug.Module{Path: "../relative"}},
+			{Path: "example.com/missing", Version: "v1.0.0"},
+		},
+	}
+	var warned []string
+	ctx := WithWarningHandler(context.Background(), func(w Warning) {
+		warned = append(warned, w.Module)
+	})
+	got, err := buildInfoModules(ctx, bi, modCache, false)
+	if err != nil {
+		t.Fatalf("buildInfoModules() = (_, %q), want (_, nil)", err)
+	}
+	want := []*Module{
+		{Path: "example.com/Upper", Version: "v1.0.0", Dir: filepath.Join(modCache, "example.com/!upper@v1.0.0")},
+		{Path: "example.com/fork", Version: "v1.1.0", Dir: filepath.Join(modCache, "example.com/fork@v1.1.0"), Replaces: &Module{Path: "example.com/orig", Version: "v1.0.0"}},
+		{Path: "example.com/old", Version: "v2.0.0", Dir: filepath.Join(modCache, "example.com/old@v2.0.0+incompatible")},
+		{Path: "example.com/local", Dir: local, Replaces: &Module{Path: "example.com/local", Version: "v1.0.0"}},
+		{Path: "example.com/relative", Replaces: &Module{Path: "example.com/relative", Version: "v1.0.0"}},
+		{Path: "example.com/missing", Version: "v1.0.0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("buildInfoModules(): (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"example.com/relative", "example.com/missing"}, warned); diff != "" {
+		t.Errorf("buildInfoModules() warned about modules (-want +got):\n%s", diff)
+	}
+}
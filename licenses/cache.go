@@ -0,0 +1,284 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+)
+
+// Cache keeps the results of classifying license files, resolving their URLs and
+// downloading license texts on disk, so that repeated runs, e.g. in CI, don't repeat
+// them. Results are stored per module version and only for files in the module cache,
+// which don't change. A Cache is safe for concurrent use.
+type Cache struct {
+	dir      string
+	modCache string
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	dirty   map[string]bool
+}
+
+// cacheEntry holds the cached results of a module version.
+type cacheEntry struct {
+	// Licenses are the classifications of license files, keyed by the fingerprint of the
+	// classifier and the slash-separated path of the file in the module.
+	Licenses map[string]cachedLicense `json:"licenses,omitempty"`
+	// URLs are the URLs of files, keyed by the fingerprint of the host rules of the
+	// resolver and the slash-separated path of the file in the module.
+	URLs map[string]string `json:"urls,omitempty"`
+	// Texts are downloaded license texts, keyed by URL.
+	Texts map[string]string `json:"texts,omitempty"`
+}
+
+// cachedLicense is the result of classifying a license file.
+type cachedLicense struct {
+	Name string `json:"name,omitempty"`
+	Type Type   `json:"type,omitempty"`
+	// Unknown is set if the file is not a known license.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// NewCache returns a Cache stored in dir for the files of the module cache at modCache.
+// Nothing is read or written until the results of a module version are needed or Save
+// is called.
+func NewCache(dir, modCache string) *Cache {
+	return &Cache{
+		dir:      dir,
+		modCache: resolveSymlinks(modCache),
+		entries:  make(map[string]*cacheEntry),
+		dirty:    make(map[string]bool),
+	}
+}
+
+// DefaultCacheDir returns the directory where go-licenses caches results by default,
+// below the user's cache directory, e.g. $XDG_CACHE_HOME/go-licenses on Linux.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	// The version is part of the path, so that changes of the format start a new cache.
+	return filepath.Join(dir, "go-licenses", "v1"), nil
+}
+
+// Save writes the results added since the last call to disk.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id := range c.dirty {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := c.write(id, c.entries[id]); err != nil {
+			return err
+		}
+		delete(c.dirty, id)
+	}
+	return nil
+}
+
+// LicenseText returns the license text downloaded from url for version of module
+// modulePath, if cached.
+func (c *Cache) LicenseText(modulePath, version, url string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	text, ok := c.entry(modulePath, version).Texts[url]
+	return text, ok
+}
+
+// SetLicenseText caches the license text downloaded from url for version of module
+// modulePath.
+func (c *Cache) SetLicenseText(modulePath, version, url, text string) {
+	c.update(modulePath, version, func(e *cacheEntry) {
+		if e.Texts == nil {
+			e.Texts = make(map[string]string)
+		}
+		e.Texts[url] = text
+	})
+}
+
+// license returns the cached classification of the license file at path by the
+// classifier with fingerprint, if any.
+func (c *Cache) license(path, fingerprint string) (cachedLicense, bool) {
+	modulePath, version, file, ok := c.moduleFile(path)
+	if !ok {
+		return cachedLicense{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.entry(modulePath, version).Licenses[fingerprint+":"+file]
+	return l, ok
+}
+
+// setLicense caches the classification of the license file at path, if it is in the
+// module cache.
+func (c *Cache) setLicense(path, fingerprint string, l cachedLicense) {
+	modulePath, version, file, ok := c.moduleFile(path)
+	if !ok {
+		return
+	}
+	c.update(modulePath, version, func(e *cacheEntry) {
+		if e.Licenses == nil {
+			e.Licenses = make(map[string]cachedLicense)
+		}
+		e.Licenses[fingerprint+":"+file] = l
+	})
+}
+
+// fileURL returns the cached URL of file, a slash-separated path in version of module
+// modulePath, as resolved with the host rules with fingerprint.
+func (c *Cache) fileURL(modulePath, version, fingerprint, file string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	url, ok := c.entry(modulePath, version).URLs[fingerprint+":"+file]
+	return url, ok
+}
+
+// setFileURL caches the URL of file, a slash-separated path in version of module
+// modulePath, as resolved with the host rules with fingerprint.
+func (c *Cache) setFileURL(modulePath, version, fingerprint, file, url string) {
+	c.update(modulePath, version, func(e *cacheEntry) {
+		if e.URLs == nil {
+			e.URLs = make(map[string]string)
+		}
+		e.URLs[fingerprint+":"+file] = url
+	})
+}
+
+// moduleFile splits path, a file in the module cache, into the module path and version
+// of its module and its slash-separated path in the module. ok is false for files
+// outside of the module cache.
+func (c *Cache) moduleFile(path string) (modulePath, version, file string, ok bool) {
+	if c.modCache == "" {
+		return "", "", "", false
+	}
+	rel, err := filepath.Rel(c.modCache, resolveSymlinks(path))
+	if err != nil {
+		return "", "", "", false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for i, elem := range elems {
+		if elem == ".." || (i == 0 && elem == "cache") {
+			// Outside of the module cache, or in its download cache.
+			return "", "", "", false
+		}
+		at := strings.LastIndex(elem, "@")
+		if at < 0 {
+			continue
+		}
+		escaped := strings.Join(append(elems[:i:i], elem[:at]), "/")
+		if modulePath, err = module.UnescapePath(escaped); err != nil {
+			return "", "", "", false
+		}
+		if version, err = module.UnescapeVersion(elem[at+1:]); err != nil {
+			return "", "", "", false
+		}
+		return modulePath, version, strings.Join(elems[i+1:], "/"), i+1 < len(elems)
+	}
+	return "", "", "", false
+}
+
+// update applies f to the entry of version of module modulePath and marks it to be saved.
+// Modules without a version, e.g. the main module, are not cached.
+func (c *Cache) update(modulePath, version string, f func(*cacheEntry)) {
+	if version == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(c.entry(modulePath, version))
+	c.dirty[modulePath+"@"+version] = true
+}
+
+// entry returns the entry of version of module modulePath, reading it from disk the
+// first time. c.mu must be held.
+func (c *Cache) entry(modulePath, version string) *cacheEntry {
+	id := modulePath + "@" + version
+	if e, ok := c.entries[id]; ok {
+		return e
+	}
+	e := &cacheEntry{}
+	if path, err := c.path(modulePath, version); err == nil {
+		if b, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(b, e); err != nil {
+				// A corrupt entry is replaced by the results of this run.
+				e = &cacheEntry{}
+			}
+		}
+	}
+	c.entries[id] = e
+	return e
+}
+
+// write writes the entry with id, module path@version, to disk.
+func (c *Cache) write(id string, e *cacheEntry) error {
+	at := strings.LastIndex(id, "@")
+	path, err := c.path(id[:at], id[at+1:])
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	// Entries are replaced atomically, so that concurrent runs never read partial ones.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	_, werr := tmp.Write(b)
+	if cerr := tmp.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr == nil {
+		werr = os.Rename(tmp.Name(), path)
+	}
+	if werr != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache: %w", werr)
+	}
+	return nil
+}
+
+// path returns the file that the entry of version of module modulePath is stored in.
+func (c *Cache) path(modulePath, version string) (string, error) {
+	if version == "" {
+		return "", errors.New("module has no version")
+	}
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.dir, filepath.FromSlash(escapedPath)+"@"+escapedVersion+".json"), nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheModuleFile(t *testing.T) {
+	modCache := t.TempDir()
+	c := NewCache(t.TempDir(), modCache)
+	for _, test := range []struct {
+		path        string
+		wantModule  string
+		wantVersion string
+		wantFile    string
+		wantOK      bool
+	}{
+		{
+			path:        filepath.Join(modCache, "github.com/!burnt!sushi/toml@v1.2.0/LICENSE"),
+			wantModule:  "github.com/BurntSushi/toml",
+			wantVersion: "v1.2.0",
+			wantFile:    "LICENSE",
+			wantOK:      true,
+		},
+		{
+			path:        filepath.Join(modCache, "golang.org/x/sys@v0.1.0/unix/LICENSE"),
+			wantModule:  "golang.org/x/sys",
+			wantVersion: "v0.1.0",
+			wantFile:    "unix/LICENSE",
+			wantOK:      true,
+		},
+		{path: filepath.Join(modCache, "cache/download/golang.org/x/sys/@v/v0.1.0.info")},
+		{path: "/src/example.com/module/LICENSE"},
+	} {
+		module, version, file, ok := c.moduleFile(test.path)
+		if module != test.wantModule || version != test.wantVersion || file != test.wantFile || ok != test.wantOK {
+			t.Errorf("moduleFile(%q) = (%q, %q, %q, %t), want (%q, %q, %q, %t)", test.path, module, version, file, ok, test.wantModule, test.wantVersion, test.wantFile, test.wantOK)
+		}
+	}
+}
+
+func TestCacheIdentify(t *testing.T) {
+	modCache := t.TempDir()
+	cacheDir := t.TempDir()
+	licensePath := filepath.Join(modCache, "example.com/module@v1.0.0/LICENSE")
+	if err := os.MkdirAll(filepath.Dir(licensePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mit, err := os.ReadFile("testdata/MIT/LICENSE.MIT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(licensePath, mit, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	identify := func(threshold float64) (string, error) {
+		t.Helper()
+		cache := NewCache(cacheDir, modCache)
+		c, err := NewClassifier(threshold, WithCache(cache))
+		if err != nil {
+			t.Fatalf("NewClassifier(%v) = (_, %q), want (_, nil)", threshold, err)
+		}
+		name, _, err := c.Identify(licensePath)
+		if serr := cache.Save(); serr != nil {
+			t.Fatalf("Save() = %q, want nil", serr)
+		}
+		return name, err
+	}
+
+	if name, err := identify(0.9); err != nil || name != "MIT" {
+		t.Fatalf("Identify(%q) = (%q, %v), want (%q, nil)", licensePath, name, err, "MIT")
+	}
+	// Module cache files never change, so a changed file shows whether results come from
+	// the cache.
+	if err := os.WriteFile(licensePath, []byte("All rights reserved."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := identify(0.9); err != nil || name != "MIT" {
+		t.Errorf("Identify(%q) with cache = (%q, %v), want (%q, nil)", licensePath, name, err, "MIT")
+	}
+	if name, err := identify(0.95); err == nil {
+		t.Errorf("Identify(%q) with another threshold = (%q, nil), want error", licensePath, name)
+	}
+}
+
+func TestCacheLicenseText(t *testing.T) {
+	dir := t.TempDir()
+	const url = "https://raw.githubusercontent.com/example/module/v1.0.0/LICENSE"
+	c := NewCache(dir, "")
+	c.SetLicenseText("example.com/module", "v1.0.0", url, "license text")
+	// Modules without a version are not cached.
+	c.SetLicenseText("example.com/main", "", url, "main license text")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() = %q, want nil", err)
+	}
+
+	c = NewCache(dir, "")
+	if text, ok := c.LicenseText("example.com/module", "v1.0.0", url); !ok || text != "license text" {
+		t.Errorf("LicenseText() = (%q, %t), want (%q, true)", text, ok, "license text")
+	}
+	if text, ok := c.LicenseText("example.com/module", "v1.1.0", url); ok {
+		t.Errorf("LicenseText() of another version = (%q, true), want (_, false)", text)
+	}
+	if text, ok := c.LicenseText("example.com/main", "", url); ok {
+		t.Errorf("LicenseText() without version = (%q, true), want (_, false)", text)
+	}
+}
+
+func TestCacheFileURL(t *testing.T) {
+	dir := t.TempDir()
+	const module, version = "git.example.com/group/project", "v1.0.0"
+	moduleFileURL := func(rules ...HostRule) string {
+		t.Helper()
+		cache := NewCache(dir, "")
+		opts := Options{SourceResolver: NewPkgsiteResolver(0, rules...), Cache: cache}
+		url, err := ModuleFileURL(context.Background(), opts, module, version, "LICENSE")
+		if err != nil {
+			t.Fatalf("ModuleFileURL() = (_, %q), want (_, nil)", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() = %q, want nil", err)
+		}
+		return url
+	}
+
+	gitlab := HostRule{Host: "git.example.com", RepoDepth: 2, Kind: "gitlab"}
+	const gitlabURL = "https://git.example.com/group/project/-/blob/v1.0.0/LICENSE"
+	if got := moduleFileURL(gitlab); got != gitlabURL {
+		t.Fatalf("ModuleFileURL() = %q, want %q", got, gitlabURL)
+	}
+	if got := moduleFileURL(gitlab); got != gitlabURL {
+		t.Errorf("ModuleFileURL() with cache = %q, want %q", got, gitlabURL)
+	}
+	// Changing the host rules in the config must not serve the URLs of the old ones.
+	browse := HostRule{Host: "git.example.com", RepoDepth: 2, File: "{repo}/browse/{file}?at={commit}"}
+	const browseURL = "https://git.example.com/group/project/browse/LICENSE?at=v1.0.0"
+	if got := moduleFileURL(browse); got != browseURL {
+		t.Errorf("ModuleFileURL() with other host rules = %q, want %q", got, browseURL)
+	}
+}
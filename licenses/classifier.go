@@ -15,10 +15,18 @@
 package licenses
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
 
 	"github.com/google/licenseclassifier"
+	"k8s.io/klog/v2"
 )
 
 // Type identifies a class of software license.
@@ -68,34 +76,284 @@ type Classifier interface {
 	Identify(licensePath string) (string, Type, error)
 }
 
+// ClassifierInfo describes the classifier backend that identified licenses, so that
+// results can be audited and differences between runs explained.
+type ClassifierInfo struct {
+	// Name of the classifier backend.
+	Name string `json:"name"`
+	// Version of the classifier backend, if known.
+	Version string `json:"version,omitempty"`
+	// DatasetRevision identifies the set of license texts the classifier matches against, if known.
+	DatasetRevision string `json:"datasetRevision,omitempty"`
+}
+
+// DescribeClassifier returns information about the backend of classifier.
+// Classifiers not created by NewClassifier are described by their Go type.
+func DescribeClassifier(classifier Classifier) ClassifierInfo {
+	if d, ok := classifier.(interface{ Info() ClassifierInfo }); ok {
+		return d.Info()
+	}
+	return ClassifierInfo{Name: fmt.Sprintf("%T", classifier)}
+}
+
+const googleClassifierModule = "github.com/google/licenseclassifier"
+
+// DefaultMaxLicenseFileSize is the default number of bytes of a license file that the
+// classifier scans. Larger files, e.g. concatenations of many license texts, are only
+// partially scanned because classification time grows quickly with the text size.
+const DefaultMaxLicenseFileSize = 512 * 1024
+
 type googleClassifier struct {
 	classifier *licenseclassifier.License
+	// threshold is the confidence required for licenses without an entry in licenseThresholds.
+	threshold float64
+	// licenseThresholds maps license names to the confidence required to identify them.
+	licenseThresholds map[string]float64
+	// maxFileSize is the number of bytes of a license file that are scanned, 0 means unlimited.
+	maxFileSize int64
+	// cache, if set, keeps the results of Identify for files in the module cache.
+	cache *Cache
+	// fingerprint identifies the settings and dataset that results depend on in cache.
+	fingerprint string
+}
+
+// ClassifierOption configures optional behaviour of the classifier returned by NewClassifier.
+type ClassifierOption func(*googleClassifier)
+
+// WithLicenseThresholds sets per-license confidence thresholds, keyed by license name
+// (e.g. "GPL-2.0"). They take precedence over the confidence threshold passed to
+// NewClassifier, so that licenses where a false negative is costly can require a higher
+// confidence than others.
+func WithLicenseThresholds(thresholds map[string]float64) ClassifierOption {
+	return func(c *googleClassifier) {
+		c.licenseThresholds = thresholds
+	}
+}
+
+// WithMaxFileSize sets the number of bytes of a license file that are scanned,
+// DefaultMaxLicenseFileSize by default. Use 0 to scan files of any size.
+func WithMaxFileSize(n int64) ClassifierOption {
+	return func(c *googleClassifier) {
+		c.maxFileSize = n
+	}
+}
+
+// WithCache keeps the results of Identify for files in the module cache in cache, so that
+// they are not classified again in later runs with the same settings.
+func WithCache(cache *Cache) ClassifierOption {
+	return func(c *googleClassifier) {
+		c.cache = cache
+	}
 }
 
 // NewClassifier creates a classifier that requires a specified confidence threshold
 // in order to return a positive license classification.
-func NewClassifier(confidenceThreshold float64) (Classifier, error) {
-	c, err := licenseclassifier.New(confidenceThreshold)
+func NewClassifier(confidenceThreshold float64, opts ...ClassifierOption) (Classifier, error) {
+	if confidenceThreshold <= 0 || confidenceThreshold > 1 {
+		return nil, fmt.Errorf("confidence threshold must be greater than 0 and at most 1, got %v", confidenceThreshold)
+	}
+	gc := &googleClassifier{threshold: confidenceThreshold, maxFileSize: DefaultMaxLicenseFileSize}
+	for _, opt := range opts {
+		opt(gc)
+	}
+	// The underlying classifier has to accept every match that at least one of the
+	// thresholds would accept; Identify then applies the threshold of each match.
+	minThreshold := confidenceThreshold
+	for name, t := range gc.licenseThresholds {
+		if t < 0 || t > 1 {
+			return nil, fmt.Errorf("confidence threshold for license %q must be between 0 and 1, got %v", name, t)
+		}
+		minThreshold = math.Min(minThreshold, t)
+	}
+	c, err := licenseclassifier.New(minThreshold)
 	if err != nil {
 		return nil, err
 	}
-	return &googleClassifier{classifier: c}, nil
+	gc.classifier = c
+	if gc.cache != nil {
+		gc.fingerprint = gc.settingsFingerprint()
+	}
+	return gc, nil
 }
 
+// settingsFingerprint returns a digest of the settings and the dataset of c, which
+// determine the results of Identify.
+func (c *googleClassifier) settingsFingerprint() string {
+	var names []string
+	for name := range c.licenseThresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %v %d", c.Info().DatasetRevision, c.threshold, c.maxFileSize)
+	for _, name := range names {
+		fmt.Fprintf(h, " %s=%v", name, c.licenseThresholds[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// errUnknownLicense is returned by Identify for files that are not a known license.
+var errUnknownLicense = errors.New("unknown license")
+
 // Identify returns the name and type of a license, given its file path.
 // An empty license path results in an empty name and Unknown type.
 func (c *googleClassifier) Identify(licensePath string) (string, Type, error) {
 	if licensePath == "" {
 		return "", Unknown, nil
 	}
-	content, err := os.ReadFile(licensePath)
+	if c.cache == nil {
+		return c.identify(licensePath)
+	}
+	if l, ok := c.cache.license(licensePath, c.fingerprint); ok {
+		if l.Unknown {
+			return "", "", errUnknownLicense
+		}
+		return l.Name, l.Type, nil
+	}
+	name, typ, err := c.identify(licensePath)
+	if err == nil || errors.Is(err, errUnknownLicense) {
+		c.cache.setLicense(licensePath, c.fingerprint, cachedLicense{Name: name, Type: typ, Unknown: err != nil})
+	}
+	return name, typ, err
+}
+
+// identify is Identify without the cache.
+func (c *googleClassifier) identify(licensePath string) (string, Type, error) {
+	text, size, err := c.readText(licensePath)
 	if err != nil {
 		return "", "", err
 	}
-	matches := c.classifier.MultipleMatch(string(content), true)
-	if len(matches) == 0 {
-		return "", "", fmt.Errorf("unknown license")
+	if IsOversized(size, c.maxFileSize) {
+		klog.Warningf("License file %q is oversized (%d bytes), only the first %d bytes were scanned and the classification may be incomplete", licensePath, size, c.maxFileSize)
+	}
+	// Matches are sorted by descending confidence, so the first one meeting its
+	// threshold is the best match. Exceptions to it are part of the returned name, since
+	// they change what the license allows.
+	for _, m := range c.classifier.MultipleMatch(text, true) {
+		if c.withinThreshold(m.Name, m.Confidence) {
+			return withException(m.Name, text), Type(licenseclassifier.LicenseType(m.Name)), nil
+		}
+	}
+	return "", "", errUnknownLicense
+}
+
+// Info returns the module version of licenseclassifier this binary was built with and
+// a digest of its license archive.
+func (c *googleClassifier) Info() ClassifierInfo {
+	info := ClassifierInfo{Name: googleClassifierModule}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == googleClassifierModule {
+				info.Version = dep.Version
+				if dep.Replace != nil {
+					info.Version = dep.Replace.Version
+				}
+				break
+			}
+		}
+	}
+	if archive, err := licenseclassifier.ReadLicenseFile(licenseclassifier.LicenseArchive); err == nil {
+		sum := sha256.Sum256(archive)
+		info.DatasetRevision = "sha256:" + hex.EncodeToString(sum[:])[:12]
+	}
+	return info
+}
+
+// NearestMatch returns the known license that the file at licensePath is most similar to
+// and the confidence of that match, even if it is below the confidence threshold.
+func (c *googleClassifier) NearestMatch(licensePath string) (string, float64, error) {
+	text, _, err := c.readText(licensePath)
+	if err != nil {
+		return "", 0, err
+	}
+	m := c.classifier.NearestMatch(text)
+	if m == nil {
+		return "", 0, nil
+	}
+	return m.Name, m.Confidence, nil
+}
+
+// nearestMatcher is implemented by classifiers that can report near misses.
+type nearestMatcher interface {
+	NearestMatch(licensePath string) (string, float64, error)
+}
+
+// LicenseMatch is a known license that a file was found similar to.
+type LicenseMatch struct {
+	Name       string
+	Type       Type
+	Confidence float64
+	// Accepted is true if Confidence meets the threshold configured for the license.
+	Accepted bool
+}
+
+// Matches returns the known licenses that the file at licensePath is similar to, sorted
+// by descending confidence. The result includes matches below the confidence threshold,
+// or at least the nearest one, to explain why a license was or wasn't identified.
+func (c *googleClassifier) Matches(licensePath string) ([]LicenseMatch, error) {
+	text, _, err := c.readText(licensePath)
+	if err != nil {
+		return nil, err
+	}
+	found := c.classifier.MultipleMatch(text, true)
+	if len(found) == 0 {
+		if m := c.classifier.NearestMatch(text); m != nil {
+			found = append(found, m)
+		}
+	}
+	var matches []LicenseMatch
+	for _, m := range found {
+		matches = append(matches, LicenseMatch{
+			Name:       m.Name,
+			Type:       Type(licenseclassifier.LicenseType(m.Name)),
+			Confidence: m.Confidence,
+			Accepted:   c.withinThreshold(m.Name, m.Confidence),
+		})
+	}
+	return matches, nil
+}
+
+// Matches returns the known licenses that the file at licensePath is similar to according
+// to classifier, with their confidence. It returns nil if the classifier doesn't support
+// reporting matches.
+func Matches(classifier Classifier, licensePath string) ([]LicenseMatch, error) {
+	m, ok := classifier.(interface {
+		Matches(licensePath string) ([]LicenseMatch, error)
+	})
+	if !ok {
+		return nil, nil
+	}
+	return m.Matches(licensePath)
+}
+
+// readText returns the text of the license file at licensePath, truncated to the maximum
+// file size, and the size of the file. For Go files, the text is their header comment.
+func (c *googleClassifier) readText(licensePath string) (string, int64, error) {
+	content, err := os.ReadFile(licensePath)
+	if err != nil {
+		return "", 0, err
+	}
+	if isGoFile(licensePath) {
+		content = []byte(headerComment(licensePath, content))
+	}
+	size := int64(len(content))
+	if IsOversized(size, c.maxFileSize) {
+		return strings.ToValidUTF8(string(content[:c.maxFileSize]), ""), size, nil
+	}
+	return string(content), size, nil
+}
+
+// IsOversized reports whether a license file of the given size exceeds maxFileSize and
+// is therefore only partially scanned. A maxFileSize of 0 means unlimited.
+func IsOversized(size, maxFileSize int64) bool {
+	return maxFileSize > 0 && size > maxFileSize
+}
+
+// withinThreshold reports whether confidence meets the threshold configured for licenseName.
+func (c *googleClassifier) withinThreshold(licenseName string, confidence float64) bool {
+	threshold, ok := c.licenseThresholds[licenseName]
+	if !ok {
+		threshold = c.threshold
 	}
-	licenseName := matches[0].Name
-	return licenseName, Type(licenseclassifier.LicenseType(licenseName)), nil
+	return confidence >= threshold
 }
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -38,6 +39,7 @@ func (c classifierStub) Identify(licensePath string) (string, Type, error) {
 	if err != nil {
 		return "", Unknown, err
 	}
+	relPath = filepath.ToSlash(relPath)
 	if name, ok := c.licenseNames[relPath]; ok {
 		return name, c.licenseTypes[relPath], c.errors[relPath]
 	}
@@ -49,12 +51,14 @@ func (c classifierStub) Identify(licensePath string) (string, Type, error) {
 
 func TestIdentify(t *testing.T) {
 	for _, test := range []struct {
-		desc        string
-		file        string
-		confidence  float64
-		wantLicense string
-		wantType    Type
-		wantErr     bool
+		desc              string
+		file              string
+		confidence        float64
+		licenseThresholds map[string]float64
+		maxFileSize       int64
+		wantLicense       string
+		wantType          Type
+		wantErr           bool
 	}{
 		{
 			desc:        "Apache 2.0 license",
@@ -70,6 +74,49 @@ func TestIdentify(t *testing.T) {
 			wantLicense: "MIT",
 			wantType:    Notice,
 		},
+		{
+			desc:        "Apache 2.0 license with LLVM exception",
+			file:        "testdata/llvm-exception/LICENSE",
+			confidence:  0.9,
+			wantLicense: "Apache-2.0 WITH LLVM-exception",
+			wantType:    Notice,
+		},
+		{
+			desc:        "GPL 2.0 license with Classpath exception",
+			file:        "testdata/classpath-exception/LICENSE",
+			confidence:  0.9,
+			wantLicense: "GPL-2.0-only WITH Classpath-exception-2.0",
+			wantType:    Restricted,
+		},
+		{
+			desc:        "modified MIT license below default threshold",
+			file:        "testdata/modified-mit/LICENSE",
+			confidence:  0.9,
+			wantLicense: "MIT",
+			wantType:    Notice,
+		},
+		{
+			desc:              "modified MIT license below per-license threshold",
+			file:              "testdata/modified-mit/LICENSE",
+			confidence:        0.9,
+			licenseThresholds: map[string]float64{"MIT": 0.98},
+			wantErr:           true,
+		},
+		{
+			desc:              "per-license threshold lower than default threshold",
+			file:              "testdata/modified-mit/LICENSE",
+			confidence:        0.98,
+			licenseThresholds: map[string]float64{"MIT": 0.9},
+			wantLicense:       "MIT",
+			wantType:          Notice,
+		},
+		{
+			desc:        "oversized license file is partially scanned",
+			file:        "testdata/MIT/LICENSE.MIT",
+			confidence:  0.9,
+			maxFileSize: 100,
+			wantErr:     true,
+		},
 		{
 			desc:       "non-existent file",
 			file:       "non-existent-file",
@@ -85,7 +132,11 @@ func TestIdentify(t *testing.T) {
 		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
-			c, err := NewClassifier(test.confidence)
+			opts := []ClassifierOption{WithLicenseThresholds(test.licenseThresholds)}
+			if test.maxFileSize != 0 {
+				opts = append(opts, WithMaxFileSize(test.maxFileSize))
+			}
+			c, err := NewClassifier(test.confidence, opts...)
 			if err != nil {
 				t.Fatalf("NewClassifier(%v) = (_, %q), want (_, nil)", test.confidence, err)
 			}
@@ -101,3 +152,62 @@ func TestIdentify(t *testing.T) {
 		})
 	}
 }
+
+func TestNewClassifierThresholdRange(t *testing.T) {
+	for _, confidence := range []float64{0, -0.5, 1.5} {
+		if _, err := NewClassifier(confidence); err == nil {
+			t.Errorf("NewClassifier(%v) = (_, nil), want (_, error)", confidence)
+		}
+	}
+	if _, err := NewClassifier(1); err != nil {
+		t.Errorf("NewClassifier(1) = (_, %q), want (_, nil)", err)
+	}
+}
+
+func TestDescribeClassifier(t *testing.T) {
+	c, err := NewClassifier(0.9)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.9) = (_, %q), want (_, nil)", err)
+	}
+	info := DescribeClassifier(c)
+	if info.Name != "github.com/google/licenseclassifier" {
+		t.Errorf("DescribeClassifier().Name = %q, want %q", info.Name, "github.com/google/licenseclassifier")
+	}
+	if !strings.HasPrefix(info.DatasetRevision, "sha256:") {
+		t.Errorf("DescribeClassifier().DatasetRevision = %q, want sha256 digest", info.DatasetRevision)
+	}
+	if got, want := DescribeClassifier(classifierStub{}).Name, "licenses.classifierStub"; got != want {
+		t.Errorf("DescribeClassifier(stub).Name = %q, want %q", got, want)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	for _, test := range []struct {
+		desc         string
+		confidence   float64
+		wantAccepted bool
+	}{
+		{desc: "accepted", confidence: 0.9, wantAccepted: true},
+		{desc: "below threshold", confidence: 0.98, wantAccepted: false},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c, err := NewClassifier(test.confidence)
+			if err != nil {
+				t.Fatalf("NewClassifier(%v) = (_, %q), want (_, nil)", test.confidence, err)
+			}
+			matches, err := Matches(c, "testdata/modified-mit/LICENSE")
+			if err != nil {
+				t.Fatalf("Matches() = (_, %q), want (_, nil)", err)
+			}
+			if len(matches) == 0 {
+				t.Fatalf("Matches() = %v, want at least one match", matches)
+			}
+			if got := matches[0]; got.Name != "MIT" || got.Type != Notice || got.Accepted != test.wantAccepted {
+				t.Errorf("Matches()[0] = %+v, want MIT notice match with Accepted %t", got, test.wantAccepted)
+			}
+		})
+	}
+	if matches, err := Matches(classifierStub{}, "testdata/modified-mit/LICENSE"); matches != nil || err != nil {
+		t.Errorf("Matches(stub) = (%v, %v), want (nil, nil)", matches, err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// minCommentLicenseLength is the minimum length of a header comment to be considered a
+// license text, which avoids classifying short copyright lines and SPDX tags.
+const minCommentLicenseLength = 200
+
+// commentLicense returns the first of goFiles whose header comment is a license text
+// identified by classifier, trying doc.go first. Some older modules declare their
+// license solely in such a comment. It returns "" if there is none.
+func commentLicense(goFiles []string, classifier Classifier) string {
+	files := append([]string(nil), goFiles...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return filepath.Base(files[i]) == "doc.go" && filepath.Base(files[j]) != "doc.go"
+	})
+	for _, f := range files {
+		if len(headerComment(f, nil)) < minCommentLicenseLength {
+			continue
+		}
+		if _, _, err := classifier.Identify(f); err == nil {
+			return f
+		}
+	}
+	return ""
+}
+
+// isGoFile reports whether path is a Go source file, whose license text is the header
+// comment rather than the whole file.
+func isGoFile(path string) bool {
+	return strings.HasSuffix(path, ".go")
+}
+
+// headerComment returns the text of the comments before the package clause of the Go
+// file at path, including the package documentation. If src is not nil, it is parsed
+// instead of reading the file.
+func headerComment(path string, src []byte) string {
+	// A nil []byte must not be passed as a non-nil interface, or it is parsed as empty source.
+	var source interface{}
+	if src != nil {
+		source = src
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, source, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, cg := range f.Comments {
+		if cg.Pos() > f.Package {
+			break
+		}
+		b.WriteString(cg.Text())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Copyright is a copyright statement found in a license file or in the header of a
+// source file, e.g. "Copyright (c) 2016-2018 The Foo Authors".
+type Copyright struct {
+	// Statement is the whole statement, without comment markers.
+	Statement string `json:"statement"`
+	// Years are the years of the statement as written, e.g. "2016-2018" or "2016, 2019".
+	Years string `json:"years"`
+	// Holder is the copyright holder, e.g. "The Foo Authors", without a trailing "All
+	// rights reserved.". It is empty if the statement names none.
+	Holder string `json:"holder,omitempty"`
+	// Path of the file the statement was found in.
+	Path string `json:"path,omitempty"`
+}
+
+// copyrightPartsRegexp splits a copyright statement into its years and holder.
+var copyrightPartsRegexp = regexp.MustCompile(`(?i)^(?:copyright\s*(?:\(c\)|©)?|\(c\)|©)\s*(\d{4}(?:\s*(?:[-–,]|and)\s*(?:\d{4}|present))*)[\s,.:]*(.*)$`)
+
+// commentMarkerRegexp matches the comment markers that precede a line of a source file
+// header, e.g. "//", "/*", " * " or "#".
+var commentMarkerRegexp = regexp.MustCompile(`^\s*(//+|/\*+|\*+|#+|--|;+)?\s*`)
+
+// allRightsReservedRegexp matches the "All rights reserved." that often ends a copyright
+// statement.
+var allRightsReservedRegexp = regexp.MustCompile(`(?i)[\s,.;]*all rights reserved\.?$`)
+
+// abbreviationRegexp matches holders ending in an abbreviation, e.g. "Foo Inc.", whose
+// period is kept.
+var abbreviationRegexp = regexp.MustCompile(`(?i)\b(inc|ltd|co|corp|jr|sr)\.$`)
+
+// ParseCopyright parses line as a copyright statement with a year, e.g.
+// "// Copyright 2016 Foo Inc. All rights reserved.", ignoring leading comment markers.
+// It returns false if line is no such statement, see IsCopyrightStatement.
+func ParseCopyright(line string) (Copyright, bool) {
+	line = strings.TrimSpace(commentMarkerRegexp.ReplaceAllString(line, ""))
+	line = strings.TrimSpace(strings.TrimSuffix(line, "*/"))
+	m := copyrightPartsRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return Copyright{}, false
+	}
+	holder := allRightsReservedRegexp.ReplaceAllString(m[2], "")
+	holder = strings.TrimSpace(strings.TrimPrefix(holder, "by "))
+	holder = strings.TrimRight(holder, " ,;")
+	if !abbreviationRegexp.MatchString(holder) {
+		holder = strings.TrimRight(holder, " ,.;")
+	}
+	return Copyright{Statement: line, Years: m[1], Holder: holder}, true
+}
+
+// Copyrights returns the copyright statements in text, in order and without duplicates.
+func Copyrights(text string) []Copyright {
+	var cs []Copyright
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		if c, ok := ParseCopyright(line); ok && !seen[c.Statement] {
+			seen[c.Statement] = true
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// FileCopyrights returns the copyright statements in the file at path, e.g. a license
+// file, with their Path set.
+func FileCopyrights(path string) ([]Copyright, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cs := Copyrights(string(b))
+	for i := range cs {
+		cs[i].Path = path
+	}
+	return cs, nil
+}
+
+// applyCopyrights records the copyright statements in the headers of the Go files of
+// pkgs, see Options.ScanCopyrights.
+func (l *Library) applyCopyrights(pkgs []*packages.Package, opts Options) {
+	if !opts.ScanCopyrights {
+		return
+	}
+	var goFiles []string
+	for _, p := range pkgs {
+		goFiles = append(goFiles, p.GoFiles...)
+	}
+	var moduleDir string
+	if l.module != nil {
+		moduleDir = l.module.Dir
+	}
+	l.Copyrights = headerCopyrights(deepScanFiles(moduleDir, goFiles, opts))
+}
+
+// headerCopyrights returns the copyright statements in the headers of files, i.e. above
+// their package clause. A statement found in several files, as is common for headers, is
+// only returned for the first file by path.
+func headerCopyrights(files []string) []Copyright {
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	var cs []Copyright
+	seen := make(map[string]bool)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for i := 0; i < reuseHeaderLines && scanner.Scan(); i++ {
+			if strings.HasPrefix(scanner.Text(), "package ") {
+				break
+			}
+			if c, ok := ParseCopyright(scanner.Text()); ok && !seen[c.Statement] {
+				seen[c.Statement] = true
+				c.Path = path
+				cs = append(cs, c)
+			}
+		}
+		f.Close()
+	}
+	return cs
+}
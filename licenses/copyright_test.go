@@ -0,0 +1,96 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCopyright(t *testing.T) {
+	for _, test := range []struct {
+		line   string
+		want   Copyright
+		wantOK bool
+	}{
+		{
+			line:   "Copyright (c) 2016 Foo Inc.",
+			want:   Copyright{Statement: "Copyright (c) 2016 Foo Inc.", Years: "2016", Holder: "Foo Inc."},
+			wantOK: true,
+		},
+		{
+			line:   "// Copyright 2009 The Go Authors. All rights reserved.",
+			want:   Copyright{Statement: "Copyright 2009 The Go Authors. All rights reserved.", Years: "2009", Holder: "The Go Authors"},
+			wantOK: true,
+		},
+		{
+			line:   " * Copyright © 2014-2018, 2020 Jane Doe <jane@example.com>",
+			want:   Copyright{Statement: "Copyright © 2014-2018, 2020 Jane Doe <jane@example.com>", Years: "2014-2018, 2020", Holder: "Jane Doe <jane@example.com>"},
+			wantOK: true,
+		},
+		{
+			line:   "# (c) 2015 - present by Bar",
+			want:   Copyright{Statement: "(c) 2015 - present by Bar", Years: "2015 - present", Holder: "Bar"},
+			wantOK: true,
+		},
+		{
+			line:   "/* Copyright 2021 */",
+			want:   Copyright{Statement: "Copyright 2021", Years: "2021"},
+			wantOK: true,
+		},
+		{line: "Copyright [yyyy] [name of copyright owner]"},
+		{line: "the above copyright notice and this permission notice"},
+	} {
+		got, ok := ParseCopyright(test.line)
+		if ok != test.wantOK {
+			t.Errorf("ParseCopyright(%q) ok = %v, want %v", test.line, ok, test.wantOK)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("ParseCopyright(%q): (-want +got):\n%s", test.line, diff)
+		}
+	}
+}
+
+func TestFileCopyrights(t *testing.T) {
+	path := "testdata/copyright/LICENSE"
+	want := []Copyright{
+		{Statement: "Copyright (c) 2016-2018 The Foo Authors", Years: "2016-2018", Holder: "The Foo Authors", Path: path},
+		{Statement: "Copyright (c) 2019 Bar Inc.", Years: "2019", Holder: "Bar Inc.", Path: path},
+	}
+	got, err := FileCopyrights(path)
+	if err != nil {
+		t.Fatalf("FileCopyrights(%q) = %v", path, err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FileCopyrights(%q): (-want +got):\n%s", path, diff)
+	}
+}
+
+func TestHeaderCopyrights(t *testing.T) {
+	files := []string{
+		"testdata/copyright/b.go",
+		"testdata/copyright/a.go",
+		"testdata/copyright/sub/c.go",
+	}
+	want := []Copyright{
+		{Statement: "Copyright 2016 The Foo Authors. All rights reserved.", Years: "2016", Holder: "The Foo Authors", Path: "testdata/copyright/a.go"},
+		{Statement: "Copyright (c) 2017, 2019 Baz <baz@example.com>", Years: "2017, 2019", Holder: "Baz <baz@example.com>", Path: "testdata/copyright/b.go"},
+	}
+	if diff := cmp.Diff(want, headerCopyrights(files)); diff != "" {
+		t.Errorf("headerCopyrights(): (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedRegexp matches the comment marking generated Go files, see
+// https://go.dev/s/generatedcode.
+var generatedRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// deepScanFiles returns the files that scans of file contents, such as searching for
+// SPDX tags, should consider. It drops files excluded by opts.DeepScanExcludes and,
+// if requested, generated files, so that fixture license texts don't cause false
+// positives.
+func deepScanFiles(moduleDir string, files []string, opts Options) []string {
+	if len(opts.DeepScanExcludes) == 0 && !opts.DeepScanSkipGenerated {
+		return files
+	}
+	var kept []string
+	for _, f := range files {
+		if excludedFromDeepScan(moduleDir, f, opts.DeepScanExcludes) {
+			continue
+		}
+		if opts.DeepScanSkipGenerated && isGenerated(f) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// excludedFromDeepScan reports whether any directory between moduleDir and path matches
+// one of patterns.
+func excludedFromDeepScan(moduleDir, path string, patterns []string) bool {
+	rel, err := filepath.Rel(moduleDir, filepath.Dir(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(rel), "/") {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGenerated reports whether the Go file at path has a comment marking it as generated
+// before its package clause.
+func isGenerated(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if generatedRegexp.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeepScanFiles(t *testing.T) {
+	files := []string{
+		"testdata/reuse/reuse.go",
+		"testdata/reuse/examples/hello/hello.go",
+		"testdata/reuse/gen/gen.go",
+	}
+	for _, test := range []struct {
+		desc string
+		opts Options
+		want []string
+	}{
+		{
+			desc: "no exclusions",
+			want: files,
+		},
+		{
+			desc: "excluded directory",
+			opts: Options{DeepScanExcludes: []string{"testdata", "example*"}},
+			want: []string{"testdata/reuse/reuse.go", "testdata/reuse/gen/gen.go"},
+		},
+		{
+			desc: "generated files",
+			opts: Options{DeepScanSkipGenerated: true},
+			want: []string{"testdata/reuse/reuse.go", "testdata/reuse/examples/hello/hello.go"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := deepScanFiles("testdata/reuse", files, test.opts)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("deepScanFiles() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
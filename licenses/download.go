@@ -0,0 +1,58 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleLicensePath returns the path of the license file that applies to the package in
+// directory subdir of module modulePath at version, e.g. a Go component listed in an SBOM
+// produced by another tool. The module is downloaded to the module cache if necessary.
+func ModuleLicensePath(ctx context.Context, classifier Classifier, modulePath, version, subdir string) (string, error) {
+	dir, err := downloadModule(ctx, modulePath, version)
+	if err != nil {
+		return "", err
+	}
+	pkgDir := dir
+	if subdir = strings.Trim(subdir, "/"); subdir != "" {
+		pkgDir = filepath.Join(dir, filepath.FromSlash(subdir))
+	}
+	return Find(pkgDir, dir, classifier)
+}
+
+// downloadModule downloads module modulePath at version to the module cache and returns
+// its directory.
+func downloadModule(ctx context.Context, modulePath, version string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", modulePath+"@"+version)
+	cmd.Env = goEnviron(ctx)
+	out, err := cmd.Output()
+	var info struct {
+		Dir   string
+		Error string
+	}
+	if jsonErr := json.Unmarshal(out, &info); jsonErr == nil && info.Error != "" {
+		return "", fmt.Errorf("downloading %s@%s: %s", modulePath, version, info.Error)
+	}
+	if err != nil {
+		return "", fmt.Errorf("downloading %s@%s: %w", modulePath, version, err)
+	}
+	return info.Dir, nil
+}
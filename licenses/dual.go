@@ -0,0 +1,94 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var (
+	// choiceRegexp matches statements that let licensees choose among licenses, e.g.
+	// "Licensed under either of Apache License, Version 2.0 or MIT license at your
+	// option".
+	choiceRegexp = regexp.MustCompile(`(?i)(dual[- ]licen[sc]ed|at (your|the licensee's) (option|choice)|licen[sc]ed under either)`)
+	readmeRegexp = regexp.MustCompile(`^(?i)README`)
+)
+
+// licenseChoices returns the licenses that the license file at licensePath and the
+// license files next to it offer licensees a choice of, e.g. MIT and Apache-2.0 for a
+// module with LICENSE-MIT and LICENSE-APACHE files whose README says "at your option".
+// A single license file may also hold several license texts. Licenses are only
+// alternatives if one of the license files or a README next to them says so, otherwise
+// all of them apply, e.g. to bundled code. It returns nil unless there are at least two
+// distinct licenses to choose from, and the names sorted otherwise.
+func licenseChoices(classifier Classifier, licensePath string) []string {
+	if licensePath == "" || filepath.Ext(licensePath) == ".go" {
+		return nil
+	}
+	dir := filepath.Dir(licensePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var names, statementFiles []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case readmeRegexp.MatchString(e.Name()):
+			statementFiles = append(statementFiles, path)
+		case licenseTextRegexp.MatchString(e.Name()):
+			statementFiles = append(statementFiles, path)
+			if name, _, err := classifier.Identify(path); err == nil && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	offered := false
+	if b, err := os.ReadFile(licensePath); err == nil && choiceRegexp.Match(b) {
+		// The license file itself offers the choice, so it may hold all license texts.
+		// Matching all of them is expensive, so it is only done then.
+		offered = true
+		matches, _ := Matches(classifier, licensePath)
+		for _, m := range matches {
+			if m.Accepted && !seen[m.Name] {
+				seen[m.Name] = true
+				names = append(names, m.Name)
+			}
+		}
+	}
+	if len(names) < 2 || !(offered || anyFileMatches(statementFiles, choiceRegexp)) {
+		return nil
+	}
+	sort.Strings(names)
+	return names
+}
+
+// anyFileMatches reports whether the content of one of paths matches r.
+func anyFileMatches(paths []string, r *regexp.Regexp) bool {
+	for _, path := range paths {
+		if b, err := os.ReadFile(path); err == nil && r.Match(b) {
+			return true
+		}
+	}
+	return false
+}
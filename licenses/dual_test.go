@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLicenseChoices(t *testing.T) {
+	stub := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/dual/LICENSE-MIT":        "MIT",
+			"testdata/dual/LICENSE-APACHE":     "Apache-2.0",
+			"testdata/bundled/LICENSE":         "MIT",
+			"testdata/bundled/LICENSE.bundled": "BSD-3-Clause",
+		},
+	}
+	classifier, err := NewClassifier(0.9)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.9) = (_, %q), want (_, nil)", err)
+	}
+	for _, test := range []struct {
+		desc        string
+		classifier  Classifier
+		licensePath string
+		want        []string
+	}{
+		{
+			desc:        "license files and README offering a choice",
+			classifier:  stub,
+			licensePath: "testdata/dual/LICENSE-MIT",
+			want:        []string{"Apache-2.0", "MIT"},
+		},
+		{
+			desc:        "license file with all license texts",
+			classifier:  classifier,
+			licensePath: "testdata/dual-combined/LICENSE",
+			want:        []string{"Apache-2.0", "MIT"},
+		},
+		{
+			desc:        "license of bundled code",
+			classifier:  stub,
+			licensePath: "testdata/bundled/LICENSE",
+		},
+		{
+			desc:        "single license",
+			classifier:  classifier,
+			licensePath: "testdata/MIT/LICENSE.MIT",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			licensePath, err := filepath.Abs(test.licensePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := licenseChoices(test.classifier, licensePath)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("licenseChoices(%q): (-want +got):\n%s", test.licensePath, diff)
+			}
+		})
+	}
+}
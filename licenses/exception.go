@@ -0,0 +1,70 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "strings"
+
+// classifierExceptions maps the names of licenses with an exception that the classifier
+// knows to their SPDX expressions, since SPDX deprecated identifiers like
+// GPL-2.0-with-classpath-exception in favor of the WITH operator.
+var classifierExceptions = map[string]string{
+	"GPL-2.0-with-autoconf-exception":  "GPL-2.0-only WITH Autoconf-exception-2.0",
+	"GPL-2.0-with-bison-exception":     "GPL-2.0-only WITH Bison-exception-2.2",
+	"GPL-2.0-with-classpath-exception": "GPL-2.0-only WITH Classpath-exception-2.0",
+	"GPL-2.0-with-font-exception":      "GPL-2.0-only WITH Font-exception-2.0",
+	"GPL-2.0-with-GCC-exception":       "GPL-2.0-only WITH GCC-exception-2.0",
+	"GPL-3.0-with-autoconf-exception":  "GPL-3.0-only WITH Autoconf-exception-3.0",
+	"GPL-3.0-with-GCC-exception":       "GPL-3.0-only WITH GCC-exception-3.1",
+}
+
+// exceptionPhrases identify exceptions that license files add to the text of a license
+// the classifier knows, e.g. the LLVM exception to Apache-2.0. Phrases are lower case,
+// with single spaces.
+var exceptionPhrases = []struct {
+	id     string
+	phrase string
+}{
+	{"LLVM-exception", "llvm exceptions to the apache 2.0 license"},
+	{"Classpath-exception-2.0", "give you permission to link this library with independent modules to produce an executable"},
+	{"GCC-exception-3.1", "gcc runtime library exception"},
+	{"Universal-FOSS-exception-1.0", "universal foss exception"},
+}
+
+// withException returns the SPDX expression of license name, identified in text,
+// together with the exception to it that text contains, if any, e.g.
+// "Apache-2.0 WITH LLVM-exception".
+func withException(name, text string) string {
+	if expr, ok := classifierExceptions[name]; ok {
+		return expr
+	}
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	for _, e := range exceptionPhrases {
+		if strings.Contains(text, e.phrase) {
+			return exceptionBase(name) + " WITH " + e.id
+		}
+	}
+	return name
+}
+
+// exceptionBase returns the identifier of license name to use with an exception. The
+// deprecated identifiers of GNU licenses that the classifier uses, e.g. GPL-2.0, are
+// replaced by their -only form.
+func exceptionBase(name string) string {
+	switch name {
+	case "GPL-1.0", "GPL-2.0", "GPL-3.0", "LGPL-2.0", "LGPL-2.1", "LGPL-3.0", "AGPL-1.0", "AGPL-3.0":
+		return name + "-only"
+	}
+	return name
+}
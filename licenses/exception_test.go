@@ -0,0 +1,56 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestWithException(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		name string
+		text string
+		want string
+	}{
+		{
+			desc: "no exception",
+			name: "MIT",
+			text: "Permission is hereby granted, free of charge, to any person",
+			want: "MIT",
+		},
+		{
+			desc: "deprecated classifier identifier",
+			name: "GPL-3.0-with-GCC-exception",
+			want: "GPL-3.0-only WITH GCC-exception-3.1",
+		},
+		{
+			desc: "exception appended to the license text",
+			name: "GPL-2.0",
+			text: "As a special exception, the copyright holders of this library give you\npermission to link this library with independent modules to produce an\nexecutable, regardless of the license terms of these independent modules",
+			want: "GPL-2.0-only WITH Classpath-exception-2.0",
+		},
+		{
+			desc: "exception to a license without -only form",
+			name: "Apache-2.0",
+			text: "---- LLVM Exceptions to the Apache 2.0 License ----",
+			want: "Apache-2.0 WITH LLVM-exception",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := withException(test.name, test.text); got != test.want {
+				t.Errorf("withException(%q, _) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
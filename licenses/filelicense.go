@@ -0,0 +1,92 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// FileLicense is a license declared by an SPDX-License-Identifier tag in the header of a
+// source file, which may differ from the license of its library if the file was
+// relicensed or copied from another project.
+type FileLicense struct {
+	// Path of the file.
+	Path string `json:"path"`
+	// License is the SPDX license expression of the tag.
+	License string `json:"license"`
+}
+
+// applyFileLicenses records the licenses declared in the headers of the Go files of pkgs,
+// see Options.ScanFileLicenses.
+func (l *Library) applyFileLicenses(pkgs []*packages.Package, opts Options) {
+	if !opts.ScanFileLicenses {
+		return
+	}
+	var goFiles []string
+	for _, p := range pkgs {
+		goFiles = append(goFiles, p.GoFiles...)
+	}
+	var moduleDir string
+	if l.module != nil {
+		moduleDir = l.module.Dir
+	}
+	l.FileLicenses = fileLicenses(deepScanFiles(moduleDir, goFiles, opts))
+}
+
+// fileLicenses returns the licenses declared by the SPDX-License-Identifier tags in the
+// headers of files, sorted by path.
+func fileLicenses(files []string) []FileLicense {
+	var fls []FileLicense
+	for _, f := range files {
+		for _, expr := range spdxTags(f) {
+			fls = append(fls, FileLicense{Path: f, License: expr})
+		}
+	}
+	sort.SliceStable(fls, func(i, j int) bool {
+		return fls[i].Path < fls[j].Path
+	})
+	return fls
+}
+
+// ExpressionCoveredBy reports whether every license referenced by the SPDX expression
+// expr is also referenced by the expression of, e.g. whether the license tag of a file
+// agrees with the license of its library. Identifiers are compared case-insensitively,
+// and the deprecated identifiers of GNU licenses that the classifier returns, e.g.
+// GPL-2.0, match their current forms, e.g. GPL-2.0-only.
+func ExpressionCoveredBy(expr, of string) bool {
+	known := make(map[string]bool)
+	for _, l := range ExpressionLicenses(of) {
+		known[normalizedLicenseName(l)] = true
+	}
+	for _, l := range ExpressionLicenses(expr) {
+		if !known[normalizedLicenseName(l)] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizedLicenseName returns name in lower case without the suffixes of GNU license
+// identifiers, which the classifier can't tell apart.
+func normalizedLicenseName(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.Index(name, " with "); i >= 0 {
+		return normalizedLicenseName(name[:i]) + name[i:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "+"), "-only"), "-or-later")
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileLicenses(t *testing.T) {
+	files := []string{
+		"testdata/reuse/reuse.go",
+		"testdata/reuse/gen/gen.go",
+		"testdata/reuse/notags/notags.go",
+	}
+	want := []FileLicense{
+		{Path: "testdata/reuse/gen/gen.go", License: "GPL-3.0-only"},
+		{Path: "testdata/reuse/reuse.go", License: "MIT OR Apache-2.0"},
+	}
+	if diff := cmp.Diff(want, fileLicenses(files)); diff != "" {
+		t.Errorf("fileLicenses(): (-want +got):\n%s", diff)
+	}
+	opts := Options{DeepScanSkipGenerated: true}
+	want = want[1:]
+	if diff := cmp.Diff(want, fileLicenses(deepScanFiles("testdata/reuse", files, opts))); diff != "" {
+		t.Errorf("fileLicenses() of files without generated ones: (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpressionCoveredBy(t *testing.T) {
+	for _, test := range []struct {
+		expr, of string
+		want     bool
+	}{
+		{expr: "MIT", of: "MIT", want: true},
+		{expr: "mit", of: "MIT", want: true},
+		{expr: "GPL-2.0-only", of: "GPL-2.0", want: true},
+		{expr: "GPL-2.0-or-later", of: "GPL-2.0", want: true},
+		{expr: "MIT", of: "Apache-2.0 OR MIT", want: true},
+		{expr: "MIT OR Apache-2.0", of: "MIT", want: false},
+		{expr: "GPL-3.0-only", of: "MIT", want: false},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", of: "GPL-2.0 WITH Classpath-exception-2.0", want: true},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", of: "GPL-2.0", want: false},
+	} {
+		if got := ExpressionCoveredBy(test.expr, test.of); got != test.want {
+			t.Errorf("ExpressionCoveredBy(%q, %q) = %v, want %v", test.expr, test.of, got, test.want)
+		}
+	}
+}
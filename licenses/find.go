@@ -20,31 +20,50 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 )
 
 var (
 	licenseRegexp = regexp.MustCompile(`^(?i)((UN)?LICEN(S|C)E|COPYING|README|NOTICE).*$`)
+	// localizedLicenseRegexp also matches the localized license file names used by some
+	// modules, e.g. LIZENZ, LICENCIA or ライセンス.
+	localizedLicenseRegexp = regexp.MustCompile(`^(?i)((UN)?LICEN(S|C)E|COPYING|README|NOTICE|LIZENZ|LICENCIA|LICENÇA|LICENZA|LICENTIE|LICENS|LISENS|ЛИЦЕНЗИЯ|ライセンス|许可证|許可證|라이선스).*$`)
+	// licenseTextRegexp matches the names of files that hold nothing but a license text,
+	// unlike e.g. READMEs, which only sometimes do.
+	licenseTextRegexp = regexp.MustCompile(`^(?i)((UN)?LICEN(S|C)E|COPYING)([-_.][\w.-]*)?$`)
 )
 
+// licenseFileRegexp returns the regexp that the names of license files match.
+func licenseFileRegexp(localized bool) *regexp.Regexp {
+	if localized {
+		return localizedLicenseRegexp
+	}
+	return licenseRegexp
+}
+
 // Find returns the file path of the license for this package.
 //
 // dir is path of the directory where we want to find a license.
 // rootDir is path of the module containing this package. Find will not search out of the
 // rootDir.
 func Find(dir string, rootDir string, classifier Classifier) (string, error) {
-	dir, err := filepath.Abs(dir)
+	return find(dir, rootDir, classifier, licenseRegexp, false)
+}
+
+// find is Find for license files whose names match names, with the option to ignore
+// symlinked files and directories while searching.
+func find(dir string, rootDir string, classifier Classifier, names *regexp.Regexp, skipSymlinks bool) (string, error) {
+	dir, err := absResolved(dir)
 	if err != nil {
 		return "", err
 	}
-	rootDir, err = filepath.Abs(rootDir)
+	rootDir, err = absResolved(rootDir)
 	if err != nil {
 		return "", err
 	}
-	if !strings.HasPrefix(dir, rootDir) {
+	if !isWithinDir(rootDir, dir) {
 		return "", fmt.Errorf("licenses.Find: rootDir %s should contain dir %s", rootDir, dir)
 	}
-	found, err := findUpwards(dir, licenseRegexp, rootDir, func(path string) bool {
+	found, err := findUpwards(dir, names, rootDir, skipSymlinks, func(path string) bool {
 		// TODO(RJPercival): Return license details
 		if _, _, err := classifier.Identify(path); err != nil {
 			return false
@@ -53,16 +72,112 @@ func Find(dir string, rootDir string, classifier Classifier) (string, error) {
 	})
 	if err != nil {
 		if errors.Is(err, errNotFound) {
-			return "", fmt.Errorf("cannot find a known open source license for %q whose name matches regexp %s and locates up until %q", dir, licenseRegexp, rootDir)
+			return "", fmt.Errorf("cannot find a known open source license for %q whose name matches regexp %s and locates up until %q", dir, names, rootDir)
 		}
 		return "", fmt.Errorf("finding a known open source license: %w", err)
 	}
 	return found, nil
 }
 
+// unidentifiedLicense returns a license file in dir, or in a parent of dir below the
+// directory of licensePath, that the classifier can't identify, if any. Find skips such
+// files, so that dir is attributed the license of licensePath although a nearer license
+// file, e.g. a modified GPL in a sub-directory of a BSD-licensed module, applies to it.
+func unidentifiedLicense(dir string, licensePath string, classifier Classifier, skipSymlinks bool) string {
+	dir, err := absResolved(dir)
+	if err != nil {
+		return ""
+	}
+	licenseDir := filepath.Dir(licensePath)
+	for ; dir != licenseDir && isWithinDir(licenseDir, dir); dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return ""
+		}
+		for _, e := range entries {
+			if e.IsDir() || (skipSymlinks && e.Type()&os.ModeSymlink != 0) || !licenseTextRegexp.MatchString(e.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if _, _, err := classifier.Identify(path); err != nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// LicenseCandidate is a file that was considered as the license file of a package, but
+// could not be identified as a known license.
+type LicenseCandidate struct {
+	// Path of the file.
+	Path string `json:"path"`
+	// BestMatch is the known license the file is most similar to, if any.
+	BestMatch string `json:"bestMatch,omitempty"`
+	// Confidence of BestMatch, which is below the classifier's confidence threshold.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+func (c LicenseCandidate) String() string {
+	if c.BestMatch == "" {
+		return fmt.Sprintf("%s (no similar license)", c.Path)
+	}
+	return fmt.Sprintf("%s (best match %s, confidence %.2f)", c.Path, c.BestMatch, c.Confidence)
+}
+
+// findCandidates returns the files that Find considers when searching for the license of
+// dir, i.e. all files matching its regexp up until rootDir, together with the license each
+// of them is most similar to. It helps to tell whether a license was not found because of
+// its file name, because of the confidence threshold, or because there is none.
+func findCandidates(dir string, rootDir string, classifier Classifier, names *regexp.Regexp, skipSymlinks bool) []LicenseCandidate {
+	dir, err := absResolved(dir)
+	if err != nil {
+		return nil
+	}
+	rootDir, err = absResolved(rootDir)
+	if err != nil {
+		return nil
+	}
+	var candidates []LicenseCandidate
+	_, _ = findUpwards(dir, names, rootDir, skipSymlinks, func(path string) bool {
+		if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+			return false
+		}
+		c := LicenseCandidate{Path: path}
+		if nm, ok := classifier.(nearestMatcher); ok {
+			if name, confidence, err := nm.NearestMatch(path); err == nil {
+				c.BestMatch, c.Confidence = name, confidence
+			}
+		}
+		candidates = append(candidates, c)
+		// Keep searching to collect all candidates.
+		return false
+	})
+	return candidates
+}
+
 var errNotFound = fmt.Errorf("file/directory matching predicate and regexp not found")
 
-func findUpwards(dir string, r *regexp.Regexp, stopAt string, predicate func(path string) bool) (string, error) {
+// absResolved returns the absolute path of path with all symlinks resolved, so that paths
+// reached through symlinked directories, e.g. a symlinked GOMODCACHE, compare consistently.
+func absResolved(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return resolveSymlinks(path), nil
+}
+
+// resolveSymlinks returns path with all symlinks resolved, or path itself if it cannot be
+// resolved, e.g. because it does not exist.
+func resolveSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+func findUpwards(dir string, r *regexp.Regexp, stopAt string, skipSymlinks bool, predicate func(path string) bool) (string, error) {
 	// Dir must be made absolute for reliable matching with stopAt regexps
 	dir, err := filepath.Abs(dir)
 	if err != nil {
@@ -70,12 +185,15 @@ func findUpwards(dir string, r *regexp.Regexp, stopAt string, predicate func(pat
 	}
 	start := dir
 	// Stop once we go out of the stopAt dir.
-	for strings.HasPrefix(dir, stopAt) {
+	for isWithinDir(stopAt, dir) {
 		dirContents, err := os.ReadDir(dir)
 		if err != nil {
 			return "", err
 		}
 		for _, f := range dirContents {
+			if skipSymlinks && f.Type()&os.ModeSymlink != 0 {
+				continue
+			}
 			if r.MatchString(f.Name()) {
 				path := filepath.Join(dir, f.Name())
 				if predicate != nil && !predicate(path) {
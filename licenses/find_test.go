@@ -17,6 +17,7 @@ package licenses
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"testing"
 )
@@ -133,3 +134,240 @@ func TestFind(t *testing.T) {
 		})
 	}
 }
+
+// readableClassifier identifies every readable file as a notice license.
+type readableClassifier struct{}
+
+func (readableClassifier) Identify(licensePath string) (string, Type, error) {
+	if _, err := os.ReadFile(licensePath); err != nil {
+		return "", Unknown, err
+	}
+	return "foo", Notice, nil
+}
+
+func TestFindSymlinks(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Cannot get working directory: %v", err)
+	}
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tmp/module/LICENSE is a symlink to a license file outside of the module.
+	moduleDir := filepath.Join(tmp, "module")
+	if err := os.MkdirAll(filepath.Join(moduleDir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(wd, "testdata/LICENSE"), filepath.Join(moduleDir, "LICENSE")); err != nil {
+		// Creating symlinks requires extra privileges on Windows.
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	// tmp/direct is a symlink to a package directory.
+	linkedPkgDir := filepath.Join(tmp, "direct")
+	if err := os.Symlink(filepath.Join(wd, "testdata/direct"), linkedPkgDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc            string
+		dir             string
+		rootDir         string
+		classifier      Classifier
+		skipSymlinks    bool
+		wantLicensePath string
+		wantErr         bool
+	}{
+		{
+			desc:            "follows symlinked license file",
+			dir:             filepath.Join(moduleDir, "pkg"),
+			rootDir:         moduleDir,
+			classifier:      readableClassifier{},
+			wantLicensePath: filepath.Join(moduleDir, "LICENSE"),
+		},
+		{
+			desc:         "skips symlinked license file",
+			dir:          filepath.Join(moduleDir, "pkg"),
+			rootDir:      moduleDir,
+			classifier:   readableClassifier{},
+			skipSymlinks: true,
+			wantErr:      true,
+		},
+		{
+			desc:    "resolves symlinked package dir",
+			dir:     linkedPkgDir,
+			rootDir: "testdata",
+			classifier: classifierStub{
+				licenseNames: map[string]string{"testdata/direct/LICENSE": "foo"},
+				licenseTypes: map[string]Type{"testdata/direct/LICENSE": Notice},
+			},
+			wantLicensePath: filepath.Join(wd, "testdata/direct/LICENSE"),
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			licensePath, err := find(test.dir, test.rootDir, test.classifier, licenseRegexp, test.skipSymlinks)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("find(%q, %q) = (%q, %v), want err? %t", test.dir, test.rootDir, licensePath, err, test.wantErr)
+			}
+			if licensePath != test.wantLicensePath {
+				t.Fatalf("find(%q, %q) = %q, want %q", test.dir, test.rootDir, licensePath, test.wantLicensePath)
+			}
+		})
+	}
+}
+
+func TestFindLocalizedNames(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Cannot get working directory: %v", err)
+	}
+	for _, test := range []struct {
+		desc            string
+		dir             string
+		localized       bool
+		wantLicensePath string
+		wantErr         bool
+	}{
+		{
+			desc:            "LIZENZ",
+			dir:             "testdata/localized",
+			localized:       true,
+			wantLicensePath: filepath.Join(wd, "testdata/localized/LIZENZ"),
+		},
+		{
+			desc:            "ライセンス",
+			dir:             "testdata/localized-ja",
+			localized:       true,
+			wantLicensePath: filepath.Join(wd, "testdata/localized-ja/ライセンス"),
+		},
+		{
+			desc:    "LIZENZ not localized",
+			dir:     "testdata/localized",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			licensePath, err := find(test.dir, test.dir, readableClassifier{}, licenseFileRegexp(test.localized), false)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("find(%q) = (%q, %v), want err? %t", test.dir, licensePath, err, test.wantErr)
+			}
+			if licensePath != test.wantLicensePath {
+				t.Fatalf("find(%q) = %q, want %q", test.dir, licensePath, test.wantLicensePath)
+			}
+		})
+	}
+}
+
+func TestFindCandidates(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier, err := NewClassifier(0.98)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.98) = (_, %q), want (_, nil)", err)
+	}
+	for _, test := range []struct {
+		desc          string
+		dir           string
+		classifier    Classifier
+		wantPaths     []string
+		wantBestMatch string
+	}{
+		{
+			desc:          "near miss below threshold",
+			dir:           "testdata/modified-mit",
+			classifier:    classifier,
+			wantPaths:     []string{filepath.Join(wd, "testdata/modified-mit/LICENSE")},
+			wantBestMatch: "MIT",
+		},
+		{
+			desc:       "classifier without near misses",
+			dir:        "testdata/proprietary-license",
+			classifier: classifierStub{},
+			wantPaths:  []string{filepath.Join(wd, "testdata/proprietary-license/LICENSE")},
+		},
+		{
+			desc:       "no license files",
+			dir:        "testdata/internal",
+			classifier: classifier,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			candidates := findCandidates(test.dir, test.dir, test.classifier, licenseRegexp, false)
+			var paths []string
+			for _, c := range candidates {
+				paths = append(paths, c.Path)
+			}
+			if !reflect.DeepEqual(paths, test.wantPaths) {
+				t.Fatalf("findCandidates(%q) paths = %q, want %q", test.dir, paths, test.wantPaths)
+			}
+			if test.wantBestMatch == "" {
+				return
+			}
+			if got := candidates[0]; got.BestMatch != test.wantBestMatch || got.Confidence <= 0 || got.Confidence >= 0.98 {
+				t.Errorf("findCandidates(%q)[0] = %+v, want best match %q below threshold", test.dir, got, test.wantBestMatch)
+			}
+		})
+	}
+}
+
+func TestUnidentifiedLicense(t *testing.T) {
+	for _, test := range []struct {
+		dir         string
+		licensePath string
+		want        string
+	}{
+		{dir: "testdata/subdirectory/custom/pkg", licensePath: "testdata/subdirectory/LICENSE", want: "testdata/subdirectory/custom/LICENSE"},
+		{dir: "testdata/subdirectory/gpl/inner", licensePath: "testdata/subdirectory/gpl/inner/LICENSE"},
+		{dir: "testdata/subdirectory/gpl/inner", licensePath: "testdata/subdirectory/LICENSE"},
+	} {
+		t.Run(test.dir, func(t *testing.T) {
+			licensePath, err := absResolved(test.licensePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := unidentifiedLicense(test.dir, licensePath, subdirectoryClassifier, false)
+			if test.want == "" {
+				if got != "" {
+					t.Errorf("unidentifiedLicense(%q, %q) = %q, want \"\"", test.dir, test.licensePath, got)
+				}
+				return
+			}
+			if got == "" || !sameFile(t, got, test.want) {
+				t.Errorf("unidentifiedLicense(%q, %q) = %q, want %q", test.dir, test.licensePath, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommentLicense(t *testing.T) {
+	classifier, err := NewClassifier(0.9)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.9) = (_, %q), want (_, nil)", err)
+	}
+	for _, test := range []struct {
+		desc    string
+		goFiles []string
+		want    string
+	}{
+		{
+			desc:    "license in doc.go",
+			goFiles: []string{"testdata/doccomment/other.go", "testdata/doccomment/doc.go"},
+			want:    "testdata/doccomment/doc.go",
+		},
+		{
+			desc:    "copyright line only",
+			goFiles: []string{"testdata/doccomment/other.go"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := commentLicense(test.goFiles, classifier); got != test.want {
+				t.Errorf("commentLicense(%q) = %q, want %q", test.goFiles, got, test.want)
+			}
+		})
+	}
+	if name, _, err := classifier.Identify("testdata/doccomment/doc.go"); err != nil || name != "MIT" {
+		t.Errorf(`Identify("testdata/doccomment/doc.go") = (%q, _, %v), want ("MIT", _, nil)`, name, err)
+	}
+}
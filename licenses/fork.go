@@ -0,0 +1,45 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// UpstreamLicensePath returns the path of the license file that applies to the library's
+// packages in the module replaced by its module, e.g. the original of a fork, so that
+// both licenses can be compared. The replaced module is downloaded to the module cache
+// if necessary. It returns an error if the library's module doesn't replace another one.
+func (l *Library) UpstreamLicensePath(ctx context.Context, classifier Classifier) (string, error) {
+	m := l.module
+	if m == nil || m.Replaces == nil {
+		return "", fmt.Errorf("library %s does not replace another module", l.Name())
+	}
+	upstream := m.Replaces
+	dir, err := downloadModule(ctx, upstream.Path, upstream.Version)
+	if err != nil {
+		return "", err
+	}
+	// Import paths of a replaced module don't change, so they tell the package directory
+	// in the upstream module as well.
+	pkgDir := dir
+	if sub := strings.TrimPrefix(l.Name(), upstream.Path); sub != l.Name() {
+		pkgDir = filepath.Join(dir, filepath.FromSlash(sub))
+	}
+	return Find(pkgDir, dir, classifier)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestModuleIsFork(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		module Module
+		want   bool
+	}{
+		{
+			desc:   "Not replaced",
+			module: Module{Path: "github.com/foo/bar", Version: "v1.0.0"},
+			want:   false,
+		},
+		{
+			desc:   "Replaced by another version",
+			module: Module{Path: "github.com/foo/bar", Version: "v1.0.0", Replaces: &Module{Path: "github.com/foo/bar", Version: "v1.1.0"}},
+			want:   false,
+		},
+		{
+			desc:   "Replaced by a fork",
+			module: Module{Path: "github.com/acme/bar", Version: "v1.0.1", Replaces: &Module{Path: "github.com/foo/bar", Version: "v1.0.0"}},
+			want:   true,
+		},
+		{
+			desc:   "Replaced by a directory",
+			module: Module{Path: "github.com/foo/bar", Replaces: &Module{Path: "github.com/foo/bar", Version: "v1.0.0"}},
+			want:   true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.module.IsFork(); got != test.want {
+				t.Errorf("IsFork() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -49,7 +49,7 @@ func FindGitRepo(filePath string) (*GitRepo, error) {
 	// TODO(Bobgy): the "/" is used just to fix the test. git.go is not
 	// currently used, but I plan to bring it back to detect version of the
 	// main module in following up PRs.
-	path, err := findUpwards(filepath.Dir(filePath), gitRegexp, "/", nil)
+	path, err := findUpwards(filepath.Dir(filePath), gitRegexp, "/", false, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,107 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// GoCacheMode selects how packages whose source files are all in the build cache
+// (GOCACHE) are treated, e.g. packages whose only Go files are generated by cgo. Test
+// binaries, whose generated main package is in the build cache too, are always left out
+// since they only import the standard library and the packages under test.
+type GoCacheMode string
+
+const (
+	// GoCacheResolve, the default, attributes packages in the build cache to the
+	// directory of their import path in their module, and leaves them out if there is
+	// none.
+	GoCacheResolve = GoCacheMode("resolve")
+	// GoCacheSkip leaves packages in the build cache out, but still checks their
+	// dependencies.
+	GoCacheSkip = GoCacheMode("skip")
+	// GoCacheError fails for packages in the build cache, e.g. for hermetic builds that
+	// must not attribute licenses by guessing.
+	GoCacheError = GoCacheMode("error")
+)
+
+// ParseGoCacheMode returns the GoCacheMode named s, which is GoCacheResolve if s is empty.
+func ParseGoCacheMode(s string) (GoCacheMode, error) {
+	switch m := GoCacheMode(s); m {
+	case "":
+		return GoCacheResolve, nil
+	case GoCacheResolve, GoCacheSkip, GoCacheError:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown build cache mode %q, want one of: %s, %s, %s", s, GoCacheResolve, GoCacheSkip, GoCacheError)
+}
+
+// goCacheDir finds the build cache directory once, as the go command that loads the
+// packages sees it, so that custom GOCACHE locations of hermetic builders are respected.
+type goCacheDir struct {
+	cfg  *packages.Config
+	dir  string
+	done bool
+}
+
+// contains reports whether path is in the build cache. It is false for all paths if the
+// build cache is off or its location can't be determined.
+func (c *goCacheDir) contains(path string) bool {
+	if !c.done {
+		c.done = true
+		if dir, err := goEnv(c.cfg, "GOCACHE"); err == nil && dir != "" && dir != "off" {
+			c.dir = resolveSymlinks(dir)
+		}
+	}
+	return c.dir != "" && isWithinDir(c.dir, resolveSymlinks(path))
+}
+
+// packageDir returns the directory of the source files of p, skipping files in the build
+// cache, e.g. Go files generated by cgo. inCache is set if p has files, but all of them
+// are in the build cache. Files in the directory of p's module are never looked up in the
+// build cache, so that it is only located for packages that need it.
+func packageDir(p *packages.Package, cache *goCacheDir) (dir string, inCache bool) {
+	var files []string
+	files = append(files, p.GoFiles...)
+	files = append(files, p.CompiledGoFiles...)
+	files = append(files, p.OtherFiles...)
+	for _, f := range files {
+		if p.Module != nil && p.Module.Dir != "" && isWithinDir(p.Module.Dir, f) {
+			return filepath.Dir(f), false
+		}
+		if !cache.contains(f) {
+			return filepath.Dir(f), false
+		}
+	}
+	return "", len(files) > 0
+}
+
+// importPathDir returns the directory of p in its module as derived from its import
+// path, or "" if it doesn't exist, e.g. because p is not in a module.
+func importPathDir(p *packages.Package) string {
+	if p.Module == nil || p.Module.Dir == "" || !strings.HasPrefix(p.PkgPath+"/", p.Module.Path+"/") {
+		return ""
+	}
+	dir := filepath.Join(p.Module.Dir, filepath.FromSlash(strings.TrimPrefix(p.PkgPath, p.Module.Path)))
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return ""
+	}
+	return dir
+}
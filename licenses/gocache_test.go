@@ -0,0 +1,110 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestIsTestBinary(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		pkg  *packages.Package
+		want bool
+	}{
+		{
+			desc: "test binary",
+			pkg:  &packages.Package{ID: "example.com/foo.test", PkgPath: "example.com/foo.test", Name: "main"},
+			want: true,
+		},
+		{
+			desc: "package compiled for a test binary",
+			pkg:  &packages.Package{ID: "example.com/foo [example.com/foo.test]", PkgPath: "example.com/foo", Name: "foo"},
+		},
+		{
+			desc: "library whose path ends in .test",
+			pkg:  &packages.Package{ID: "example.com/fixtures.test", PkgPath: "example.com/fixtures.test", Name: "fixtures"},
+		},
+	} {
+		if got := isTestBinary(test.pkg); got != test.want {
+			t.Errorf("%s: isTestBinary() = %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	cacheDir := t.TempDir()
+	moduleDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(moduleDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cache := &goCacheDir{dir: resolveSymlinks(cacheDir), done: true}
+	module := &packages.Module{Path: "example.com/mod", Dir: moduleDir}
+	cached := filepath.Join(cacheDir, "ab", "abcdef-d")
+	for _, test := range []struct {
+		desc        string
+		pkg         *packages.Package
+		wantDir     string
+		wantInCache bool
+	}{
+		{
+			desc:    "Go files in the module",
+			pkg:     &packages.Package{PkgPath: "example.com/mod/sub", GoFiles: []string{filepath.Join(moduleDir, "sub", "a.go")}, Module: module},
+			wantDir: filepath.Join(moduleDir, "sub"),
+		},
+		{
+			desc:    "Go files generated by cgo in the build cache",
+			pkg:     &packages.Package{PkgPath: "example.com/mod/sub", CompiledGoFiles: []string{cached}, OtherFiles: []string{filepath.Join(moduleDir, "sub", "a.c")}, Module: module},
+			wantDir: filepath.Join(moduleDir, "sub"),
+		},
+		{
+			desc:        "only files in the build cache",
+			pkg:         &packages.Package{PkgPath: "example.com/mod/sub", GoFiles: []string{cached}, Module: module},
+			wantInCache: true,
+		},
+		{
+			desc: "empty package",
+			pkg:  &packages.Package{PkgPath: "example.com/mod/sub", Module: module},
+		},
+	} {
+		dir, inCache := packageDir(test.pkg, cache)
+		if dir != test.wantDir || inCache != test.wantInCache {
+			t.Errorf("%s: packageDir() = (%q, %v), want (%q, %v)", test.desc, dir, inCache, test.wantDir, test.wantInCache)
+		}
+	}
+	pkg := &packages.Package{PkgPath: "example.com/mod/sub", Module: module}
+	if got, want := importPathDir(pkg), filepath.Join(moduleDir, "sub"); got != want {
+		t.Errorf("importPathDir() = %q, want %q", got, want)
+	}
+	pkg.PkgPath = "example.com/mod/missing"
+	if got := importPathDir(pkg); got != "" {
+		t.Errorf("importPathDir() of a package without directory = %q, want \"\"", got)
+	}
+}
+
+func TestParseGoCacheMode(t *testing.T) {
+	for s, want := range map[string]GoCacheMode{"": GoCacheResolve, "resolve": GoCacheResolve, "skip": GoCacheSkip, "error": GoCacheError} {
+		if got, err := ParseGoCacheMode(s); err != nil || got != want {
+			t.Errorf("ParseGoCacheMode(%q) = (%q, %v), want (%q, nil)", s, got, err, want)
+		}
+	}
+	if _, err := ParseGoCacheMode("ignore"); err == nil {
+		t.Errorf("ParseGoCacheMode(%q) = (_, nil), want an error", "ignore")
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "context"
+
+type goEnvironKey struct{}
+
+// WithGoEnviron returns a context that makes the functions of this package called with
+// it run the go command in the environment env, e.g. with GOPROXY=off to keep it from
+// downloading modules, rather than in the environment of the current process.
+func WithGoEnviron(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, goEnvironKey{}, env)
+}
+
+// goEnviron returns the environment of the go command set with WithGoEnviron, or nil for
+// the environment of the current process.
+func goEnviron(ctx context.Context) []string {
+	env, _ := ctx.Value(goEnvironKey{}).([]string)
+	return env
+}
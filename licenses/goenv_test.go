@@ -0,0 +1,37 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestWithGoEnviron(t *testing.T) {
+	ctx := WithGoEnviron(context.Background(), append(os.Environ(), "GOPROXY=off"))
+	got, err := goEnv(&packages.Config{Context: ctx, Env: goEnviron(ctx)}, "GOPROXY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "off" {
+		t.Errorf("go env GOPROXY = %q, want %q", got, "off")
+	}
+	if env := goEnviron(context.Background()); env != nil {
+		t.Errorf("goEnviron(context.Background()) = %v, want nil", env)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+	"k8s.io/klog/v2"
+)
+
+// GoSumLibraries returns a library per module listed in the go.sum file of the main
+// module in dir, and one for the main module itself, without loading any packages.
+// Licenses are searched in the module roots of the copies in the module cache, which
+// is never downloaded to. Modules missing from the module cache are returned without
+// license.
+//
+// This is much faster than LibrariesWithOptions, but less accurate: go.sum may list
+// modules that no package imports, and only sub-directories whose license file names
+// another license than the one above them are split off into libraries of their own.
+// Libraries are direct if the go.mod file requires their module without an // indirect
+// comment. Of opts, only IgnoreRules, which match module paths, SkipSymlinks,
+// LocalizedLicenseNames, TraceURLs, SourceResolver and Cache apply.
+func GoSumLibraries(ctx context.Context, classifier Classifier, opts Options, dir string) ([]*Library, error) {
+	modules, err := GoSumModules(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return moduleLibraries(classifier, opts, modules)
+}
+
+// GoSumModules returns the main module in dir and the modules whose content is listed
+// in its go.sum file, see ParseGoSum. Dir is set to the module's directory in the module
+// cache, or left empty if the module has not been downloaded.
+func GoSumModules(ctx context.Context, dir string) ([]*Module, error) {
+	cfg := &packages.Config{Context: ctx, Dir: dir, Env: goEnviron(ctx)}
+	goMod, err := goEnv(cfg, "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	if goMod == "" || goMod == os.DevNull {
+		return nil, fmt.Errorf("%s is not in a module", dir)
+	}
+	modCache, err := goEnv(cfg, "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(goMod)
+	if err != nil {
+		return nil, err
+	}
+	mainPath := modfile.ModulePath(b)
+	if mainPath == "" {
+		return nil, fmt.Errorf("%s has no module directive", goMod)
+	}
+	sumPath := strings.TrimSuffix(goMod, ".mod") + ".sum"
+	sum, err := os.ReadFile(sumPath)
+	if err != nil && !os.IsNotExist(err) {
+		// Modules without dependencies have no go.sum file.
+		return nil, err
+	}
+	deps, err := ParseGoSum(sum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sumPath, err)
+	}
+
+	modules := []*Module{{Path: mainPath, Dir: filepath.Dir(goMod), Main: true}}
+	for _, m := range deps {
+		escPath, err := module.EscapePath(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		escVersion, err := module.EscapeVersion(m.Version)
+		if err != nil {
+			return nil, err
+		}
+		m.Dir = filepath.Join(modCache, escPath+"@"+escVersion)
+		if _, err := os.Stat(m.Dir); err != nil {
+			warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s@%s is not in the module cache, run \"go mod download\" to find its license", m.Path, m.Version)
+			m.Dir = ""
+		}
+		// The +incompatible suffix is part of the directory, but not of the module version.
+		m.Version = strings.TrimSuffix(m.Version, "+incompatible")
+		modules = append(modules, m)
+	}
+
+	policy, err := loadChecksumPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range modules {
+		policy.apply(m)
+	}
+	return modules, nil
+}
+
+// ModuleLibrary returns the library of all packages of module m, licensed by the license
+// file in the root of m.Dir. Sub-directories with licenses of their own are not split off,
+// unlike in GoSumLibraries and ModuleListLibraries. The library has no license if m.Dir is empty. Of opts, only
+// SkipSymlinks, LocalizedLicenseNames, TraceURLs, SourceResolver and Cache apply.
+func ModuleLibrary(classifier Classifier, opts Options, m *Module) *Library {
+	lib := &Library{
+		Packages:  []string{m.Path},
+		module:    m,
+		traceURLs: opts.TraceURLs,
+		resolver:  opts.SourceResolver,
+		cache:     opts.Cache,
+	}
+	if m.Dir == "" {
+		return lib
+	}
+	licensePath, err := find(m.Dir, m.Dir, classifier, licenseFileRegexp(opts.LocalizedLicenseNames), opts.SkipSymlinks)
+	if err != nil {
+		klog.Errorf("Failed to find license for module %s: %v", m.Path, err)
+		return lib
+	}
+	lib.LicensePath = licensePath
+	lib.NoticePath = findNotice(licensePath)
+	lib.LicenseFiles = findLicenseFiles(licensePath)
+	lib.LicenseChoices = licenseChoices(classifier, licensePath)
+	return lib
+}
+
+// subdirectoryLibraries returns a library for each sub-directory of m.Dir whose license
+// file identifies as another license than the nearest license file above it, e.g. for the
+// GPL-licensed directory of an otherwise BSD-licensed module. root is the library of m,
+// see ModuleLibrary. Like packages, directories named testdata or vendor, those starting
+// with . or _ and those of nested modules are left out.
+func subdirectoryLibraries(classifier Classifier, opts Options, m *Module, root *Library) []*Library {
+	if m.Dir == "" {
+		return nil
+	}
+	moduleDir, err := absResolved(m.Dir)
+	if err != nil {
+		klog.Errorf("Failed to find the licenses of the sub-directories of module %s: %v", m.Path, err)
+		return nil
+	}
+	// licenseNames are the names of the license found in each directory, by path relative
+	// to m.Dir.
+	licenseNames := make(map[string]string)
+	if root.LicensePath != "" {
+		if name, _, err := classifier.Identify(root.LicensePath); err == nil {
+			licenseNames["."] = name
+		}
+	}
+	var libraries []*Library
+	err = filepath.WalkDir(moduleDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == moduleDir {
+			return nil
+		}
+		if name := d.Name(); name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			return filepath.SkipDir
+		}
+		licensePath, name := identifiedLicenseIn(path, classifier, opts.SkipSymlinks)
+		if licensePath == "" {
+			return nil
+		}
+		rel, err := filepath.Rel(moduleDir, path)
+		if err != nil {
+			return err
+		}
+		licenseNames[rel] = name
+		if name == nearestLicenseName(licenseNames, filepath.Dir(rel)) {
+			return nil
+		}
+		libraries = append(libraries, &Library{
+			Packages:       []string{m.Path + "/" + filepath.ToSlash(rel)},
+			LicensePath:    licensePath,
+			NoticePath:     findNotice(licensePath),
+			LicenseFiles:   findLicenseFiles(licensePath),
+			LicenseChoices: licenseChoices(classifier, licensePath),
+			module:         m,
+			traceURLs:      opts.TraceURLs,
+			resolver:       opts.SourceResolver,
+			cache:          opts.Cache,
+		})
+		return nil
+	})
+	if err != nil {
+		klog.Errorf("Failed to find the licenses of the sub-directories of module %s: %v", m.Path, err)
+	}
+	return libraries
+}
+
+// identifiedLicenseIn returns the first license file directly in dir that the classifier
+// identifies, together with its license names, or "" if there is none.
+func identifiedLicenseIn(dir string, classifier Classifier, skipSymlinks bool) (path string, name string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", ""
+	}
+	for _, e := range entries {
+		if e.IsDir() || (skipSymlinks && e.Type()&os.ModeSymlink != 0) || !licenseTextRegexp.MatchString(e.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if name, _, err := classifier.Identify(path); err == nil {
+			return path, name
+		}
+	}
+	return "", ""
+}
+
+// nearestLicenseName returns the license names of dir or its nearest parent in
+// licenseNames, where dir is relative to the module root.
+func nearestLicenseName(licenseNames map[string]string, dir string) string {
+	for {
+		if name, ok := licenseNames[dir]; ok {
+			return name
+		}
+		if dir == "." {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// ignoredModule reports whether an ignore rule matches modulePath.
+func ignoredModule(modulePath string, rules []IgnoreRule) bool {
+	for _, rule := range rules {
+		if strings.HasPrefix(modulePath, rule.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGoSum returns the modules whose content is listed in the go.sum file data, at
+// the highest version listed. Modules of which only the go.mod file is listed are left
+// out, because none of their packages are built.
+func ParseGoSum(data []byte) ([]*Module, error) {
+	versions := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: malformed go.sum line", i+1)
+		}
+		modPath, version := fields[0], fields[1]
+		if strings.HasSuffix(version, "/go.mod") {
+			continue
+		}
+		if v, ok := versions[modPath]; !ok || semver.Compare(version, v) > 0 {
+			versions[modPath] = version
+		}
+	}
+	var modules []*Module
+	for modPath, version := range versions {
+		modules = append(modules, &Module{Path: modPath, Version: version})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGoSum(t *testing.T) {
+	sum := `github.com/foo/bar v1.0.0 h1:aaa=
+github.com/foo/bar v1.0.0/go.mod h1:bbb=
+github.com/foo/bar v1.2.0 h1:ccc=
+github.com/foo/bar v1.2.0/go.mod h1:ddd=
+github.com/foo/modonly v0.1.0/go.mod h1:eee=
+golang.org/x/text v0.3.5 h1:fff=
+`
+	got, err := ParseGoSum([]byte(sum))
+	if err != nil {
+		t.Fatalf("ParseGoSum() = (_, %q), want (_, nil)", err)
+	}
+	want := []*Module{
+		{Path: "github.com/foo/bar", Version: "v1.2.0"},
+		{Path: "golang.org/x/text", Version: "v0.3.5"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseGoSum(): (-want +got):\n%s", diff)
+	}
+}
+
+func TestGoSumLibraries(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"../testdata/modules/cli02/LICENSE": "Apache-2.0",
+		},
+		licenseTypes: map[string]Type{
+			"../testdata/modules/cli02/LICENSE": Notice,
+		},
+	}
+	opts := Options{IgnoreRules: []IgnoreRule{{Prefix: "golang.org/x/", Mode: IgnoreHide}}}
+	libs, err := GoSumLibraries(context.Background(), classifier, opts, "../testdata/modules/cli02")
+	if err != nil {
+		t.Fatalf("GoSumLibraries() = (_, %q), want (_, nil)", err)
+	}
+	versions := make(map[string]string)
+	for _, lib := range libs {
+		versions[lib.Name()] = lib.Version()
+		if lib.Name() == "github.com/nilsbeck/go-licenses/testdata/modules/cli02" {
+			if want := "../testdata/modules/cli02/LICENSE"; !sameFile(t, lib.LicensePath, want) {
+				t.Errorf("main module LicensePath = %q, want %q", lib.LicensePath, want)
+			}
+		}
+	}
+	for name, want := range map[string]string{
+		"github.com/nilsbeck/go-licenses/testdata/modules/cli02": "",
+		"github.com/spf13/cobra":                                 "v1.1.3",
+		"github.com/mitchellh/go-homedir":                        "v1.1.0",
+	} {
+		if got, ok := versions[name]; !ok || got != want {
+			t.Errorf("library %s has version %q (found: %v), want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := versions["golang.org/x/text"]; ok {
+		t.Errorf("ignored module golang.org/x/text was returned")
+	}
+}
+
+// subdirectoryClassifier identifies the license files of testdata/subdirectory, except
+// the one of custom.
+var subdirectoryClassifier = classifierStub{
+	licenseNames: map[string]string{
+		"testdata/subdirectory/LICENSE":           "BSD-3-Clause",
+		"testdata/subdirectory/bsd/LICENSE":       "BSD-3-Clause",
+		"testdata/subdirectory/gpl/LICENSE":       "GPL-2.0",
+		"testdata/subdirectory/gpl/inner/LICENSE": "GPL-2.0",
+		"testdata/subdirectory/nested/LICENSE":    "MIT",
+		"testdata/subdirectory/testdata/LICENSE":  "MIT",
+	},
+}
+
+func TestSubdirectoryLibraries(t *testing.T) {
+	m := &Module{Path: "example.com/subdirectory", Version: "v1.0.0", Dir: "testdata/subdirectory"}
+	root := ModuleLibrary(subdirectoryClassifier, Options{}, m)
+	if want := "testdata/subdirectory/LICENSE"; !sameFile(t, root.LicensePath, want) {
+		t.Errorf("ModuleLibrary().LicensePath = %q, want %q", root.LicensePath, want)
+	}
+	libs := subdirectoryLibraries(subdirectoryClassifier, Options{}, m, root)
+	if len(libs) != 1 {
+		t.Fatalf("subdirectoryLibraries() returned %d libraries, want 1", len(libs))
+	}
+	if got, want := libs[0].Name(), "example.com/subdirectory/gpl"; got != want {
+		t.Errorf("subdirectoryLibraries()[0].Name() = %q, want %q", got, want)
+	}
+	if want := "testdata/subdirectory/gpl/LICENSE"; !sameFile(t, libs[0].LicensePath, want) {
+		t.Errorf("subdirectoryLibraries()[0].LicensePath = %q, want %q", libs[0].LicensePath, want)
+	}
+	if got, want := libs[0].Version(), "v1.0.0"; got != want {
+		t.Errorf("subdirectoryLibraries()[0].Version() = %q, want %q", got, want)
+	}
+}
+
+func sameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	ai, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(ai, bi)
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// minLanguageWords is the minimum number of words needed to guess the language of a text.
+const minLanguageWords = 20
+
+// stopwords are frequent words of the languages that LicenseLanguage tells apart by words,
+// English first so that it wins ties.
+var stopwords = []struct {
+	language string
+	words    []string
+}{
+	{"en", []string{"the", "and", "of", "to", "or", "in", "is", "for", "this", "that", "by", "with", "any", "be", "are", "without", "not", "software"}},
+	{"de", []string{"der", "die", "das", "und", "oder", "zu", "mit", "von", "nicht", "ist", "sind", "ein", "eine", "für", "auf", "dem", "den", "des"}},
+	{"fr", []string{"le", "la", "les", "et", "ou", "des", "du", "un", "une", "pour", "est", "sont", "dans", "sur", "aux", "ce", "cette", "logiciel"}},
+	{"es", []string{"el", "los", "las", "y", "o", "del", "un", "una", "para", "por", "con", "es", "son", "sin", "cualquier", "este", "esta", "software"}},
+	{"it", []string{"il", "lo", "gli", "e", "o", "di", "della", "delle", "un", "una", "per", "con", "è", "sono", "senza", "qualsiasi", "questo", "questa"}},
+	{"pt", []string{"o", "os", "as", "e", "ou", "do", "da", "dos", "um", "uma", "para", "por", "com", "é", "são", "sem", "qualquer", "este"}},
+	{"nl", []string{"de", "het", "een", "en", "of", "van", "voor", "met", "is", "zijn", "niet", "zonder", "deze", "dit", "die", "op", "aan", "bij"}},
+}
+
+// scriptLanguages maps scripts that identify a language, or a family of languages, to
+// the language code reported for them.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	// Japanese texts mix kana with Han characters, so kana are checked first.
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+}
+
+// LicenseLanguage guesses the language of the license file at licensePath and returns
+// its ISO 639-1 code, e.g. "en" or "de". Languages written in non-Latin scripts are
+// identified by script, e.g. any Cyrillic text is reported as "ru". It returns "" if
+// the text is too short to tell.
+//
+// The classifier only knows English license texts, so translated licenses show up as
+// low-confidence unknowns and should be reviewed manually.
+func LicenseLanguage(licensePath string) (string, error) {
+	content, err := os.ReadFile(licensePath)
+	if err != nil {
+		return "", err
+	}
+	return detectLanguage(string(content)), nil
+}
+
+func detectLanguage(text string) string {
+	var letters int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.script, r) {
+				scripts[sl.language]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	for _, sl := range scriptLanguages {
+		// Licenses in other scripts still contain Latin names and URLs.
+		if scripts[sl.language]*3 > letters {
+			return sl.language
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < minLanguageWords {
+		return ""
+	}
+	best, bestCount := "", -1
+	for _, sw := range stopwords {
+		set := make(map[string]bool, len(sw.words))
+		for _, w := range sw.words {
+			set[w] = true
+		}
+		count := 0
+		for _, w := range words {
+			if set[w] {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = sw.language, count
+		}
+	}
+	return best
+}
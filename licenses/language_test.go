@@ -0,0 +1,40 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestLicenseLanguage(t *testing.T) {
+	for _, test := range []struct {
+		licensePath string
+		want        string
+	}{
+		{"testdata/LICENSE", "en"},
+		{"testdata/MIT/LICENSE.MIT", "en"},
+		{"testdata/language/LICENSE.de", "de"},
+		{"testdata/language/LICENSE.zh", "zh"},
+		{"testdata/proprietary-license/LICENSE", ""},
+	} {
+		t.Run(test.licensePath, func(t *testing.T) {
+			got, err := LicenseLanguage(test.licensePath)
+			if err != nil {
+				t.Fatalf("LicenseLanguage(%q) = (_, %q), want (_, nil)", test.licensePath, err)
+			}
+			if got != test.want {
+				t.Errorf("LicenseLanguage(%q) = %q, want %q", test.licensePath, got, test.want)
+			}
+		})
+	}
+}
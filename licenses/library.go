@@ -17,11 +17,10 @@ package licenses
 import (
 	"context"
 	"fmt"
-	"go/build"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/source"
 	"golang.org/x/tools/go/packages"
@@ -35,8 +34,63 @@ type Library struct {
 	// Packages contains import paths for Go packages in this library.
 	// It may not be the complete set of all packages in the library.
 	Packages []string
+	// NoticePath is the path of the NOTICE file next to the license file, if any.
+	NoticePath string
+	// LicenseFiles are the license file followed by the files whose terms apply along
+	// with it, e.g. NOTICE, PATENTS and COPYING files next to it and the licenses of code
+	// bundled in its third_party directory.
+	LicenseFiles []string
+	// LicenseChoices are the licenses that licensees may choose among, sorted by name, if
+	// the library is dual- or multi-licensed, e.g. MIT and Apache-2.0 for a module with
+	// LICENSE-MIT and LICENSE-APACHE files whose README says "at your option". The file at
+	// LicensePath holds the text of one or all of them.
+	LicenseChoices []string
+	// ReuseLicenses are the SPDX license expressions that apply to this library's packages
+	// if its module follows the REUSE specification (https://reuse.software), i.e. has a
+	// LICENSES directory with one <SPDX-ID>.txt file per license.
+	ReuseLicenses []string
+	// ReuseLicensePaths are the paths of the license texts in the LICENSES directory of
+	// a module following the REUSE specification.
+	ReuseLicensePaths []string
+	// FileLicenses are the licenses declared in the headers of the Go files of Packages,
+	// if Options.ScanFileLicenses is set.
+	FileLicenses []FileLicense
+	// Copyrights are the copyright statements in the headers of the Go files of Packages,
+	// if Options.ScanCopyrights is set.
+	Copyrights []Copyright
+	// LicenseCandidates are the files that were considered when no license file could be
+	// found for this library, with the known license each of them is most similar to.
+	LicenseCandidates []LicenseCandidate
+	// Imports maps each of Packages to the sorted import paths of the packages it
+	// imports, excluding the standard library.
+	Imports map[string][]string
+	// TestOnly is true if none of Packages is imported by non-test code, i.e. the library
+	// is only a dependency of tests. It is always false unless Options.IncludeTests is set.
+	TestOnly bool
+	// Direct is true if one of Packages is imported by a package of the main module, i.e.
+	// the library is a direct rather than a transitive dependency. It is false for the
+	// libraries of the main module itself.
+	Direct bool
+	// RequiredAt is the require directive of the library's module in the go.mod file of
+	// the main module, if the library is a direct dependency and go.mod requires it, e.g.
+	// to annotate that line in code review.
+	RequiredAt *GoModLocation
+	// DependencyPath is the shortest chain of imports from one of the packages the library
+	// was loaded for to one of Packages, starting with the former and ending with the
+	// latter. Imports by runtime code are preferred over imports by tests. It is empty for
+	// libraries found without loading packages, e.g. by GoSumLibraries.
+	DependencyPath []string
+	// ModuleLevel is true if the library stands for a whole module none of whose packages
+	// were loaded, see Options.IncludeAssetModules. Packages then only holds the module path.
+	ModuleLevel bool
 	// Parent go module.
 	module *Module
+	// traceURLs logs the steps of FileURL, see Options.TraceURLs.
+	traceURLs bool
+	// resolver resolves file URLs, see Options.SourceResolver.
+	resolver SourceResolver
+	// cache keeps resolved file URLs, see Options.Cache.
+	cache *Cache
 }
 
 // PackagesError aggregates all Packages[].Errors into a single error.
@@ -55,60 +109,223 @@ func (e PackagesError) Error() string {
 	return str.String()
 }
 
+// Options configures how LibrariesWithOptions discovers libraries.
+type Options struct {
+	// Dir is the directory in which import paths are loaded, the current directory if
+	// empty. Its go.mod selects the versions of dependencies.
+	Dir string
+	// IncludeTests includes packages only imported by testing code.
+	IncludeTests bool
+	// IgnoredPaths are package path prefixes to be ignored. Dependencies of ignored
+	// packages are still checked. It is equivalent to IgnoreRules with IgnoreHide.
+	IgnoredPaths []string
+	// IgnoreRules are package path prefixes to be ignored, each with its own mode.
+	IgnoreRules []IgnoreRule
+	// OnIgnored, if set, is called for every package left out by an ignore rule, e.g.
+	// to verify that the rules don't hide third-party code by accident.
+	OnIgnored func(IgnoredPackage)
+	// OnLoaded, if set, is called with the root packages loaded by packages.Load before
+	// libraries are derived from them. Following their Imports gives the complete package
+	// graph, e.g. for tools that compute reachability or per-binary attribution without
+	// loading the packages again. The packages must not be modified.
+	OnLoaded func(roots []*packages.Package)
+	// OnModule, if set, is called when the first package of a module is visited, before
+	// its license is searched, e.g. to report the progress of long scans.
+	OnModule func(*Module)
+	// SkipSymlinks ignores symlinked files and directories when searching for license
+	// files. Symlinks in the paths of module and package directories are always resolved.
+	SkipSymlinks bool
+	// LocalizedLicenseNames also searches for license files with localized names, e.g.
+	// LIZENZ, LICENCIA or ライセンス. Their texts are classified like other license files.
+	LocalizedLicenseNames bool
+	// DeepScanExcludes are glob patterns for directory names, e.g. "testdata" or
+	// "examples". Files below a matching directory of their module are skipped when file
+	// contents are scanned, e.g. for SPDX tags.
+	DeepScanExcludes []string
+	// DeepScanSkipGenerated skips generated Go files when file contents are scanned.
+	DeepScanSkipGenerated bool
+	// TraceURLs makes Library.FileURL log every step of resolving a file's URL.
+	TraceURLs bool
+	// SourceResolver resolves the URLs returned by Library.FileURL, e.g. to link an
+	// internal source browser. It defaults to NewPkgsiteResolver.
+	SourceResolver SourceResolver
+	// Cache, if set, keeps the file URLs that Library.FileURL resolves with a
+	// SourceResolver returned by NewPkgsiteResolver, the default, for module versions, so
+	// that later runs don't look them up again.
+	Cache *Cache
+	// Vendor loads packages from the vendor directory of the main module like
+	// -mod=vendor, and identifies the modules of vendored packages by the paths and
+	// versions in vendor/modules.txt, so that their file URLs point to their upstream
+	// repositories. Otherwise, vendored libraries are attributed to the vendoring module.
+	Vendor bool
+	// IncludeStdLib returns the standard library packages used as a single library
+	// named StdLibModulePath, licensed by the Go toolchain's LICENSE file and
+	// versioned by the Go version. Otherwise, the standard library is left out.
+	IncludeStdLib bool
+	// IncludeAssetModules also returns a library, licensed by the license file in its
+	// module root, for each module required directly by the go.mod file of the main
+	// module that none of the loaded packages belongs to, e.g. a module required only for
+	// its assets or for a tool imported by a file excluded by build constraints.
+	// Otherwise, such modules are left out.
+	IncludeAssetModules bool
+	// GoCacheMode selects how packages whose files are all in the build cache are
+	// treated, GoCacheResolve if empty.
+	GoCacheMode GoCacheMode
+	// ScanFileLicenses reads the SPDX-License-Identifier tags in the headers of the Go
+	// files of each package, see Library.FileLicenses. Files are filtered like for other
+	// scans of file contents, see DeepScanExcludes and DeepScanSkipGenerated.
+	ScanFileLicenses bool
+	// ScanCopyrights reads the copyright statements in the headers of the Go files of
+	// each package, see Library.Copyrights. Files are filtered like for ScanFileLicenses.
+	ScanCopyrights bool
+	// DownloadModules downloads the modules of BuildInfoLibraries that are missing from
+	// the module cache, so that their licenses can be found.
+	DownloadModules bool
+}
+
+// IgnoreMode selects what ignoring a package means.
+type IgnoreMode string
+
+const (
+	// IgnoreHide leaves the package out of the results, but still checks its dependencies.
+	IgnoreHide = IgnoreMode("hide")
+	// IgnoreSkipSubtree leaves the package out of the results and doesn't traverse its
+	// dependencies. Dependencies that are also imported by other packages are still checked.
+	IgnoreSkipSubtree = IgnoreMode("skip_subtree")
+)
+
+// IgnoreRule ignores packages whose path starts with Prefix.
+type IgnoreRule struct {
+	Prefix string     `json:"prefix"`
+	Mode   IgnoreMode `json:"mode"`
+}
+
+// IgnoredPackage is a package that was left out by an ignore rule.
+type IgnoredPackage struct {
+	Package string `json:"package"`
+	// Module is the path of the package's module, if any.
+	Module string     `json:"module,omitempty"`
+	Rule   IgnoreRule `json:"rule"`
+}
+
+// ignoreRules returns all rules configured by opts, IgnoredPaths first.
+func (opts Options) ignoreRules() []IgnoreRule {
+	var rules []IgnoreRule
+	for _, p := range opts.IgnoredPaths {
+		rules = append(rules, IgnoreRule{Prefix: p, Mode: IgnoreHide})
+	}
+	return append(rules, opts.IgnoreRules...)
+}
+
 // Libraries returns the collection of libraries used by this package, directly or transitively.
 // A library is a collection of one or more packages covered by the same license file.
 // Packages not covered by a license will be returned as individual libraries.
 // Standard library packages will be ignored.
 func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ignoredPaths []string, importPaths ...string) ([]*Library, error) {
+	return LibrariesWithOptions(ctx, classifier, Options{IncludeTests: includeTests, IgnoredPaths: ignoredPaths}, importPaths...)
+}
+
+// LibrariesWithOptions is like Libraries, but allows to configure optional behaviour.
+func LibrariesWithOptions(ctx context.Context, classifier Classifier, opts Options, importPaths ...string) ([]*Library, error) {
 	cfg := &packages.Config{
 		Context: ctx,
+		Dir:     opts.Dir,
+		Env:     goEnviron(ctx),
 		Mode:    packages.NeedImports | packages.NeedDeps | packages.NeedFiles | packages.NeedName | packages.NeedModule,
-		Tests:   includeTests,
+		Tests:   opts.IncludeTests,
+	}
+	if opts.Vendor {
+		cfg.BuildFlags = []string{"-mod=vendor"}
 	}
 
 	rootPkgs, err := packages.Load(cfg, importPaths...)
 	if err != nil {
 		return nil, err
 	}
+	// Ask the go command that listed the packages, build.Default.GOROOT is the GOROOT
+	// go-licenses was built with and differs e.g. if GOTOOLCHAIN selects another toolchain.
+	goroot, err := goEnv(cfg, "GOROOT")
+	if err != nil {
+		return nil, err
+	}
+	if opts.OnLoaded != nil {
+		opts.OnLoaded(rootPkgs)
+	}
 
 	pkgs := map[string]*packages.Package{}
 	pkgsByLicense := make(map[string][]*packages.Package)
+	candidatesByPkg := make(map[string][]LicenseCandidate)
 	pkgErrorOccurred := false
 	otherErrorOccurred := false
+	ignoreRules := opts.ignoreRules()
+	var stdPkgs []string
+	visitedModules := make(map[string]bool)
+	vendored := make(vendoredModules)
+	goCache := &goCacheDir{cfg: cfg}
+	// vendoredPkgs are the modules of vendored packages from vendor/modules.txt, see
+	// Options.Vendor.
+	vendoredPkgs := make(map[string]*Module)
+	moduleOf := func(p *packages.Package) *Module {
+		if m, ok := vendoredPkgs[p.PkgPath]; ok {
+			return m
+		}
+		return newModule(p.Module)
+	}
 	packages.Visit(rootPkgs, func(p *packages.Package) bool {
 		if len(p.Errors) > 0 {
 			pkgErrorOccurred = true
 			return false
 		}
-		if isStdLib(p) {
-			// No license requirements for the Go standard library.
+		if isStdLib(p, goroot) {
+			if opts.IncludeStdLib && !isTestVariant(p) {
+				stdPkgs = append(stdPkgs, p.PkgPath)
+			}
+			// Standard library packages only import other standard library packages.
 			return false
 		}
-		if includeTests && isTestBinary(p) {
-			// A test binary only imports the standard library, so we do not need to check its license.
-			// Moreover, Find below will return an error because pkgDir is not under p.Module.Dir
-			// as pkgDir is under GOCACHE instead.
+		if opts.IncludeTests && isTestBinary(p) {
+			// A test binary only imports the standard library and the packages under test, so
+			// we do not need to check its license. Moreover, its only file is in the build
+			// cache rather than in p.Module.Dir, see GoCacheMode.
 			return false
 		}
-		for _, i := range ignoredPaths {
-			if strings.HasPrefix(p.PkgPath, i) {
+		for _, rule := range ignoreRules {
+			if strings.HasPrefix(p.PkgPath, rule.Prefix) {
 				// Marked to be ignored.
-				return true
+				if opts.OnIgnored != nil {
+					ignored := IgnoredPackage{Package: p.PkgPath, Rule: rule}
+					if p.Module != nil {
+						ignored.Module = p.Module.Path
+					}
+					opts.OnIgnored(ignored)
+				}
+				return rule.Mode != IgnoreSkipSubtree
 			}
 		}
 
 		if len(p.OtherFiles) > 0 {
-			klog.Warningf("%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
-		}
-		var pkgDir string
-		switch {
-		case len(p.GoFiles) > 0:
-			pkgDir = filepath.Dir(p.GoFiles[0])
-		case len(p.CompiledGoFiles) > 0:
-			pkgDir = filepath.Dir(p.CompiledGoFiles[0])
-		case len(p.OtherFiles) > 0:
-			pkgDir = filepath.Dir(p.OtherFiles[0])
-		default:
+			var modulePath string
+			if p.Module != nil {
+				modulePath = p.Module.Path
+			}
+			warnf(ctx, WarningNonGoCode, modulePath, "%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
+		}
+		pkgDir, inCache := packageDir(p, goCache)
+		if inCache {
+			switch opts.GoCacheMode {
+			case GoCacheSkip:
+				return true
+			case GoCacheError:
+				otherErrorOccurred = true
+				klog.Errorf("Package %s only has files in the build cache, so its license can't be found", p.PkgPath)
+				return false
+			}
+			if pkgDir = importPathDir(p); pkgDir == "" {
+				klog.Warningf("Package %s only has files in the build cache and no directory in its module, leaving it out", p.PkgPath)
+				return true
+			}
+		}
+		if pkgDir == "" {
 			// This package is empty - nothing to do.
 			return true
 		}
@@ -117,9 +334,39 @@ func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ig
 			klog.Errorf("Package %s does not have module info. Non go modules projects are no longer supported. For feedback, refer to https://github.com/nilsbeck/go-licenses/issues/128.", p.PkgPath)
 			return false
 		}
-		licensePath, err := Find(pkgDir, p.Module.Dir, classifier)
+		if opts.OnModule != nil && !visitedModules[p.Module.Path] {
+			visitedModules[p.Module.Path] = true
+			opts.OnModule(newModule(p.Module))
+		}
+		moduleDir := p.Module.Dir
+		if opts.Vendor && moduleDir == "" {
+			m, err := vendored.module(pkgDir, p.PkgPath)
+			if err != nil {
+				otherErrorOccurred = true
+				klog.Errorf("Failed to identify the vendored module of %s: %v", p.PkgPath, err)
+				return false
+			}
+			if m != nil {
+				vendoredPkgs[p.PkgPath] = m
+				moduleDir = m.Dir
+			}
+		}
+		licensePath, err := find(pkgDir, moduleDir, classifier, licenseFileRegexp(opts.LocalizedLicenseNames), opts.SkipSymlinks)
 		if err != nil {
-			klog.Errorf("Failed to find license for %s: %v", p.PkgPath, err)
+			if _, reusePaths := reuseLicenses(moduleDir, nil); len(reusePaths) > 0 {
+				// Modules following the REUSE specification may only have license texts
+				// in their LICENSES directory.
+				licensePath = reusePaths[0]
+			} else if path := commentLicense(p.GoFiles, classifier); path != "" {
+				warnf(ctx, WarningLicenseInComment, p.Module.Path, "Package %s has no license file, using the license in the header comment of %s", p.PkgPath, path)
+				licensePath = path
+			} else {
+				candidates := findCandidates(pkgDir, moduleDir, classifier, licenseFileRegexp(opts.LocalizedLicenseNames), opts.SkipSymlinks)
+				candidatesByPkg[p.PkgPath] = candidates
+				klog.Errorf("Failed to find license for %s: %v%s", p.PkgPath, err, describeCandidates(candidates))
+			}
+		} else if path := unidentifiedLicense(pkgDir, licensePath, classifier, opts.SkipSymlinks); path != "" {
+			warnf(ctx, WarningUnidentifiedLicense, p.Module.Path, "Package %s has a license file %s that can't be identified, using the license of %s instead", p.PkgPath, path, licensePath)
 		}
 		pkgs[p.PkgPath] = p
 		pkgsByLicense[licensePath] = append(pkgsByLicense[licensePath], p)
@@ -139,28 +386,49 @@ func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ig
 		if licensePath == "" {
 			// No license for these packages - return each one as a separate library.
 			for _, p := range pkgs {
-				libraries = append(libraries, &Library{
-					Packages: []string{p.PkgPath},
-					module:   newModule(p.Module),
-				})
+				lib := &Library{
+					Packages:          []string{p.PkgPath},
+					LicenseCandidates: candidatesByPkg[p.PkgPath],
+					Imports:           map[string][]string{p.PkgPath: imports(p, goroot)},
+					module:            moduleOf(p),
+					traceURLs:         opts.TraceURLs,
+					resolver:          opts.SourceResolver,
+					cache:             opts.Cache,
+				}
+				lib.applyReuse([]*packages.Package{p}, opts)
+				lib.applyFileLicenses([]*packages.Package{p}, opts)
+				lib.applyCopyrights([]*packages.Package{p}, opts)
+				libraries = append(libraries, lib)
 			}
 			continue
 		}
 		lib := &Library{
-			LicensePath: licensePath,
+			LicensePath:    licensePath,
+			NoticePath:     findNotice(licensePath),
+			LicenseFiles:   findLicenseFiles(licensePath),
+			LicenseChoices: licenseChoices(classifier, licensePath),
+			Imports:        make(map[string][]string),
+			traceURLs:      opts.TraceURLs,
+			resolver:       opts.SourceResolver,
+			cache:          opts.Cache,
 		}
 		for _, pkg := range pkgs {
 			lib.Packages = append(lib.Packages, pkg.PkgPath)
+			lib.Imports[pkg.PkgPath] = imports(pkg, goroot)
 			if lib.module == nil && pkg.Module != nil {
 				// All the sub packages should belong to the same module.
-				lib.module = newModule(pkg.Module)
+				lib.module = moduleOf(pkg)
 			}
 		}
+		lib.applyReuse(pkgs, opts)
+		lib.applyFileLicenses(pkgs, opts)
+		lib.applyCopyrights(pkgs, opts)
 		if lib.module != nil && lib.module.Path != "" && lib.module.Dir == "" {
 			// A known cause is that the module is vendored, so some information is lost.
-			splits := strings.SplitN(lib.LicensePath, "/vendor/", 2)
+			sep := string(filepath.Separator)
+			splits := strings.SplitN(lib.LicensePath, sep+"vendor"+sep, 2)
 			if len(splits) != 2 {
-				klog.Warningf("module %s does not have dir and it's not vendored, cannot discover the license URL. Report to go-licenses developer if you see this.", lib.module.Path)
+				warnf(ctx, WarningVendoredModule, lib.module.Path, "module %s does not have dir and it's not vendored, cannot discover the license URL. Report to go-licenses developer if you see this.", lib.module.Path)
 			} else {
 				// This is vendored. Handle this known special case.
 
@@ -179,13 +447,13 @@ func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ig
 				parentModDir := splits[0]
 				var parentPkg *packages.Package
 				for _, rootPkg := range rootPkgs {
-					if rootPkg.Module != nil && rootPkg.Module.Dir == parentModDir {
+					if rootPkg.Module != nil && samePath(resolveSymlinks(rootPkg.Module.Dir), parentModDir) {
 						parentPkg = rootPkg
 						break
 					}
 				}
 				if parentPkg == nil {
-					klog.Warningf("cannot find parent package of vendored module %s", lib.module.Path)
+					warnf(ctx, WarningVendoredModule, lib.module.Path, "cannot find parent package of vendored module %s", lib.module.Path)
 				} else {
 					// Vendored modules should be commited in the parent module, so it counts as part of the
 					// parent module.
@@ -195,6 +463,34 @@ func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ig
 		}
 		libraries = append(libraries, lib)
 	}
+	policy, err := loadChecksumPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, lib := range libraries {
+		if lib.module != nil {
+			policy.apply(lib.module)
+		}
+	}
+	if len(stdPkgs) > 0 {
+		lib, err := stdLibrary(cfg, goroot, stdPkgs, opts)
+		if err != nil {
+			return nil, err
+		}
+		libraries = append(libraries, lib)
+	}
+	if opts.IncludeTests {
+		markTestOnly(libraries, rootPkgs)
+	}
+	markDirect(libraries, rootPkgs)
+	setDependencyPaths(libraries, rootPkgs, goroot)
+	if opts.IncludeAssetModules {
+		assetLibs, err := assetModuleLibraries(ctx, classifier, opts, libraries, rootPkgs)
+		if err != nil {
+			return nil, err
+		}
+		libraries = append(libraries, assetLibs...)
+	}
 	// Sort libraries to produce a stable result for snapshot diffing.
 	sort.Slice(libraries, func(i, j int) bool {
 		return libraries[i].Name() < libraries[j].Name()
@@ -202,8 +498,199 @@ func Libraries(ctx context.Context, classifier Classifier, includeTests bool, ig
 	return libraries, nil
 }
 
+// markTestOnly sets TestOnly for the libraries that are not reachable from the
+// non-test variants of rootPkgs.
+func markTestOnly(libraries []*Library, rootPkgs []*packages.Package) {
+	var roots []*packages.Package
+	for _, p := range rootPkgs {
+		if !isTestVariant(p) {
+			roots = append(roots, p)
+		}
+	}
+	runtimePkgs := make(map[string]bool)
+	packages.Visit(roots, func(p *packages.Package) bool {
+		runtimePkgs[p.PkgPath] = true
+		return true
+	}, nil)
+	for _, lib := range libraries {
+		lib.TestOnly = true
+		for _, pkg := range lib.Packages {
+			if runtimePkgs[pkg] {
+				lib.TestOnly = false
+				break
+			}
+		}
+	}
+}
+
+// markDirect sets Direct for the libraries with a package that a package of the main
+// module imports from another module.
+func markDirect(libraries []*Library, rootPkgs []*packages.Package) {
+	direct := make(map[string]bool)
+	packages.Visit(rootPkgs, func(p *packages.Package) bool {
+		if p.Module == nil || !p.Module.Main {
+			return true
+		}
+		for _, imp := range p.Imports {
+			if imp.Module == nil || imp.Module.Path != p.Module.Path {
+				direct[imp.PkgPath] = true
+			}
+		}
+		return true
+	}, nil)
+	for _, lib := range libraries {
+		if m := lib.module; m != nil && m.Main {
+			continue
+		}
+		for _, pkg := range lib.Packages {
+			if direct[pkg] {
+				lib.Direct = true
+				break
+			}
+		}
+	}
+	setRequiredAt(libraries, rootPkgs)
+}
+
+// setRequiredAt sets RequiredAt for the direct dependencies among libraries from the
+// go.mod files of the main modules of rootPkgs. Modules that a go.mod file marks as
+// // indirect, although imported directly, are located too.
+func setRequiredAt(libraries []*Library, rootPkgs []*packages.Package) {
+	locations := make(map[string]GoModLocation)
+	seen := make(map[string]bool)
+	packages.Visit(rootPkgs, nil, func(p *packages.Package) {
+		if p.Module == nil || !p.Module.Main || p.Module.GoMod == "" || seen[p.Module.GoMod] {
+			return
+		}
+		seen[p.Module.GoMod] = true
+		reqs, err := requirements(p.Module.GoMod)
+		if err != nil {
+			klog.Warningf("Failed to read the require directives of %s: %v", p.Module.GoMod, err)
+			return
+		}
+		for path, r := range reqs {
+			if _, ok := locations[path]; !ok {
+				locations[path] = r.at
+			}
+		}
+	})
+	for _, lib := range libraries {
+		m := lib.module
+		if !lib.Direct || m == nil {
+			continue
+		}
+		required := m.Path
+		if m.Replaces != nil {
+			required = m.Replaces.Path
+		}
+		if at, ok := locations[required]; ok {
+			lib.RequiredAt = &at
+		}
+	}
+}
+
+// setDependencyPaths sets the DependencyPath of libraries by searching the import graph
+// of rootPkgs breadth-first, first from their non-test variants and then from the test
+// variants for the packages that only tests import.
+func setDependencyPaths(libraries []*Library, rootPkgs []*packages.Package, goroot string) {
+	// parent is the package through which each package was reached first.
+	parent := make(map[*packages.Package]*packages.Package)
+	// reached is the package that each package path was reached with first.
+	reached := make(map[string]*packages.Package)
+	seen := make(map[*packages.Package]bool)
+	for _, test := range []bool{false, true} {
+		var queue []*packages.Package
+		for _, p := range rootPkgs {
+			if !seen[p] && !isTestBinary(p) && isTestVariant(p) == test {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			if _, ok := reached[p.PkgPath]; !ok {
+				reached[p.PkgPath] = p
+			}
+			if isStdLib(p, goroot) {
+				continue
+			}
+			var paths []string
+			for path := range p.Imports {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				if imp := p.Imports[path]; !seen[imp] {
+					seen[imp] = true
+					parent[imp] = p
+					queue = append(queue, imp)
+				}
+			}
+		}
+	}
+	for _, lib := range libraries {
+		var shortest []string
+		for _, pkg := range lib.Packages {
+			p, ok := reached[pkg]
+			if !ok {
+				continue
+			}
+			chain := []string{p.PkgPath}
+			for q, ok := parent[p]; ok; q, ok = parent[q] {
+				chain = append([]string{q.PkgPath}, chain...)
+			}
+			if shortest == nil || len(chain) < len(shortest) {
+				shortest = chain
+			}
+		}
+		lib.DependencyPath = shortest
+	}
+}
+
+// describeCandidates formats candidates for log messages.
+func describeCandidates(candidates []LicenseCandidate) string {
+	if len(candidates) == 0 {
+		return "\nNo candidate license files were found, check whether the license file is named unusually."
+	}
+	var b strings.Builder
+	b.WriteString("\nCandidate license files:")
+	for _, c := range candidates {
+		b.WriteString("\n  " + c.String())
+	}
+	return b.String()
+}
+
+// imports returns the sorted import paths of the non-standard-library packages imported by p.
+func imports(p *packages.Package, goroot string) []string {
+	var paths []string
+	for _, imp := range p.Imports {
+		if !isStdLib(imp, goroot) {
+			paths = append(paths, imp.PkgPath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// applyReuse records the licenses declared via the REUSE specification for pkgs, if the
+// library's module follows it.
+func (l *Library) applyReuse(pkgs []*packages.Package, opts Options) {
+	if l.module == nil || l.module.Dir == "" {
+		return
+	}
+	var goFiles []string
+	for _, p := range pkgs {
+		goFiles = append(goFiles, p.GoFiles...)
+	}
+	l.ReuseLicenses, l.ReuseLicensePaths = reuseLicenses(l.module.Dir, deepScanFiles(l.module.Dir, goFiles, opts))
+}
+
 // Name is the common prefix of the import paths for all of the packages in this library.
 func (l *Library) Name() string {
+	if l.module != nil && l.module.Path == StdLibModulePath {
+		return StdLibModulePath
+	}
 	return commonAncestor(l.Packages)
 }
 
@@ -248,40 +735,105 @@ func (l *Library) FileURL(ctx context.Context, filePath string) (string, error)
 	if m.Dir == "" {
 		return "", wrap(fmt.Errorf("empty go module dir"))
 	}
-	client := source.NewClient(time.Second * 20)
-	remote, err := source.ModuleInfo(ctx, client, m.Path, m.Version)
+	if l.traceURLs {
+		ctx = source.WithTracef(ctx, l.tracef)
+		l.tracef("%s: resolving URL of %s in module %s@%s", l.Name(), filePath, m.Path, m.Version)
+	}
+	// License paths have symlinks resolved, so module dirs need to be resolved as well.
+	relativePath, err := relSlashPath(resolveSymlinks(m.Dir), resolveSymlinks(filePath))
 	if err != nil {
 		return "", wrap(err)
 	}
-	if m.Version == "" {
-		// This always happens for the module in development.
-		// Note#1 if we pass version=HEAD to source.ModuleInfo, github tag for modules not at the root
-		// of the repo will be incorrect, because there's a convention that:
-		// * I have a module at github.com/nilsbeck/go-licenses/submod.
-		// * The module is of version v1.0.0.
-		// Then the github tag should be submod/v1.0.0.
-		// In our case, if we pass HEAD as version, the result commit will be submod/HEAD which is incorrect.
-		// Therefore, to workaround this problem, we directly set the commit after getting module info.
-		//
-		// Note#2 repos have different branches as default, some use the
-		// master branch and some use the main branch. However, HEAD
-		// always refers to the default branch, so it's better than
-		// both of master/main when we do not know which branch is default.
-		// Examples:
-		// * https://github.com/nilsbeck/go-licenses/blob/HEAD/LICENSE
-		// points to latest commit of master branch.
-		// * https://github.com/google/licenseclassifier/blob/HEAD/LICENSE
-		// points to latest commit of main branch.
-		remote.SetCommit("HEAD")
-		klog.Warningf("module %s has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!", m.Path)
-	}
-	relativePath, err := filepath.Rel(m.Dir, filePath)
+	url, err := l.moduleFileURL(ctx, relativePath)
 	if err != nil {
 		return "", wrap(err)
 	}
+	return url, nil
+}
+
+// ModuleFileURL returns the URL at which the file at pathname, which is slash-separated
+// and relative to the root of module modulePath at version, can be viewed, e.g. of a
+// NOTICE or PATENTS file or of a source file. version is empty for modules without a
+// version, e.g. the main module. The file doesn't need to exist locally. Of opts, only
+// TraceURLs, SourceResolver and Cache apply.
+func ModuleFileURL(ctx context.Context, opts Options, modulePath, version, pathname string) (string, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("getting URL of %s in module %s@%s: %w", pathname, modulePath, version, err)
+	}
+	relativePath := path.Clean(pathname)
+	if path.IsAbs(relativePath) || relativePath == ".." || strings.HasPrefix(relativePath, "../") {
+		return "", wrap(fmt.Errorf("path is not relative to the module root"))
+	}
+	lib := &Library{
+		Packages:  []string{modulePath},
+		module:    &Module{Path: modulePath, Version: version},
+		traceURLs: opts.TraceURLs,
+		resolver:  opts.SourceResolver,
+		cache:     opts.Cache,
+	}
+	if lib.traceURLs {
+		ctx = source.WithTracef(ctx, lib.tracef)
+		lib.tracef("%s: resolving URL of %s in module %s@%s", modulePath, relativePath, modulePath, version)
+	}
+	url, err := lib.moduleFileURL(ctx, relativePath)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return url, nil
+}
+
+// moduleFileURL returns the URL of the file at relativePath in the module of l.
+func (l *Library) moduleFileURL(ctx context.Context, relativePath string) (string, error) {
+	m := l.module
+	resolver := l.resolver
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+	// Only URLs of pkgsite resolvers are cached, since other resolvers map modules
+	// differently. Tracing shows how URLs are resolved, so it bypasses the cache.
+	cache := l.cache
+	pkgsite, ok := resolver.(*pkgsiteResolver)
+	if !ok || l.traceURLs {
+		cache = nil
+	}
+	if cache != nil {
+		if url, ok := cache.fileURL(m.Path, m.Version, pkgsite.fingerprint, relativePath); ok {
+			return url, nil
+		}
+	}
+	remote, err := resolver.ModuleInfo(ctx, m.Path, m.Version)
+	if err != nil {
+		return "", err
+	}
+	if m.Version == "" {
+		l.tracef("%s: module has no version, the resolver picks the revision", l.Name())
+	}
 	// TODO: there are still rare cases this may result in an incorrect URL.
 	// https://github.com/nilsbeck/go-licenses/issues/73#issuecomment-1005587408
-	return remote.FileURL(relativePath), nil
+	url := remote.FileURL(relativePath)
+	l.tracef("%s: path %q relative to the root of module %s results in %s", l.Name(), relativePath, m.Path, url)
+	if cache != nil {
+		cache.setFileURL(m.Path, m.Version, pkgsite.fingerprint, relativePath, url)
+	}
+	return url, nil
+}
+
+// tracef logs a step of resolving a URL if tracing is enabled.
+func (l *Library) tracef(format string, args ...interface{}) {
+	if l.traceURLs {
+		klog.Infof("[url] "+format, args...)
+	}
+}
+
+// LicenseInComment reports whether the library's license was found in the header comment
+// of a Go file, e.g. doc.go, rather than in a license file.
+func (l *Library) LicenseInComment() bool {
+	return isGoFile(l.LicensePath)
+}
+
+// Module returns the Go module containing the library, or nil if it is unknown.
+func (l *Library) Module() *Module {
+	return l.module
 }
 
 func (l *Library) Version() string {
@@ -291,8 +843,8 @@ func (l *Library) Version() string {
 	return ""
 }
 
-// isStdLib returns true if this package is part of the Go standard library.
-func isStdLib(pkg *packages.Package) bool {
+// isStdLib returns true if this package is part of the Go standard library in goroot.
+func isStdLib(pkg *packages.Package, goroot string) bool {
 	if pkg.Name == "unsafe" {
 		// Special case unsafe stdlib, because it does not contain go files.
 		return true
@@ -300,7 +852,7 @@ func isStdLib(pkg *packages.Package) bool {
 	if len(pkg.GoFiles) == 0 {
 		return false
 	}
-	prefix := build.Default.GOROOT
+	prefix := goroot
 	sep := string(filepath.Separator)
 	if !strings.HasSuffix(prefix, sep) {
 		prefix += sep
@@ -308,7 +860,15 @@ func isStdLib(pkg *packages.Package) bool {
 	return strings.HasPrefix(pkg.GoFiles[0], prefix)
 }
 
-// isTestBinary returns true iff pkg is a test binary.
+// isTestBinary returns true iff pkg is a test binary, i.e. the main package generated by
+// go test for a package, e.g. "example.com/foo.test". Its generated file is in the build
+// cache, whose location varies, so only its name and ID are relied upon.
 func isTestBinary(pkg *packages.Package) bool {
-	return strings.HasSuffix(pkg.PkgPath, ".test")
+	return pkg.Name == "main" && pkg.ID == pkg.PkgPath && strings.HasSuffix(pkg.PkgPath, ".test")
+}
+
+// isTestVariant returns true if pkg is a test binary or a package compiled for one, e.g.
+// "example.com/foo [example.com/foo.test]" or its external test package.
+func isTestVariant(pkg *packages.Package) bool {
+	return isTestBinary(pkg) || strings.Contains(pkg.ID, " [")
 }
@@ -17,10 +17,12 @@ package licenses
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestLibraries(t *testing.T) {
@@ -43,6 +45,7 @@ func TestLibraries(t *testing.T) {
 		goflags      string
 		includeTests bool
 		ignore       []string
+		ignoreRules  []IgnoreRule
 		wantLibs     []string
 	}{
 		{
@@ -73,6 +76,16 @@ func TestLibraries(t *testing.T) {
 				"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
 			},
 		},
+		{
+			desc:       "Ignores a package subtree",
+			importPath: "github.com/nilsbeck/go-licenses/licenses/testdata",
+			ignoreRules: []IgnoreRule{
+				{Prefix: "github.com/nilsbeck/go-licenses/licenses/testdata/direct", Mode: IgnoreSkipSubtree},
+			},
+			wantLibs: []string{
+				"github.com/nilsbeck/go-licenses/licenses/testdata",
+			},
+		},
 		{
 			desc:         "Detects the dependencies only imported in testing code",
 			importPath:   "github.com/nilsbeck/go-licenses/licenses/testdata/testlib",
@@ -105,7 +118,8 @@ func TestLibraries(t *testing.T) {
 				os.Setenv("GOFLAGS", test.goflags)
 				defer os.Unsetenv("GOFLAGS")
 			}
-			gotLibs, err := Libraries(context.Background(), classifier, test.includeTests, test.ignore, test.importPath)
+			opts := Options{IncludeTests: test.includeTests, IgnoredPaths: test.ignore, IgnoreRules: test.ignoreRules}
+			gotLibs, err := LibrariesWithOptions(context.Background(), classifier, opts, test.importPath)
 			if err != nil {
 				t.Fatalf("Libraries(_, %q) = (_, %q), want (_, nil)", test.importPath, err)
 			}
@@ -120,6 +134,306 @@ func TestLibraries(t *testing.T) {
 	}
 }
 
+func TestLibrariesOnIgnored(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	rule := IgnoreRule{Prefix: "github.com/nilsbeck/go-licenses/licenses/testdata/direct", Mode: IgnoreHide}
+	var got []IgnoredPackage
+	opts := Options{
+		IgnoreRules: []IgnoreRule{rule},
+		OnIgnored:   func(p IgnoredPackage) { got = append(got, p) },
+	}
+	if _, err := LibrariesWithOptions(context.Background(), classifier, opts, "github.com/nilsbeck/go-licenses/licenses/testdata"); err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	want := []IgnoredPackage{
+		{Package: "github.com/nilsbeck/go-licenses/licenses/testdata/direct", Module: "github.com/nilsbeck/go-licenses", Rule: rule},
+		{Package: "github.com/nilsbeck/go-licenses/licenses/testdata/direct/subpkg", Module: "github.com/nilsbeck/go-licenses", Rule: rule},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(x, y IgnoredPackage) bool { return x.Package < y.Package })); diff != "" {
+		t.Errorf("ignored packages diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestLibrariesTestOnly(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	libs, err := LibrariesWithOptions(context.Background(), classifier, Options{IncludeTests: true}, "github.com/nilsbeck/go-licenses/licenses/testdata/testlib")
+	if err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	got := make(map[string]bool)
+	for _, lib := range libs {
+		got[lib.Name()] = lib.TestOnly
+	}
+	want := map[string]bool{
+		"github.com/nilsbeck/go-licenses/licenses/testdata/testlib":  false,
+		"github.com/nilsbeck/go-licenses/licenses/testdata/indirect": true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TestOnly by library diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestLibrariesDependencyPath(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	for _, test := range []struct {
+		desc         string
+		importPath   string
+		includeTests bool
+		want         map[string][]string
+	}{
+		{
+			desc:       "Transitive dependency",
+			importPath: "github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+			want: map[string][]string{
+				"github.com/nilsbeck/go-licenses/licenses/testdata/direct": {
+					"github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+				},
+				"github.com/nilsbeck/go-licenses/licenses/testdata/indirect": {
+					"github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+					"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
+				},
+			},
+		},
+		{
+			desc:         "Dependency of tests",
+			importPath:   "github.com/nilsbeck/go-licenses/licenses/testdata/testlib",
+			includeTests: true,
+			want: map[string][]string{
+				"github.com/nilsbeck/go-licenses/licenses/testdata/testlib": {
+					"github.com/nilsbeck/go-licenses/licenses/testdata/testlib",
+				},
+				"github.com/nilsbeck/go-licenses/licenses/testdata/indirect": {
+					"github.com/nilsbeck/go-licenses/licenses/testdata/testlib",
+					"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
+				},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			libs, err := LibrariesWithOptions(context.Background(), classifier, Options{IncludeTests: test.includeTests}, test.importPath)
+			if err != nil {
+				t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+			}
+			got := make(map[string][]string)
+			for _, lib := range libs {
+				got[lib.Name()] = lib.DependencyPath
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("DependencyPath by library diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLibrariesDirect(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("..", "testdata", "modules", "cli02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"../testdata/modules/cli02/LICENSE": "Apache-2.0",
+		},
+		licenseTypes: map[string]Type{
+			"../testdata/modules/cli02/LICENSE": Notice,
+		},
+	}
+	libs, err := LibrariesWithOptions(context.Background(), classifier, Options{Dir: dir}, ".")
+	if err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	got := make(map[string]bool)
+	for _, lib := range libs {
+		got[lib.Name()] = lib.Direct
+	}
+	for name, want := range map[string]bool{
+		"github.com/nilsbeck/go-licenses/testdata/modules/cli02": false,
+		"github.com/spf13/cobra":                                 true,
+		"github.com/mitchellh/go-homedir":                        true,
+		"github.com/spf13/pflag":                                 false,
+	} {
+		if direct, ok := got[name]; !ok || direct != want {
+			t.Errorf("library %s has Direct = %v (found: %v), want %v", name, direct, ok, want)
+		}
+	}
+}
+
+func TestLibrariesIncludeStdLib(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	libs, err := LibrariesWithOptions(context.Background(), classifier, Options{IncludeStdLib: true}, "github.com/nilsbeck/go-licenses/licenses/testdata")
+	if err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	var std *Library
+	for _, lib := range libs {
+		if lib.Name() == StdLibModulePath {
+			std = lib
+		}
+	}
+	if std == nil {
+		t.Fatalf("LibrariesWithOptions() returned no %q library", StdLibModulePath)
+	}
+	if filepath.Base(std.LicensePath) != "LICENSE" {
+		t.Errorf("LicensePath = %q, want the LICENSE file of GOROOT", std.LicensePath)
+	}
+	if m := std.Module(); m == nil || m.Path != StdLibModulePath {
+		t.Errorf("Module() = %+v, want path %q", m, StdLibModulePath)
+	}
+	found := false
+	for _, p := range std.Packages {
+		found = found || p == "strings"
+	}
+	if !found {
+		t.Errorf("Packages = %q, want to contain %q", std.Packages, "strings")
+	}
+}
+
+func TestLibrariesOnLoaded(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	var roots []*packages.Package
+	opts := Options{OnLoaded: func(pkgs []*packages.Package) { roots = pkgs }}
+	if _, err := LibrariesWithOptions(context.Background(), classifier, opts, "github.com/nilsbeck/go-licenses/licenses/testdata"); err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	var got []string
+	packages.Visit(roots, nil, func(p *packages.Package) {
+		got = append(got, p.PkgPath)
+	})
+	for _, want := range []string{
+		"github.com/nilsbeck/go-licenses/licenses/testdata",
+		"github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+		"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
+		"strings",
+	} {
+		found := false
+		for _, p := range got {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("package graph %q doesn't contain %q", got, want)
+		}
+	}
+}
+
+func TestLibrariesOnModule(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	var got []string
+	opts := Options{OnModule: func(m *Module) { got = append(got, m.Path) }}
+	if _, err := LibrariesWithOptions(context.Background(), classifier, opts, "github.com/nilsbeck/go-licenses/licenses/testdata"); err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	// All packages of the testdata belong to the go-licenses module, which must be
+	// reported only once.
+	if diff := cmp.Diff([]string{"github.com/nilsbeck/go-licenses"}, got); diff != "" {
+		t.Errorf("OnModule calls: (-want +got):\n%s", diff)
+	}
+}
+
+func TestLibraryImports(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+	libs, err := Libraries(context.Background(), classifier, false, nil, "github.com/nilsbeck/go-licenses/licenses/testdata")
+	if err != nil {
+		t.Fatalf("Libraries() = (_, %q), want (_, nil)", err)
+	}
+	got := make(map[string][]string)
+	for _, lib := range libs {
+		for pkg, imports := range lib.Imports {
+			got[pkg] = imports
+		}
+	}
+	want := map[string][]string{
+		"github.com/nilsbeck/go-licenses/licenses/testdata": {
+			"github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+			"github.com/nilsbeck/go-licenses/licenses/testdata/internal",
+		},
+		"github.com/nilsbeck/go-licenses/licenses/testdata/internal": nil,
+		"github.com/nilsbeck/go-licenses/licenses/testdata/direct": {
+			"github.com/nilsbeck/go-licenses/licenses/testdata/direct/subpkg",
+			"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
+		},
+		"github.com/nilsbeck/go-licenses/licenses/testdata/direct/subpkg": nil,
+		"github.com/nilsbeck/go-licenses/licenses/testdata/indirect":      nil,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Library.Imports diff (-want +got)\n%s", diff)
+	}
+}
+
 func TestLibraryName(t *testing.T) {
 	for _, test := range []struct {
 		desc     string
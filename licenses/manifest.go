@@ -0,0 +1,102 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// manifestName is the name of the manifest file written to the root of the directory that
+// SaveLibraries saves to.
+const manifestName = "manifest.json"
+
+// manifest lists the files written by SaveLibraries, so that a bundle can be verified later.
+type manifest struct {
+	Files []manifestFile `json:"files"`
+}
+
+// manifestFile is a single file in the save directory.
+type manifestFile struct {
+	// Path is relative to the save directory, using forward slashes.
+	Path    string `json:"path"`
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// savedModule is the module whose files were saved to a library's directory.
+type savedModule struct {
+	path, version string
+}
+
+// writeManifest hashes every file below dir and writes the manifest to dir. Files are
+// attributed to the module of the most specific library directory containing them.
+func writeManifest(dir string, modules map[string]savedModule) error {
+	var m manifest
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		f := manifestFile{SHA256: sum}
+		libDir := ""
+		for ld, mod := range modules {
+			if (p == ld || strings.HasPrefix(p, ld+string(filepath.Separator))) && len(ld) > len(libDir) {
+				libDir = ld
+				f.Module, f.Version = mod.path, mod.version
+			}
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f.Path = filepath.ToSlash(rel)
+		m.Files = append(m.Files, f)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestName), append(b, '\n'), 0644)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
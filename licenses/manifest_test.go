@@ -0,0 +1,68 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{
+		"example.com/mod/LICENSE",
+		"example.com/mod/sub/LICENSE",
+		"example.com/mod/sub/NOTICE",
+		"example.com/mod-other/LICENSE",
+		"example.com/unattributed/LICENSE",
+	} {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(f)), "text")
+	}
+	modules := map[string]savedModule{
+		filepath.Join(dir, "example.com", "mod"): {path: "example.com/mod", version: "v1.0.0"},
+		// A nested library with a module of its own, e.g. a nested module.
+		filepath.Join(dir, "example.com", "mod", "sub"): {path: "example.com/mod/sub", version: "v0.1.0"},
+		// Not a prefix of example.com/mod-other by path components.
+		filepath.Join(dir, "example.com", "mod-"):      {path: "example.com/mod-", version: "v9.0.0"},
+		filepath.Join(dir, "example.com", "mod-other"): {path: "example.com/mod-other", version: "v2.0.0"},
+	}
+	if err := writeManifest(dir, modules); err != nil {
+		t.Fatalf("writeManifest() = %v, want nil", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got manifest
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	// SHA-256 of "text".
+	const sum = "982d9e3eb996f559e633f4d194def3761d909f5a3b647d1a851fead67c32c9d1"
+	want := manifest{Files: []manifestFile{
+		{Path: "example.com/mod-other/LICENSE", Module: "example.com/mod-other", Version: "v2.0.0", SHA256: sum},
+		{Path: "example.com/mod/LICENSE", Module: "example.com/mod", Version: "v1.0.0", SHA256: sum},
+		{Path: "example.com/mod/sub/LICENSE", Module: "example.com/mod/sub", Version: "v0.1.0", SHA256: sum},
+		{Path: "example.com/mod/sub/NOTICE", Module: "example.com/mod/sub", Version: "v0.1.0", SHA256: sum},
+		{Path: "example.com/unattributed/LICENSE", SHA256: sum},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("writeManifest(): manifest diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// ModuleListLibraries returns a library per module in the build list of the main module
+// in dir, as listed by `go list -m all`, without loading any packages. Unlike
+// LibrariesWithOptions, it works for modules whose packages don't compile. Libraries
+// are found like in GoSumLibraries, which opts apply to the same way.
+//
+// Only modules whose content is listed in go.sum are returned, besides the main module
+// and modules replaced with local directories. Like in GoSumLibraries, these may include
+// modules that no package imports, but the versions are exactly those that the go
+// command selects, with replace directives applied.
+func ModuleListLibraries(ctx context.Context, classifier Classifier, opts Options, dir string) ([]*Library, error) {
+	modules, err := ListModules(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := goEnv(&packages.Config{Context: ctx, Dir: dir, Env: goEnviron(ctx)}, "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	sum, err := os.ReadFile(strings.TrimSuffix(goMod, ".mod") + ".sum")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Most modules in the build list only contribute their go.mod file to the module
+	// graph. Leave them out, as GoSumLibraries does.
+	contents := goSumContents(sum)
+	var built []*Module
+	for _, m := range modules {
+		if !m.Main && m.Version != "" && !contents[m.Path+"@"+m.Version] {
+			continue
+		}
+		if m.Dir == "" && !m.Main {
+			warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s@%s is not in the module cache, run \"go mod download\" to find its license", m.Path, m.Version)
+		}
+		built = append(built, m)
+	}
+	return moduleLibraries(classifier, opts, built)
+}
+
+// ListModules returns the modules in the build list of the main module in dir, starting
+// with the main module. Replaced modules are returned as their replacement. Dir is left
+// empty for modules that have not been downloaded to the module cache or failed to load.
+func ListModules(ctx context.Context, dir string) ([]*Module, error) {
+	// -mod=readonly, since the build list can't be computed in vendor mode, which is the
+	// default for modules with a vendor directory.
+	cmd := exec.CommandContext(ctx, "go", "list", "-mod=readonly", "-m", "-json", "all")
+	cmd.Dir = dir
+	cmd.Env = goEnviron(ctx)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -m all: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var modules []*Module
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pm packages.Module
+		if err := dec.Decode(&pm); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding go list -m all output: %w", err)
+		}
+		m := newModule(&pm)
+		if pm.Error != nil {
+			m.Dir = ""
+		}
+		modules = append(modules, m)
+	}
+
+	policy, err := loadChecksumPolicy(&packages.Config{Context: ctx, Dir: dir, Env: goEnviron(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range modules {
+		policy.apply(m)
+	}
+	return modules, nil
+}
+
+// moduleLibraries returns the library of each of modules, see ModuleLibrary, and of their
+// sub-directories with licenses of their own, sorted by name. Modules other than the main module matching the ignore rules of opts are left
+// out. Libraries are direct if the go.mod file of the main module requires their module
+// without an // indirect comment.
+func moduleLibraries(classifier Classifier, opts Options, modules []*Module) ([]*Library, error) {
+	var direct map[string]GoModLocation
+	for _, m := range modules {
+		if m.Main && m.Dir != "" {
+			var err error
+			if direct, err = directRequirements(filepath.Join(m.Dir, "go.mod")); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	var libraries []*Library
+	rules := opts.ignoreRules()
+	for _, m := range modules {
+		if !m.Main && ignoredModule(m.Path, rules) {
+			continue
+		}
+		lib := ModuleLibrary(classifier, opts, m)
+		required := m.Path
+		if m.Replaces != nil {
+			required = m.Replaces.Path
+		}
+		if at, ok := direct[required]; ok && !m.Main {
+			lib.Direct = true
+			lib.RequiredAt = &at
+		}
+		libraries = append(libraries, lib)
+		for _, sub := range subdirectoryLibraries(classifier, opts, m, lib) {
+			sub.Direct = lib.Direct
+			sub.RequiredAt = lib.RequiredAt
+			libraries = append(libraries, sub)
+		}
+	}
+	sort.Slice(libraries, func(i, j int) bool {
+		return libraries[i].Name() < libraries[j].Name()
+	})
+	return libraries, nil
+}
+
+// assetModuleLibraries returns the library of each module that the go.mod file of the
+// main module of rootPkgs requires directly, but that none of libraries belongs to, see
+// Options.IncludeAssetModules. Like in ModuleListLibraries, versions are those of the
+// build list and ignore rules match module paths.
+func assetModuleLibraries(ctx context.Context, classifier Classifier, opts Options, libraries []*Library, rootPkgs []*packages.Package) ([]*Library, error) {
+	var main *packages.Module
+	for _, p := range rootPkgs {
+		if p.Module != nil && p.Module.Main && p.Module.GoMod != "" {
+			main = p.Module
+			break
+		}
+	}
+	if main == nil {
+		return nil, nil
+	}
+	direct, err := directRequirements(main.GoMod)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]bool)
+	for _, lib := range libraries {
+		if lib.module != nil {
+			loaded[lib.module.Path] = true
+		}
+	}
+	modules, err := ListModules(ctx, filepath.Dir(main.GoMod))
+	if err != nil {
+		return nil, err
+	}
+	var assetLibs []*Library
+	rules := opts.ignoreRules()
+	for _, m := range modules {
+		required := m.Path
+		if m.Replaces != nil {
+			required = m.Replaces.Path
+		}
+		at, ok := direct[required]
+		if m.Main || !ok || loaded[m.Path] || ignoredModule(m.Path, rules) {
+			continue
+		}
+		if m.Dir == "" {
+			warnf(ctx, WarningNotInModuleCache, m.Path, "Module %s@%s is not in the module cache, run \"go mod download\" to find its license", m.Path, m.Version)
+		}
+		lib := ModuleLibrary(classifier, opts, m)
+		lib.Direct = true
+		lib.RequiredAt = &at
+		lib.ModuleLevel = true
+		assetLibs = append(assetLibs, lib)
+	}
+	return assetLibs, nil
+}
+
+// GoModLocation is the position of a directive in a go.mod file.
+type GoModLocation struct {
+	// File is the path of the go.mod file.
+	File string `json:"file"`
+	// Line is the 1-based line of the directive.
+	Line int `json:"line"`
+}
+
+// requirement is a require directive of a go.mod file.
+type requirement struct {
+	at       GoModLocation
+	indirect bool
+}
+
+// requirements returns the require directive of each module that the go.mod file at
+// goMod requires, by module path.
+func requirements(goMod string) (map[string]requirement, error) {
+	b, err := os.ReadFile(goMod)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.ParseLax(goMod, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make(map[string]requirement)
+	for _, r := range f.Require {
+		reqs[r.Mod.Path] = requirement{
+			at:       GoModLocation{File: goMod, Line: r.Syntax.Start.Line},
+			indirect: r.Indirect,
+		}
+	}
+	return reqs, nil
+}
+
+// directRequirements returns the location of the require directive of each module that
+// the go.mod file at goMod requires directly, i.e. without an // indirect comment, by
+// module path.
+func directRequirements(goMod string) (map[string]GoModLocation, error) {
+	reqs, err := requirements(goMod)
+	if err != nil {
+		return nil, err
+	}
+	direct := make(map[string]GoModLocation)
+	for path, r := range reqs {
+		if !r.indirect {
+			direct[path] = r.at
+		}
+	}
+	return direct, nil
+}
+
+// goSumContents returns the modules whose content is listed in the go.sum file data, as
+// path@version without +incompatible suffix. Malformed lines are skipped.
+func goSumContents(data []byte) map[string]bool {
+	contents := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		contents[fields[0]+"@"+strings.TrimSuffix(fields[1], "+incompatible")] = true
+	}
+	return contents
+}
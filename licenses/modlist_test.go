@@ -0,0 +1,141 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestModuleListLibraries(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"../testdata/modules/cli02/LICENSE": "Apache-2.0",
+		},
+		licenseTypes: map[string]Type{
+			"../testdata/modules/cli02/LICENSE": Notice,
+		},
+	}
+	opts := Options{IgnoreRules: []IgnoreRule{{Prefix: "golang.org/x/", Mode: IgnoreHide}}}
+	libs, err := ModuleListLibraries(context.Background(), classifier, opts, "../testdata/modules/cli02")
+	if err != nil {
+		t.Fatalf("ModuleListLibraries() = (_, %q), want (_, nil)", err)
+	}
+	versions := make(map[string]string)
+	direct := make(map[string]bool)
+	for _, lib := range libs {
+		versions[lib.Name()] = lib.Version()
+		direct[lib.Name()] = lib.Direct
+		if lib.Name() == "github.com/nilsbeck/go-licenses/testdata/modules/cli02" {
+			if want := "../testdata/modules/cli02/LICENSE"; !sameFile(t, lib.LicensePath, want) {
+				t.Errorf("main module LicensePath = %q, want %q", lib.LicensePath, want)
+			}
+		}
+	}
+	for name, want := range map[string]string{
+		"github.com/nilsbeck/go-licenses/testdata/modules/cli02": "",
+		"github.com/spf13/cobra":                                 "v1.1.3",
+		"github.com/spf13/viper":                                 "v1.8.0",
+	} {
+		if got, ok := versions[name]; !ok || got != want {
+			t.Errorf("library %s has version %q (found: %v), want %q", name, got, ok, want)
+		}
+	}
+	for name, want := range map[string]bool{
+		"github.com/nilsbeck/go-licenses/testdata/modules/cli02": false,
+		"github.com/spf13/cobra":                                 true,
+		"github.com/spf13/pflag":                                 false,
+	} {
+		if direct[name] != want {
+			t.Errorf("library %s has Direct = %v, want %v", name, direct[name], want)
+		}
+	}
+	if _, ok := versions["golang.org/x/text"]; ok {
+		t.Errorf("ignored module golang.org/x/text was returned")
+	}
+}
+
+func TestDirectRequirements(t *testing.T) {
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	data := `module example.com/main
+
+go 1.17
+
+require example.com/single v1.0.0
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0 // indirect
+	example.com/c v1.0.0
+)
+`
+	if err := os.WriteFile(goMod, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := directRequirements(goMod)
+	if err != nil {
+		t.Fatalf("directRequirements() = (_, %q), want (_, nil)", err)
+	}
+	want := map[string]GoModLocation{
+		"example.com/single": {File: goMod, Line: 5},
+		"example.com/a":      {File: goMod, Line: 8},
+		"example.com/c":      {File: goMod, Line: 10},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("directRequirements(): (-want +got):\n%s", diff)
+	}
+}
+
+func TestListModulesNotAModule(t *testing.T) {
+	if _, err := ListModules(context.Background(), t.TempDir()); err == nil {
+		t.Errorf("ListModules() of a directory outside a module = (_, nil), want error")
+	}
+}
+
+func TestAssetModuleLibraries(t *testing.T) {
+	cfg := &packages.Config{Dir: "../testdata/modules/cli02", Mode: packages.NeedName | packages.NeedModule}
+	rootPkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		t.Fatalf("packages.Load() = (_, %q), want (_, nil)", err)
+	}
+	// Pretend that only packages of cobra were loaded.
+	loaded := []*Library{{Packages: []string{"github.com/spf13/cobra"}, module: &Module{Path: "github.com/spf13/cobra"}}}
+	libs, err := assetModuleLibraries(context.Background(), classifierStub{}, Options{}, loaded, rootPkgs)
+	if err != nil {
+		t.Fatalf("assetModuleLibraries() = (_, %q), want (_, nil)", err)
+	}
+	versions := make(map[string]string)
+	for _, lib := range libs {
+		versions[lib.Name()] = lib.Version()
+		if !lib.ModuleLevel || !lib.Direct {
+			t.Errorf("library %s has ModuleLevel %v and Direct %v, want both true", lib.Name(), lib.ModuleLevel, lib.Direct)
+		}
+		if lib.RequiredAt == nil || filepath.Base(lib.RequiredAt.File) != "go.mod" {
+			t.Errorf("library %s has RequiredAt %v, want a line of go.mod", lib.Name(), lib.RequiredAt)
+		}
+	}
+	want := map[string]string{
+		"github.com/mitchellh/go-homedir": "v1.1.0",
+		"github.com/spf13/viper":          "v1.8.0",
+	}
+	if diff := cmp.Diff(want, versions); diff != "" {
+		t.Errorf("assetModuleLibraries(): (-want +got):\n%s", diff)
+	}
+}
@@ -26,10 +26,31 @@ type Module struct {
 	// * Replace field is removed, it's only an implementation detail in this package.
 	//   If a module is replaced, we'll directly return the replaced module.
 	// * Version field +incompatible suffix is trimmed.
-	// * Main, ModuleError, Time, Indirect, GoMod, GoVersion fields are removed, because they are not used.
+	// * ModuleError, Time, Indirect, GoMod, GoVersion fields are removed, because they are not used.
 	Path    string // module path
 	Version string // module version
 	Dir     string // directory holding files for this module, if any
+	Main    bool   // is this the main module?
+	// ChecksumVerified is true if the go command verifies the module's content against
+	// the checksum database, i.e. it is a downloaded module that is not excluded by
+	// GOSUMDB=off, GONOSUMDB or GOPRIVATE.
+	ChecksumVerified bool
+	// ChecksumDB is the name of the checksum database that verifies the module, e.g.
+	// sum.golang.org, if ChecksumVerified.
+	ChecksumDB string
+	// ChecksumExclusion is why the module is not verified, one of the Exclusion
+	// constants, e.g. ExclusionNoSumDB if it matches GONOSUMDB. It is empty if
+	// ChecksumVerified.
+	ChecksumExclusion string
+	// Replaces is the module that this one replaces via a replace directive, if any.
+	// Only its Path and Version are set.
+	Replaces *Module
+}
+
+// IsFork returns true if m replaces a module with a different path, e.g. a patched fork,
+// or with a local directory.
+func (m *Module) IsFork() bool {
+	return m.Replaces != nil && (m.Replaces.Path != m.Path || m.Version == "")
 }
 
 func newModule(mod *packages.Module) *Module {
@@ -55,15 +76,19 @@ func newModule(mod *packages.Module) *Module {
 	// Haven't confirmed, but we may also need to override the
 	// entire struct when using replace directive with local folders.
 	tmp := *mod
+	var replaces *Module
 	if tmp.Replace != nil {
 		tmp = *tmp.Replace
+		replaces = &Module{Path: mod.Path, Version: strings.TrimSuffix(mod.Version, "+incompatible")}
 	}
 	// The +incompatible suffix does not affect module version.
 	// ref: https://golang.org/ref/mod#incompatible-versions
 	tmp.Version = strings.TrimSuffix(tmp.Version, "+incompatible")
 	return &Module{
-		Path:    tmp.Path,
-		Version: tmp.Version,
-		Dir:     tmp.Dir,
+		Path:     tmp.Path,
+		Version:  tmp.Version,
+		Dir:      tmp.Dir,
+		Main:     mod.Main,
+		Replaces: replaces,
 	}
 }
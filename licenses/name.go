@@ -0,0 +1,64 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NameStyle configures how DisplayName shortens import paths.
+type NameStyle struct {
+	// StripHost removes the host, e.g. "github.com/", from the start of the path.
+	StripHost bool
+	// StripMajorVersion removes the major version suffix of the module, e.g. "/v2", or
+	// ".v2" for gopkg.in modules.
+	StripMajorVersion bool
+}
+
+var (
+	majorVersionRegexp      = regexp.MustCompile(`/v(?:[2-9]|[1-9][0-9]+)$`)
+	gopkgMajorVersionRegexp = regexp.MustCompile(`\.v[0-9]+$`)
+)
+
+// DisplayName returns a shorter name for display of importPath, a path in the module
+// with path modulePath, according to style. modulePath may be empty if unknown. The
+// zero style returns importPath unchanged.
+func DisplayName(importPath, modulePath string, style NameStyle) string {
+	name := importPath
+	if style.StripMajorVersion && modulePath != "" && (name == modulePath || strings.HasPrefix(name, modulePath+"/")) {
+		trimmed := majorVersionRegexp.ReplaceAllString(modulePath, "")
+		if strings.HasPrefix(modulePath, "gopkg.in/") {
+			trimmed = gopkgMajorVersionRegexp.ReplaceAllString(trimmed, "")
+		}
+		name = trimmed + strings.TrimPrefix(name, modulePath)
+	}
+	if style.StripHost {
+		// Like the go command, treat a first path element with a dot as a host name.
+		if i := strings.Index(name, "/"); i > 0 && strings.Contains(name[:i], ".") {
+			name = name[i+1:]
+		}
+	}
+	return name
+}
+
+// DisplayName returns the name of the library shortened according to style.
+func (l *Library) DisplayName(style NameStyle) string {
+	var modulePath string
+	if l.module != nil {
+		modulePath = l.module.Path
+	}
+	return DisplayName(l.Name(), modulePath, style)
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestDisplayName(t *testing.T) {
+	both := NameStyle{StripHost: true, StripMajorVersion: true}
+	for _, test := range []struct {
+		importPath string
+		modulePath string
+		style      NameStyle
+		want       string
+	}{
+		{"github.com/spf13/cobra", "github.com/spf13/cobra", NameStyle{}, "github.com/spf13/cobra"},
+		{"github.com/spf13/cobra", "github.com/spf13/cobra", NameStyle{StripHost: true}, "spf13/cobra"},
+		{"golang.org/x/sys/unix", "golang.org/x/sys", NameStyle{StripHost: true}, "x/sys/unix"},
+		{"github.com/go-logr/logr/v2/funcr", "github.com/go-logr/logr/v2", NameStyle{StripMajorVersion: true}, "github.com/go-logr/logr/funcr"},
+		{"github.com/go-logr/logr/v2", "github.com/go-logr/logr/v2", both, "go-logr/logr"},
+		{"gopkg.in/yaml.v3", "gopkg.in/yaml.v3", both, "yaml"},
+		{"k8s.io/klog/v2", "k8s.io/klog/v2", NameStyle{StripHost: true}, "klog/v2"},
+		{"github.com/foo/v1", "github.com/foo/v1", NameStyle{StripMajorVersion: true}, "github.com/foo/v1"},
+		{"github.com/foo/v10", "github.com/foo/v10", NameStyle{StripMajorVersion: true}, "github.com/foo"},
+		{"mymodule/pkg/v2", "mymodule", both, "mymodule/pkg/v2"},
+	} {
+		if got := DisplayName(test.importPath, test.modulePath, test.style); got != test.want {
+			t.Errorf("DisplayName(%q, %q, %+v) = %q, want %q", test.importPath, test.modulePath, test.style, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	noticeRegexp = regexp.MustCompile(`^NOTICE(\.(txt|md))?$`)
+	// termsRegexp matches the names of files with license terms, e.g. LICENSE-MIT,
+	// COPYING or PATENTS.
+	termsRegexp = regexp.MustCompile(`^(?i)((UN)?LICEN(S|C)E|COPYING|PATENTS)([-_.][\w.-]*)?$`)
+)
+
+// IsNoticeFile returns true if name is the file name of a NOTICE file, whose content
+// e.g. the Apache License 2.0 requires to be reproduced along with the license.
+func IsNoticeFile(name string) bool {
+	return noticeRegexp.MatchString(name)
+}
+
+// findNotice returns the path of the NOTICE file next to the license file at
+// licensePath, or "" if there is none. A NOTICE file that is the license file itself
+// doesn't count.
+func findNotice(licensePath string) string {
+	if licensePath == "" {
+		return ""
+	}
+	dir := filepath.Dir(licensePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() && IsNoticeFile(e.Name()) && e.Name() != filepath.Base(licensePath) {
+			return filepath.Join(dir, e.Name())
+		}
+	}
+	return ""
+}
+
+// isLicenseFile reports whether name is the file name of a NOTICE file or of a file with
+// license terms, e.g. LICENSE-MIT, COPYING or PATENTS, rather than of code like license.go.
+func isLicenseFile(name string) bool {
+	return IsNoticeFile(name) || termsRegexp.MatchString(name) && !sourceExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// sourceExts are the extensions of source files, which may be named like license files.
+var sourceExts = map[string]bool{".go": true, ".s": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".py": true, ".js": true, ".ts": true, ".sh": true}
+
+// thirdPartyDirs are the names of the directories that bundle code of other projects.
+var thirdPartyDirs = map[string]bool{"third_party": true, "third-party": true, "thirdparty": true}
+
+// findLicenseFiles returns the license file at licensePath followed by the files whose
+// terms apply along with it: NOTICE, PATENTS, COPYING and further license files next to
+// it and the license and NOTICE files in its third_party directories, except in
+// directories with Go files, whose packages are libraries of their own.
+func findLicenseFiles(licensePath string) []string {
+	if licensePath == "" {
+		return nil
+	}
+	files := []string{licensePath}
+	dir := filepath.Dir(licensePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir() && thirdPartyDirs[e.Name()]:
+			files = append(files, findThirdPartyLicenseFiles(path)...)
+		case !e.IsDir() && isLicenseFile(e.Name()) && path != licensePath:
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// findThirdPartyLicenseFiles returns the license and NOTICE files below root in
+// directories without Go files.
+func findThirdPartyLicenseFiles(root string) []string {
+	var files []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && (d.Name() == "testdata" || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil
+		}
+		var found []string
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			if filepath.Ext(e.Name()) == ".go" {
+				return nil
+			}
+			if isLicenseFile(e.Name()) {
+				found = append(found, filepath.Join(path, e.Name()))
+			}
+		}
+		files = append(files, found...)
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindNotice(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		files []string
+		want  string
+	}{
+		{
+			desc:  "NOTICE next to LICENSE",
+			files: []string{"LICENSE", "NOTICE"},
+			want:  "NOTICE",
+		},
+		{
+			desc:  "NOTICE.txt next to LICENSE",
+			files: []string{"LICENSE", "NOTICE.txt", "NOTICES.txt"},
+			want:  "NOTICE.txt",
+		},
+		{
+			desc:  "No NOTICE",
+			files: []string{"LICENSE", "README.md"},
+			want:  "",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, f), []byte(f), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := test.want
+			if want != "" {
+				want = filepath.Join(dir, want)
+			}
+			if got := findNotice(filepath.Join(dir, "LICENSE")); got != want {
+				t.Errorf("findNotice() = %q, want %q", got, want)
+			}
+		})
+	}
+	t.Run("NOTICE is the license file", func(t *testing.T) {
+		if got := findNotice("testdata/notice/NOTICE.txt"); got != "" {
+			t.Errorf("findNotice() = %q, want %q", got, "")
+		}
+	})
+}
+
+func TestFindLicenseFiles(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		files []string
+		want  []string
+	}{
+		{
+			desc:  "License file only",
+			files: []string{"LICENSE", "README.md", "main.go"},
+			want:  []string{"LICENSE"},
+		},
+		{
+			desc:  "Terms next to the license file",
+			files: []string{"LICENSE", "NOTICE", "PATENTS", "COPYING.LESSER", "LICENSE-MIT", "license.go"},
+			want:  []string{"LICENSE", "COPYING.LESSER", "LICENSE-MIT", "NOTICE", "PATENTS"},
+		},
+		{
+			desc: "Licenses of bundled code",
+			files: []string{
+				"LICENSE",
+				"third_party/zlib/LICENSE",
+				"third_party/zlib/zlib.c",
+				"third_party/apache/NOTICE.txt",
+				"third_party/gopkg/LICENSE",
+				"third_party/gopkg/gopkg.go",
+				"third_party/testdata/LICENSE",
+			},
+			want: []string{"LICENSE", "third_party/apache/NOTICE.txt", "third_party/zlib/LICENSE"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range test.files {
+				path := filepath.Join(dir, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(f), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var want []string
+			for _, f := range test.want {
+				want = append(want, filepath.Join(dir, filepath.FromSlash(f)))
+			}
+			if got := findLicenseFiles(filepath.Join(dir, "LICENSE")); !reflect.DeepEqual(got, want) {
+				t.Errorf("findLicenseFiles() = %q, want %q", got, want)
+			}
+		})
+	}
+}
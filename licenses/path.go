@@ -0,0 +1,74 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// caseInsensitivePaths is true on platforms whose filesystems usually compare paths
+// case-insensitively, where e.g. "C:\Users" and "c:\users" denote the same directory.
+var caseInsensitivePaths = runtime.GOOS == "windows"
+
+// isWithinDir reports whether path is dir or located inside of dir.
+func isWithinDir(dir, path string) bool {
+	return withinDir(dir, path, caseInsensitivePaths)
+}
+
+func withinDir(dir, path string, foldCase bool) bool {
+	dir, path = filepath.Clean(dir), filepath.Clean(path)
+	if len(path) < len(dir) {
+		return false
+	}
+	if prefix := path[:len(dir)]; prefix != dir && !(foldCase && strings.EqualFold(prefix, dir)) {
+		return false
+	}
+	// Make sure that e.g. /foo/barbaz is not considered to be inside of /foo/bar.
+	return len(path) == len(dir) || os.IsPathSeparator(path[len(dir)]) || os.IsPathSeparator(dir[len(dir)-1])
+}
+
+// samePath reports whether a and b denote the same path.
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	return a == b || caseInsensitivePaths && strings.EqualFold(a, b)
+}
+
+// relSlashPath returns the slash-separated path of target relative to base, suitable for
+// use in URLs.
+func relSlashPath(base, target string) (string, error) {
+	return relSlash(base, target, caseInsensitivePaths)
+}
+
+func relSlash(base, target string, foldCase bool) (string, error) {
+	base, target = filepath.Clean(base), filepath.Clean(target)
+	if withinDir(base, target, foldCase) {
+		// Strip the prefix directly, filepath.Rel would fail on case differences.
+		rel := strings.TrimLeftFunc(target[len(base):], func(r rune) bool {
+			return r < 128 && os.IsPathSeparator(uint8(r))
+		})
+		if rel == "" {
+			rel = "."
+		}
+		return filepath.ToSlash(rel), nil
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
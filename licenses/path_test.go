@@ -0,0 +1,57 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestWithinDir(t *testing.T) {
+	for _, test := range []struct {
+		dir, path string
+		foldCase  bool
+		want      bool
+	}{
+		{dir: "/a/b", path: "/a/b", want: true},
+		{dir: "/a/b", path: "/a/b/c", want: true},
+		{dir: "/a/b/", path: "/a/b/c", want: true},
+		{dir: "/", path: "/a", want: true},
+		{dir: "/a/b", path: "/a/bc", want: false},
+		{dir: "/a/b", path: "/a", want: false},
+		{dir: "/A/b", path: "/a/B/c", want: false},
+		{dir: "/A/b", path: "/a/B/c", foldCase: true, want: true},
+	} {
+		if got := withinDir(test.dir, test.path, test.foldCase); got != test.want {
+			t.Errorf("withinDir(%q, %q, %t) = %t, want %t", test.dir, test.path, test.foldCase, got, test.want)
+		}
+	}
+}
+
+func TestRelSlash(t *testing.T) {
+	for _, test := range []struct {
+		base, target string
+		foldCase     bool
+		want         string
+	}{
+		{base: "/mod", target: "/mod/LICENSE", want: "LICENSE"},
+		{base: "/mod", target: "/mod/sub/LICENSE", want: "sub/LICENSE"},
+		{base: "/mod", target: "/mod", want: "."},
+		{base: "/Mod", target: "/mod/sub/LICENSE", foldCase: true, want: "sub/LICENSE"},
+		{base: "/mod/sub", target: "/mod/LICENSE", want: "../LICENSE"},
+	} {
+		got, err := relSlash(test.base, test.target, test.foldCase)
+		if err != nil || got != test.want {
+			t.Errorf("relSlash(%q, %q, %t) = (%q, %v), want (%q, nil)", test.base, test.target, test.foldCase, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/derrors"
+	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/source"
+	"golang.org/x/mod/module"
+)
+
+// SourceRepo is where the source files of a module version can be viewed.
+type SourceRepo interface {
+	// FileURL returns the URL of the file at pathname, which is slash-separated and
+	// relative to the module root.
+	FileURL(pathname string) string
+}
+
+// SourceResolver finds where the source files of modules can be viewed, e.g. on the
+// code host of their repository or in an internal source browser.
+type SourceResolver interface {
+	// ModuleInfo returns the repository of module modulePath at version. version is
+	// empty for modules without a version, e.g. the main module.
+	ModuleInfo(ctx context.Context, modulePath, version string) (SourceRepo, error)
+}
+
+// NewPkgsiteResolver returns the default SourceResolver, which maps module paths to
+// repositories and versions to tags like pkg.go.dev does. Modules without a version are
+// mapped to the default branch. Requests to look up vanity import paths time out after
+// timeout, or never if it is 0, e.g. because the transport times out requests itself.
+// Each module version is looked up once for the life of the resolver. Modules on the
+// hosts of rules are mapped with them before the well-known code hosts.
+func NewPkgsiteResolver(timeout time.Duration, rules ...HostRule) SourceResolver {
+	return newPkgsiteResolver(source.NewClient(timeout), rules)
+}
+
+// NewPkgsiteResolverWithClient is like NewPkgsiteResolver, but makes its requests with
+// client, e.g. to authenticate them or retry failures, rather than with a client of its
+// own that uses http.DefaultTransport.
+func NewPkgsiteResolverWithClient(client *http.Client, rules ...HostRule) SourceResolver {
+	return newPkgsiteResolver(source.NewClientWithHTTPClient(client), rules)
+}
+
+// HostRule maps the modules on a code host that go-licenses doesn't know, e.g. a
+// self-hosted GitLab, Gitea or Azure DevOps instance, to the URLs of their files.
+type HostRule struct {
+	// Host is the host name of the module paths or repository URLs, e.g. git.example.com.
+	Host string `json:"host"`
+	// RepoDepth is the number of path elements after Host that name a repository, e.g. 2
+	// for git.example.com/group/project. If it is 0, the repository is looked up in the
+	// go-import meta tag of the module path, which takes a request to the code host.
+	RepoDepth int `json:"repoDepth,omitempty"`
+	// Kind is the code hosting software: azure, bitbucket, gitea, github, gitlab or gogs.
+	Kind string `json:"kind,omitempty"`
+	// File, if set, overrides the URL template of Kind for files, e.g.
+	// "{repo}/browse/{file}?at={commit}". {repo} is the repository URL, {commit} the tag or
+	// commit ID of the module version and {file} the path relative to the repository root.
+	File string `json:"file,omitempty"`
+	// Raw, if set, overrides the URL template of Kind for the raw contents of files, with
+	// the same placeholders as File, e.g. "{repo}/raw/{file}?at={commit}".
+	Raw string `json:"raw,omitempty"`
+}
+
+// Validate reports whether r has a host and either a known Kind or a File template.
+func (r HostRule) Validate() error {
+	return source.HostRule{Host: r.Host, RepoDepth: r.RepoDepth, Kind: r.Kind, File: r.File, Raw: r.Raw}.Validate()
+}
+
+func sourceHostRules(rules []HostRule) []source.HostRule {
+	var converted []source.HostRule
+	for _, r := range rules {
+		converted = append(converted, source.HostRule{Host: r.Host, RepoDepth: r.RepoDepth, Kind: r.Kind, File: r.File, Raw: r.Raw})
+	}
+	return converted
+}
+
+// defaultResolver is used by libraries without Options.SourceResolver.
+var defaultResolver = NewPkgsiteResolver(time.Second * 20)
+
+// pkgsiteResolver memoizes module infos by module path and version, since every file URL
+// of a library, e.g. of its license and notice files, needs the info of its module, and
+// looking up vanity import paths takes HTTP requests.
+type pkgsiteResolver struct {
+	client *source.Client
+	// fingerprint identifies the host rules that URLs depend on in Cache.
+	fingerprint string
+
+	mu    sync.Mutex
+	infos map[string]*moduleInfoCall
+}
+
+// moduleInfoCall is a lookup of a module info, which callers asking for the same module
+// version while it is in flight wait for.
+type moduleInfoCall struct {
+	done chan struct{}
+	info *source.Info
+	err  error
+	// canceled is set if the caller that made the lookup gave up, so that its error is
+	// no answer for the others.
+	canceled bool
+}
+
+// newPkgsiteResolver returns a pkgsiteResolver that maps modules with rules before the
+// well-known code hosts and makes its requests with client.
+func newPkgsiteResolver(client *source.Client, rules []HostRule) *pkgsiteResolver {
+	return &pkgsiteResolver{
+		client:      client.WithHostRules(sourceHostRules(rules)),
+		fingerprint: hostRulesFingerprint(rules),
+		infos:       make(map[string]*moduleInfoCall),
+	}
+}
+
+// hostRulesFingerprint identifies rules, in order, since the first matching rule applies.
+// Raw templates are left out, since only the URLs of viewing files are cached.
+func hostRulesFingerprint(rules []HostRule) string {
+	h := sha256.New()
+	for _, r := range rules {
+		fmt.Fprintf(h, "%q %d %q %q\n", r.Host, r.RepoDepth, r.Kind, r.File)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+func (r *pkgsiteResolver) ModuleInfo(ctx context.Context, modulePath, version string) (SourceRepo, error) {
+	return r.info(ctx, modulePath, version)
+}
+
+// info returns the module info of modulePath at version. Module infos and modules that
+// don't exist are remembered, other failures, e.g. timeouts, are looked up again by
+// later callers.
+func (r *pkgsiteResolver) info(ctx context.Context, modulePath, version string) (*source.Info, error) {
+	key := modulePath + "@" + version
+	for {
+		r.mu.Lock()
+		call, ok := r.infos[key]
+		if !ok {
+			call = &moduleInfoCall{done: make(chan struct{})}
+			r.infos[key] = call
+		}
+		r.mu.Unlock()
+		if !ok {
+			call.info, call.err = r.lookup(ctx, modulePath, version)
+			if call.err != nil && !errors.Is(call.err, derrors.NotFound) {
+				call.canceled = ctx.Err() != nil
+				r.mu.Lock()
+				delete(r.infos, key)
+				r.mu.Unlock()
+			}
+			close(call.done)
+			return call.info, call.err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+		if !call.canceled {
+			return call.info, call.err
+		}
+		// Look the module up with ctx instead.
+	}
+}
+
+func (r *pkgsiteResolver) lookup(ctx context.Context, modulePath, version string) (*source.Info, error) {
+	info, err := source.ModuleInfo(ctx, r.client, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		// Note#1 if we pass version=HEAD to source.ModuleInfo, github tag for modules not at the root
+		// of the repo will be incorrect, because there's a convention that:
+		// * I have a module at github.com/nilsbeck/go-licenses/submod.
+		// * The module is of version v1.0.0.
+		// Then the github tag should be submod/v1.0.0.
+		// In our case, if we pass HEAD as version, the result commit will be submod/HEAD which is incorrect.
+		// Therefore, to workaround this problem, we directly set the commit after getting module info.
+		//
+		// Note#2 repos have different branches as default, some use the
+		// master branch and some use the main branch. However, HEAD
+		// always refers to the default branch, so it's better than
+		// both of master/main when we do not know which branch is default.
+		// Examples:
+		// * https://github.com/nilsbeck/go-licenses/blob/HEAD/LICENSE
+		// points to latest commit of master branch.
+		// * https://github.com/google/licenseclassifier/blob/HEAD/LICENSE
+		// points to latest commit of main branch.
+		info.SetCommit("HEAD")
+		warnf(ctx, WarningEmptyVersion, modulePath, "module %s has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!", modulePath)
+	}
+	return info, nil
+}
+
+// NewOfflineResolver returns a SourceResolver that makes no network requests, for
+// machines without internet access. Modules are mapped to repositories by the static host
+// rules of NewPkgsiteResolver, or by the repository and revision that the go command
+// recorded in the module cache at modCache when it downloaded the module, e.g. for vanity
+// import paths. Other modules fail to resolve, except for modules on the hosts of rules
+// with a RepoDepth.
+func NewOfflineResolver(modCache string, rules ...HostRule) SourceResolver {
+	return offlineResolver{client: source.NewOfflineClient().WithHostRules(sourceHostRules(rules)), modCache: modCache}
+}
+
+type offlineResolver struct {
+	client   *source.Client
+	modCache string
+}
+
+// moduleOrigin is where the go command downloaded a module version from, as recorded in
+// the module cache for modules downloaded directly from their repository or from a proxy
+// that serves it.
+type moduleOrigin struct {
+	VCS    string
+	URL    string
+	Subdir string
+	Hash   string
+	Ref    string
+}
+
+func (r offlineResolver) ModuleInfo(ctx context.Context, modulePath, version string) (SourceRepo, error) {
+	repoPath := modulePath
+	origin, ok := r.origin(modulePath, version)
+	if ok {
+		repoPath = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(origin.URL, "https://"), "http://"), ".git")
+		if origin.Subdir != "" {
+			repoPath += "/" + origin.Subdir
+		}
+	}
+	info, err := source.ModuleInfo(ctx, r.client, repoPath, version)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("no static host rule matches module %s and the module cache records no repository for it, so its URL can't be determined offline", modulePath)
+	}
+	switch {
+	case ok && strings.HasPrefix(origin.Ref, "refs/tags/"):
+		info.SetCommit(strings.TrimPrefix(origin.Ref, "refs/tags/"))
+	case ok && origin.Hash != "":
+		info.SetCommit(origin.Hash)
+	case version == "":
+		// See pkgsiteResolver.lookup.
+		info.SetCommit("HEAD")
+		warnf(ctx, WarningEmptyVersion, modulePath, "module %s has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!", modulePath)
+	}
+	return info, nil
+}
+
+// origin returns the origin of module modulePath at version recorded in the module cache.
+func (r offlineResolver) origin(modulePath, version string) (moduleOrigin, bool) {
+	if r.modCache == "" || version == "" {
+		return moduleOrigin{}, false
+	}
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return moduleOrigin{}, false
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return moduleOrigin{}, false
+	}
+	b, err := os.ReadFile(filepath.Join(r.modCache, "cache", "download", filepath.FromSlash(escapedPath), "@v", escapedVersion+".info"))
+	if err != nil {
+		return moduleOrigin{}, false
+	}
+	var info struct {
+		Origin *moduleOrigin
+	}
+	if err := json.Unmarshal(b, &info); err != nil || info.Origin == nil || info.Origin.URL == "" {
+		return moduleOrigin{}, false
+	}
+	return *info.Origin, true
+}
+
+// NewSourcegraphResolver returns a SourceResolver that links files on the Sourcegraph
+// instance at instanceURL, e.g. https://sg.example.com/github.com/foo/bar@v1.2.3/-/blob/LICENSE.
+// Repositories and revisions are found like NewPkgsiteResolver does with rules.
+func NewSourcegraphResolver(instanceURL string, timeout time.Duration, rules ...HostRule) SourceResolver {
+	return sourcegraphResolver{
+		instanceURL: strings.TrimSuffix(instanceURL, "/"),
+		pkgsite:     newPkgsiteResolver(source.NewClient(timeout), rules),
+	}
+}
+
+// NewSourcegraphResolverWithClient is like NewSourcegraphResolver, but makes its requests
+// with client, like NewPkgsiteResolverWithClient.
+func NewSourcegraphResolverWithClient(instanceURL string, client *http.Client, rules ...HostRule) SourceResolver {
+	return sourcegraphResolver{
+		instanceURL: strings.TrimSuffix(instanceURL, "/"),
+		pkgsite:     newPkgsiteResolver(source.NewClientWithHTTPClient(client), rules),
+	}
+}
+
+type sourcegraphResolver struct {
+	instanceURL string
+	pkgsite     *pkgsiteResolver
+}
+
+func (r sourcegraphResolver) ModuleInfo(ctx context.Context, modulePath, version string) (SourceRepo, error) {
+	info, err := r.pkgsite.info(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return sourcegraphRepo{
+		url:       r.instanceURL + "/" + sourcegraphRepoName(info.Repo()) + "@" + info.Commit(),
+		moduleDir: info.ModuleDir(),
+	}, nil
+}
+
+// sourcegraphRepoName returns the name of the repository at repoURL on Sourcegraph, which
+// mirrors the Go project's repositories from GitHub rather than from
+// cs.opensource.google.
+func sourcegraphRepoName(repoURL string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(repoURL, "https://"), "http://")
+	if goRepo := strings.TrimPrefix(name, "cs.opensource.google/go/"); goRepo != name {
+		return "github.com/golang/" + strings.TrimPrefix(goRepo, "x/")
+	}
+	return name
+}
+
+type sourcegraphRepo struct {
+	url       string
+	moduleDir string
+}
+
+func (r sourcegraphRepo) FileURL(pathname string) string {
+	return r.url + "/-/blob/" + path.Join(r.moduleDir, pathname)
+}
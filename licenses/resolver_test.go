@@ -0,0 +1,376 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/derrors"
+	"github.com/nilsbeck/go-licenses/internal/third_party/pkgsite/source"
+)
+
+type sourcegraphStub struct{}
+
+func (sourcegraphStub) ModuleInfo(_ context.Context, modulePath, version string) (SourceRepo, error) {
+	if version == "" {
+		version = "HEAD"
+	}
+	return sourceRepoStub("https://sg.example.com/" + modulePath + "@" + version + "/-/blob"), nil
+}
+
+type sourceRepoStub string
+
+func (r sourceRepoStub) FileURL(pathname string) string {
+	return string(r) + "/" + path.Clean(pathname)
+}
+
+func TestLibraryFileURLWithResolver(t *testing.T) {
+	lib := &Library{
+		Packages:    []string{"corp.example.com/lib"},
+		LicensePath: "/go/src/corp.example.com/lib/LICENSE",
+		module: &Module{
+			Path:    "corp.example.com/lib",
+			Dir:     "/go/src/corp.example.com/lib",
+			Version: "v1.2.3",
+		},
+		resolver: sourcegraphStub{},
+	}
+	got, err := lib.FileURL(context.Background(), "/go/src/corp.example.com/lib/LICENSE")
+	if err != nil {
+		t.Fatalf("FileURL() = (_, %q), want (_, nil)", err)
+	}
+	if want := "https://sg.example.com/corp.example.com/lib@v1.2.3/-/blob/LICENSE"; got != want {
+		t.Errorf("FileURL() = %q, want %q", got, want)
+	}
+}
+
+func TestModuleFileURL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     Options
+		module   string
+		version  string
+		pathname string
+		want     string
+		wantErr  bool
+	}{{
+		name:     "NOTICE with resolver",
+		opts:     Options{SourceResolver: sourcegraphStub{}},
+		module:   "corp.example.com/lib",
+		version:  "v1.2.3",
+		pathname: "NOTICE",
+		want:     "https://sg.example.com/corp.example.com/lib@v1.2.3/-/blob/NOTICE",
+	}, {
+		name:     "source file on GitHub",
+		module:   "github.com/foo/bar",
+		version:  "v1.0.0",
+		pathname: "internal/x/doc.go",
+		want:     "https://github.com/foo/bar/blob/v1.0.0/internal/x/doc.go",
+	}, {
+		name:     "PATENTS without version",
+		opts:     Options{SourceResolver: sourcegraphStub{}},
+		module:   "corp.example.com/lib",
+		pathname: "./PATENTS",
+		want:     "https://sg.example.com/corp.example.com/lib@HEAD/-/blob/PATENTS",
+	}, {
+		name:     "outside of module",
+		opts:     Options{SourceResolver: sourcegraphStub{}},
+		module:   "corp.example.com/lib",
+		version:  "v1.2.3",
+		pathname: "../other/LICENSE",
+		wantErr:  true,
+	}, {
+		name:     "absolute path",
+		opts:     Options{SourceResolver: sourcegraphStub{}},
+		module:   "corp.example.com/lib",
+		version:  "v1.2.3",
+		pathname: "/LICENSE",
+		wantErr:  true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ModuleFileURL(context.Background(), tc.opts, tc.module, tc.version, tc.pathname)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("ModuleFileURL() = (%q, %v), want error %v", got, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ModuleFileURL() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPkgsiteResolverMemoizesModuleInfo(t *testing.T) {
+	resolver := newPkgsiteResolver(source.NewClientForTesting(), nil)
+	ctx := context.Background()
+	first, err := resolver.info(ctx, "github.com/foo/bar", "v1.2.3")
+	if err != nil {
+		t.Fatalf("info() = (_, %q), want (_, nil)", err)
+	}
+	second, err := resolver.info(ctx, "github.com/foo/bar", "v1.2.3")
+	if err != nil {
+		t.Fatalf("info() = (_, %q), want (_, nil)", err)
+	}
+	if first != second {
+		t.Errorf("info() looked up github.com/foo/bar@v1.2.3 twice, want once")
+	}
+	other, err := resolver.info(ctx, "github.com/foo/bar", "v1.2.4")
+	if err != nil {
+		t.Fatalf("info() = (_, %q), want (_, nil)", err)
+	}
+	if other == first {
+		t.Errorf("info() of github.com/foo/bar@v1.2.4 returned the info of v1.2.3")
+	}
+	if got, want := other.FileURL("LICENSE"), "https://github.com/foo/bar/blob/v1.2.4/LICENSE"; got != want {
+		t.Errorf("FileURL() = %q, want %q", got, want)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper that answers requests with a function.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// metaTagsResponse returns a response with the go-import meta tag of a vanity import path.
+func metaTagsResponse(req *http.Request, goImport string) *http.Response {
+	body := `<html><head><meta name="go-import" content="` + goImport + `"></head></html>`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+func TestPkgsiteResolverRemembersOnlyDefiniteResults(t *testing.T) {
+	var goneRequests int32
+	unavailable := true
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Host == "gone.example.org":
+			atomic.AddInt32(&goneRequests, 1)
+			return metaTagsResponse(req, ""), nil
+		case unavailable:
+			return nil, errors.New("connection reset")
+		}
+		return metaTagsResponse(req, "vanity.example.org/mod git https://github.com/foo/mod"), nil
+	})}
+	resolver := NewPkgsiteResolverWithClient(client).(*pkgsiteResolver)
+	ctx := context.Background()
+
+	if _, err := resolver.info(ctx, "vanity.example.org/mod", "v1.0.0"); err == nil {
+		t.Fatalf("info() while unavailable = (_, nil), want an error")
+	}
+	unavailable = false
+	info, err := resolver.info(ctx, "vanity.example.org/mod", "v1.0.0")
+	if err != nil {
+		t.Fatalf("info() after a failure = (_, %q), want it looked up again", err)
+	}
+	if got, want := info.FileURL("LICENSE"), "https://github.com/foo/mod/blob/v1.0.0/LICENSE"; got != want {
+		t.Errorf("FileURL() = %q, want %q", got, want)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.info(ctx, "gone.example.org/mod", "v1.0.0"); !errors.Is(err, derrors.NotFound) {
+			t.Fatalf("info() of a module without meta tags = (_, %v), want a not found error", err)
+		}
+	}
+	if got := atomic.LoadInt32(&goneRequests); got != 1 {
+		t.Errorf("module without meta tags looked up %d times, want once", got)
+	}
+}
+
+func TestPkgsiteResolverWaitersGetTheirOwnContextErrors(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+			return metaTagsResponse(req, "vanity.example.org/mod git https://github.com/foo/mod"), nil
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	})}
+	resolver := NewPkgsiteResolverWithClient(client).(*pkgsiteResolver)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := resolver.info(firstCtx, "vanity.example.org/mod", "v1.0.0")
+		firstErr <- err
+	}()
+	<-started
+
+	// A waiter that gives up doesn't wait for the lookup in flight.
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := resolver.info(canceledCtx, "vanity.example.org/mod", "v1.0.0"); !errors.Is(err, context.Canceled) {
+		t.Errorf("info() with a canceled context = (_, %v), want context.Canceled", err)
+	}
+
+	// A waiter doesn't get the error of the caller that gave up, it looks the module up itself.
+	secondErr := make(chan error)
+	go func() {
+		_, err := resolver.info(context.Background(), "vanity.example.org/mod", "v1.0.0")
+		secondErr <- err
+	}()
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("info() of the caller that gave up = (_, %v), want context.Canceled", err)
+	}
+	close(release)
+	if err := <-secondErr; err != nil {
+		t.Errorf("info() of the waiter = (_, %v), want (_, nil)", err)
+	}
+}
+
+func TestSourcegraphResolver(t *testing.T) {
+	resolver := NewSourcegraphResolver("https://sg.example.com/", time.Second)
+	for _, test := range []struct {
+		desc    string
+		module  Module
+		path    string
+		wantURL string
+	}{
+		{
+			desc:    "Module at the repository root",
+			module:  Module{Path: "github.com/google/trillian", Version: "v1.2.3"},
+			path:    "LICENSE",
+			wantURL: "https://sg.example.com/github.com/google/trillian@v1.2.3/-/blob/LICENSE",
+		},
+		{
+			desc:    "Module in a sub directory",
+			module:  Module{Path: "github.com/google/trillian/submod", Version: "v1.0.0"},
+			path:    "LICENSE",
+			wantURL: "https://sg.example.com/github.com/google/trillian@submod/v1.0.0/-/blob/submod/LICENSE",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			repo, err := resolver.ModuleInfo(context.Background(), test.module.Path, test.module.Version)
+			if err != nil {
+				t.Fatalf("ModuleInfo() = (_, %q), want (_, nil)", err)
+			}
+			if got := repo.FileURL(test.path); got != test.wantURL {
+				t.Errorf("FileURL(%q) = %q, want %q", test.path, got, test.wantURL)
+			}
+		})
+	}
+}
+
+func TestSourcegraphRepoName(t *testing.T) {
+	for _, test := range []struct {
+		repoURL string
+		want    string
+	}{
+		{repoURL: "https://github.com/google/trillian", want: "github.com/google/trillian"},
+		{repoURL: "https://cs.opensource.google/go/x/sys", want: "github.com/golang/sys"},
+		{repoURL: "https://cs.opensource.google/go/go", want: "github.com/golang/go"},
+	} {
+		if got := sourcegraphRepoName(test.repoURL); got != test.want {
+			t.Errorf("sourcegraphRepoName(%q) = %q, want %q", test.repoURL, got, test.want)
+		}
+	}
+}
+
+func TestOfflineResolver(t *testing.T) {
+	modCache := t.TempDir()
+	info := filepath.Join(modCache, "cache", "download", "gopkg.in", "yaml.v2", "@v", "v2.4.0.info")
+	if err := os.MkdirAll(filepath.Dir(info), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	origin := `{"Version":"v2.4.0","Origin":{"VCS":"git","URL":"https://github.com/go-yaml/yaml","Ref":"refs/tags/v2.4.0"}}`
+	if err := os.WriteFile(info, []byte(origin), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewOfflineResolver(modCache, HostRule{Host: "git.example.com", RepoDepth: 2, Kind: "gitlab"})
+	for _, test := range []struct {
+		desc    string
+		module  Module
+		wantURL string
+		wantErr bool
+	}{
+		{
+			desc:    "Static host rule",
+			module:  Module{Path: "github.com/google/trillian", Version: "v1.2.3"},
+			wantURL: "https://github.com/google/trillian/blob/v1.2.3/LICENSE",
+		},
+		{
+			desc:    "Vanity import path with origin in the module cache",
+			module:  Module{Path: "gopkg.in/yaml.v2", Version: "v2.4.0"},
+			wantURL: "https://github.com/go-yaml/yaml/blob/v2.4.0/LICENSE",
+		},
+		{
+			desc:    "Self-hosted code host rule",
+			module:  Module{Path: "git.example.com/team/tool/v2", Version: "v2.0.1"},
+			wantURL: "https://git.example.com/team/tool/-/blob/v2.0.1/LICENSE",
+		},
+		{
+			desc:    "Vanity import path without origin",
+			module:  Module{Path: "go.uber.org/zap", Version: "v1.24.0"},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			repo, err := resolver.ModuleInfo(context.Background(), test.module.Path, test.module.Version)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ModuleInfo() = (%v, nil), want (_, error)", repo)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ModuleInfo() = (_, %q), want (_, nil)", err)
+			}
+			if got := repo.FileURL("LICENSE"); got != test.wantURL {
+				t.Errorf("FileURL(%q) = %q, want %q", "LICENSE", got, test.wantURL)
+			}
+		})
+	}
+}
+
+func TestHostRuleRaw(t *testing.T) {
+	rule := HostRule{Host: "scm.example.com", RepoDepth: 2, File: "{repo}/browse/{file}?at={commit}", Raw: "{repo}/raw/{file}?at={commit}"}
+	if err := rule.Validate(); err != nil {
+		t.Fatalf("Validate() = %q, want nil", err)
+	}
+	repo, err := NewOfflineResolver("", rule).ModuleInfo(context.Background(), "scm.example.com/team/tool", "v1.0.0")
+	if err != nil {
+		t.Fatalf("ModuleInfo() = (_, %q), want (_, nil)", err)
+	}
+	const wantFile = "https://scm.example.com/team/tool/browse/LICENSE?at=v1.0.0"
+	if got := repo.FileURL("LICENSE"); got != wantFile {
+		t.Errorf("FileURL(%q) = %q, want %q", "LICENSE", got, wantFile)
+	}
+	const wantRaw = "https://scm.example.com/team/tool/raw/LICENSE?at=v1.0.0"
+	if got := repo.(interface{ RawURL(string) string }).RawURL("LICENSE"); got != wantRaw {
+		t.Errorf("RawURL(%q) = %q, want %q", "LICENSE", got, wantRaw)
+	}
+}
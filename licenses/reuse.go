@@ -0,0 +1,90 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// reuseLicensesDir is the directory holding license texts in modules that follow the
+// REUSE specification (https://reuse.software/spec/).
+const reuseLicensesDir = "LICENSES"
+
+var spdxTagRegexp = regexp.MustCompile(`SPDX-License-Identifier:\s*(.*?)\s*(\*/)?\s*$`)
+
+// reuseHeaderLines is the number of lines at the top of a file searched for SPDX tags.
+const reuseHeaderLines = 30
+
+// reuseLicenses returns the SPDX license expressions that apply to goFiles of the module
+// in moduleDir according to the REUSE specification, and the paths of the license texts
+// in the module's LICENSES directory, which are named <SPDX-ID>.txt.
+//
+// The expressions are taken from SPDX-License-Identifier tags in the headers of goFiles.
+// If none of the files is tagged, all licenses in the LICENSES directory apply.
+// It returns nil for modules without a LICENSES directory.
+func reuseLicenses(moduleDir string, goFiles []string) (exprs []string, licensePaths []string) {
+	entries, err := os.ReadDir(filepath.Join(moduleDir, reuseLicensesDir))
+	if err != nil {
+		return nil, nil
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		licensePaths = append(licensePaths, filepath.Join(moduleDir, reuseLicensesDir, e.Name()))
+	}
+	if len(licensePaths) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range goFiles {
+		for _, expr := range spdxTags(f) {
+			if !seen[expr] {
+				seen[expr] = true
+				exprs = append(exprs, expr)
+			}
+		}
+	}
+	if len(exprs) == 0 {
+		exprs = ids
+	}
+	sort.Strings(exprs)
+	return exprs, licensePaths
+}
+
+// spdxTags returns the SPDX-License-Identifier expressions in the header of a file.
+func spdxTags(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var tags []string
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < reuseHeaderLines && scanner.Scan(); i++ {
+		if m := spdxTagRegexp.FindStringSubmatch(scanner.Text()); m != nil && m[1] != "" {
+			tags = append(tags, m[1])
+		}
+	}
+	return tags
+}
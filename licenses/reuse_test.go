@@ -0,0 +1,66 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReuseLicenses(t *testing.T) {
+	wantPaths := []string{
+		filepath.Join("testdata/reuse", "LICENSES", "Apache-2.0.txt"),
+		filepath.Join("testdata/reuse", "LICENSES", "MIT.txt"),
+	}
+	for _, test := range []struct {
+		desc      string
+		moduleDir string
+		goFiles   []string
+		wantExprs []string
+		wantPaths []string
+	}{
+		{
+			desc:      "file tags",
+			moduleDir: "testdata/reuse",
+			goFiles:   []string{"testdata/reuse/reuse.go"},
+			wantExprs: []string{"MIT OR Apache-2.0"},
+			wantPaths: wantPaths,
+		},
+		{
+			desc:      "no file tags",
+			moduleDir: "testdata/reuse",
+			goFiles:   []string{"testdata/reuse/notags/notags.go"},
+			wantExprs: []string{"Apache-2.0", "MIT"},
+			wantPaths: wantPaths,
+		},
+		{
+			desc:      "no LICENSES directory",
+			moduleDir: "testdata/direct",
+			goFiles:   []string{"testdata/direct/direct.go"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			gotExprs, gotPaths := reuseLicenses(test.moduleDir, test.goFiles)
+			if diff := cmp.Diff(test.wantExprs, gotExprs); diff != "" {
+				t.Errorf("reuseLicenses(%q, %q) expressions diff (-want +got):\n%s", test.moduleDir, test.goFiles, diff)
+			}
+			if diff := cmp.Diff(test.wantPaths, gotPaths); diff != "" {
+				t.Errorf("reuseLicenses(%q, %q) paths diff (-want +got):\n%s", test.moduleDir, test.goFiles, diff)
+			}
+		})
+	}
+}
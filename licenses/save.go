@@ -0,0 +1,283 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/otiai10/copy"
+	"k8s.io/klog/v2"
+)
+
+// SaveOptions configures SaveLibraries. The zero value saves like the save command with
+// its default flags.
+type SaveOptions struct {
+	// Archive writes the source code of libraries with reciprocal or restricted licenses
+	// to a SourceArchiveName tarball next to their license file instead of copying the
+	// source directory.
+	Archive bool
+	// Versioned saves the files of libraries by module path and version, e.g.
+	// example.com/mod@v1.2.3/pkg, rather than by library path.
+	Versioned bool
+	// Categories, if not nil, restricts saving to libraries with these license types.
+	// Libraries of other types that CanSave are skipped, the others still fail the save.
+	Categories map[Type]bool
+	// Parallelism is the number of libraries saved concurrently, runtime.NumCPU() if it
+	// is less than 1.
+	Parallelism int
+	// SkipSymlinks skips symlinked files and directories rather than copying the files
+	// they point to.
+	SkipSymlinks bool
+	// Override, if set, returns the license type that a license override assigns to lib
+	// and the license file it names, if any, instead of classifying lib.LicensePath.
+	Override func(lib *Library) (typ Type, licenseFile string, ok bool)
+}
+
+// saveableTypes are the license types whose requirements SaveLibraries can fulfill.
+var saveableTypes = map[Type]bool{
+	Restricted:   true,
+	Reciprocal:   true,
+	Notice:       true,
+	Permissive:   true,
+	Unencumbered: true,
+}
+
+// CanSave reports whether SaveLibraries can fulfill the requirements of licenses of type
+// t by copying files.
+func CanSave(t Type) bool {
+	return saveableTypes[t]
+}
+
+// SaveLibraries saves the files that the licenses of libs require to redistribute to dir:
+// the license and notice files of libraries under notice, permissive and unencumbered
+// licenses, and the source code of libraries under reciprocal and restricted licenses.
+// Every written file is verified against its source and listed with its checksum in a
+// manifest.json at the root of dir. Other license types can't be fulfilled by copying
+// files, so SaveLibraries fails for them after saving the other libraries.
+func SaveLibraries(classifier Classifier, libs []*Library, dir string, opts SaveOptions) error {
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = runtime.NumCPU()
+	}
+	var (
+		mu                  sync.Mutex
+		libsWithBadLicenses = make(map[Type][]*Library)
+		savedModules        = make(map[string]savedModule)
+		firstErr            error
+	)
+	groups := make(chan []*Library)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range groups {
+				for _, lib := range group {
+					libSaveDir := filepath.Join(dir, filepath.FromSlash(libSavePath(lib, opts.Versioned)))
+					licenseType, saved, err := saveLibrary(classifier, lib, opts, libSaveDir)
+					mu.Lock()
+					if err != nil && firstErr == nil {
+						firstErr = err
+					}
+					if mod := lib.Module(); mod != nil {
+						savedModules[libSaveDir] = savedModule{path: mod.Path, version: mod.Version}
+					}
+					if err == nil && !saved {
+						libsWithBadLicenses[licenseType] = append(libsWithBadLicenses[licenseType], lib)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, group := range saveGroups(libs, opts.Versioned) {
+		groups <- group
+	}
+	close(groups)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if len(libsWithBadLicenses) > 0 {
+		return fmt.Errorf("one or more libraries have an incompatible/unknown license: %q", libsWithBadLicenses)
+	}
+	return writeManifest(dir, savedModules)
+}
+
+// saveGroups groups libs whose save directories are nested, e.g. a module and one of its
+// packages with a license file of its own, so that no two workers write the same files.
+func saveGroups(libs []*Library, versioned bool) [][]*Library {
+	// Sorting with "/" as the lowest character puts nested directories right after
+	// their parent, e.g. a/b/c before a/b-c.
+	key := func(lib *Library) string { return strings.ReplaceAll(libSavePath(lib, versioned), "/", "\x00") }
+	sorted := append([]*Library(nil), libs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return key(sorted[i]) < key(sorted[j]) })
+	var groups [][]*Library
+	root := ""
+	for _, lib := range sorted {
+		p := libSavePath(lib, versioned)
+		if len(groups) > 0 && (p == root || strings.HasPrefix(p, root+"/")) {
+			groups[len(groups)-1] = append(groups[len(groups)-1], lib)
+			continue
+		}
+		root = p
+		groups = append(groups, []*Library{lib})
+	}
+	return groups
+}
+
+// saveLibrary detects what type of license lib has and fulfills its requirements, e.g.
+// copies the license, copyright notice or source code to libSaveDir. It returns false if
+// the requirements of the license type can't be fulfilled.
+func saveLibrary(classifier Classifier, lib *Library, opts SaveOptions, libSaveDir string) (Type, bool, error) {
+	var (
+		licenseType         Type
+		overrideLicenseFile string
+		overridden          bool
+	)
+	if opts.Override != nil {
+		licenseType, overrideLicenseFile, overridden = opts.Override(lib)
+	}
+	if !overridden {
+		var err error
+		if _, licenseType, err = classifier.Identify(lib.LicensePath); err != nil {
+			return licenseType, false, err
+		}
+	}
+	if opts.Categories != nil && saveableTypes[licenseType] && !opts.Categories[licenseType] {
+		klog.Infof("Skipping %s, its license type %s is not in the categories to save", lib.Name(), licenseType)
+		return licenseType, true, nil
+	}
+	if overridden && lib.LicensePath == "" {
+		klog.Errorf("%s has no license file to save, set licenseFile in its license override", lib.Name())
+		return licenseType, false, nil
+	}
+	switch licenseType {
+	case Restricted, Reciprocal:
+		// Copy the entire source directory for the library.
+		libDir := filepath.Dir(lib.LicensePath)
+		if m := lib.Module(); overridden && lib.LicensePath == overrideLicenseFile && m != nil {
+			// The license file of the override is not part of the library's source.
+			libDir = m.Dir
+		}
+		if len(lib.ReuseLicensePaths) > 0 && filepath.Base(libDir) == "LICENSES" {
+			// The license is in the LICENSES directory of a REUSE module root.
+			libDir = filepath.Dir(libDir)
+		}
+		if opts.Archive {
+			// Keep the license readable without unpacking the archive.
+			if err := copyLicenseFiles(lib, libSaveDir, opts.SkipSymlinks); err != nil {
+				return licenseType, false, err
+			}
+			if err := archiveSrc(libDir, libSaveDir, opts.SkipSymlinks); err != nil {
+				return licenseType, false, err
+			}
+			return licenseType, true, verifyArchive(libDir, filepath.Join(libSaveDir, SourceArchiveName))
+		}
+		return licenseType, true, copySrc(libDir, libSaveDir, opts.SkipSymlinks)
+	case Notice, Permissive, Unencumbered:
+		// Just copy the license and copyright notice.
+		if err := copyLicenseFiles(lib, libSaveDir, opts.SkipSymlinks); err != nil {
+			return licenseType, false, err
+		}
+		// Modules following the REUSE specification keep one file per license.
+		for _, p := range lib.ReuseLicensePaths {
+			if err := verifiedCopy(p, filepath.Join(libSaveDir, "LICENSES", filepath.Base(p)), copyOptions(opts.SkipSymlinks)); err != nil {
+				return licenseType, false, err
+			}
+		}
+		return licenseType, true, nil
+	default:
+		return licenseType, false, nil
+	}
+}
+
+// libSavePath returns the directory, relative to the save directory, that the files of
+// lib are saved to. Vendored libraries are saved by the import path of their copy.
+func libSavePath(lib *Library, versioned bool) string {
+	name := unvendor(lib.Name())
+	mod := lib.Module()
+	if !versioned || mod == nil || mod.Version == "" {
+		return name
+	}
+	modPath := unvendor(mod.Path)
+	if name == modPath {
+		return modPath + "@" + mod.Version
+	}
+	if strings.HasPrefix(name, modPath+"/") {
+		return modPath + "@" + mod.Version + strings.TrimPrefix(name, modPath)
+	}
+	return name + "@" + mod.Version
+}
+
+// unvendor removes the "*/vendor/" prefix from importPath, if present.
+func unvendor(importPath string) string {
+	if i := strings.Index(importPath, "/vendor/"); i >= 0 {
+		return importPath[i+len("/vendor/"):]
+	}
+	return importPath
+}
+
+func copySrc(src, dest string, skipSymlinks bool) error {
+	// Skip the .git directory for copying, if it exists, since we don't want to save the user's
+	// local Git config along with the source code.
+	opt := copyOptions(skipSymlinks)
+	opt.Skip = func(src string) (bool, error) {
+		return strings.HasSuffix(src, ".git"), nil
+	}
+	opt.AddPermission = 0600
+	return verifiedCopy(src, dest, opt)
+}
+
+// copyLicenseFiles copies the license file of lib and the files whose terms apply along
+// with it, e.g. NOTICE and PATENTS files, to dest, keeping their paths relative to the
+// license file.
+func copyLicenseFiles(lib *Library, dest string, skipSymlinks bool) error {
+	files := lib.LicenseFiles
+	if len(files) == 0 {
+		// E.g. the license file of a license override.
+		files = []string{lib.LicensePath}
+	}
+	dir := filepath.Dir(lib.LicensePath)
+	for _, f := range files {
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return err
+		}
+		if err := verifiedCopy(f, filepath.Join(dest, rel), copyOptions(skipSymlinks)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyOptions returns the options for copying files into the save directory. Symlinks are
+// copied as the files they point to, since links into the module cache would dangle
+// elsewhere, unless skipSymlinks is set.
+func copyOptions(skipSymlinks bool) copy.Options {
+	return copy.Options{
+		OnSymlink: func(string) copy.SymlinkAction {
+			if skipSymlinks {
+				return copy.Skip
+			}
+			return copy.Deep
+		},
+	}
+}
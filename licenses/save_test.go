@@ -0,0 +1,173 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSaveLibraries(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "notice", "LICENSE"), "MIT")
+	writeTestFile(t, filepath.Join(src, "notice", "NOTICE"), "notice")
+	writeTestFile(t, filepath.Join(src, "restricted", "LICENSE"), "GPL")
+	writeTestFile(t, filepath.Join(src, "restricted", "a.go"), "package restricted")
+	rel := func(p string) string {
+		r, err := filepath.Rel(wd, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filepath.ToSlash(r)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			rel(filepath.Join(src, "notice", "LICENSE")):     "MIT",
+			rel(filepath.Join(src, "restricted", "LICENSE")): "GPL-2.0",
+		},
+		licenseTypes: map[string]Type{
+			rel(filepath.Join(src, "notice", "LICENSE")):     Notice,
+			rel(filepath.Join(src, "restricted", "LICENSE")): Restricted,
+		},
+	}
+	libs := func() []*Library {
+		noticeDir := filepath.Join(src, "notice")
+		return []*Library{
+			{
+				LicensePath:  filepath.Join(noticeDir, "LICENSE"),
+				LicenseFiles: []string{filepath.Join(noticeDir, "LICENSE"), filepath.Join(noticeDir, "NOTICE")},
+				Packages:     []string{"example.com/notice"},
+				module:       &Module{Path: "example.com/notice", Version: "v1.0.0", Dir: noticeDir},
+			},
+			{
+				LicensePath: filepath.Join(src, "restricted", "LICENSE"),
+				Packages:    []string{"example.com/vendorer/vendor/example.com/restricted"},
+				module:      &Module{Path: "example.com/restricted", Version: "v0.2.0", Dir: filepath.Join(src, "restricted")},
+			},
+		}
+	}
+	for _, test := range []struct {
+		desc string
+		opts SaveOptions
+		want []string
+	}{
+		{
+			desc: "defaults",
+			want: []string{
+				"example.com/notice/LICENSE",
+				"example.com/notice/NOTICE",
+				"example.com/restricted/LICENSE",
+				"example.com/restricted/a.go",
+				manifestName,
+			},
+		},
+		{
+			desc: "versioned layout and source archives",
+			opts: SaveOptions{Versioned: true, Archive: true, Parallelism: 1},
+			want: []string{
+				"example.com/notice@v1.0.0/LICENSE",
+				"example.com/notice@v1.0.0/NOTICE",
+				"example.com/restricted@v0.2.0/LICENSE",
+				"example.com/restricted@v0.2.0/" + SourceArchiveName,
+				manifestName,
+			},
+		},
+		{
+			desc: "categories",
+			opts: SaveOptions{Categories: map[Type]bool{Restricted: true}},
+			want: []string{
+				"example.com/restricted/LICENSE",
+				"example.com/restricted/a.go",
+				manifestName,
+			},
+		},
+		{
+			desc: "license override",
+			opts: SaveOptions{Override: func(lib *Library) (Type, string, bool) {
+				if lib.Module().Path != "example.com/restricted" {
+					return "", "", false
+				}
+				return Notice, "", true
+			}},
+			want: []string{
+				"example.com/notice/LICENSE",
+				"example.com/notice/NOTICE",
+				"example.com/restricted/LICENSE",
+				manifestName,
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := SaveLibraries(classifier, libs(), dir, test.opts); err != nil {
+				t.Fatalf("SaveLibraries() = %v, want nil", err)
+			}
+			var got []string
+			err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				r, err := filepath.Rel(dir, p)
+				got = append(got, filepath.ToSlash(r))
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			sort.Strings(test.want)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SaveLibraries(): saved files diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSaveLibrariesUnfulfillable(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	licensePath := filepath.Join(t.TempDir(), "LICENSE")
+	writeTestFile(t, licensePath, "AGPL")
+	rel, err := filepath.Rel(wd, licensePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{filepath.ToSlash(rel): "AGPL-3.0"},
+		licenseTypes: map[string]Type{filepath.ToSlash(rel): Forbidden},
+	}
+	dir := t.TempDir()
+	libs := []*Library{{LicensePath: licensePath, Packages: []string{"example.com/forbidden"}}}
+	// Forbidden licenses fail the save even if they are not in the categories to save.
+	err = SaveLibraries(classifier, libs, dir, SaveOptions{Categories: map[Type]bool{Notice: true}})
+	if err == nil || !strings.Contains(err.Error(), "example.com/forbidden") {
+		t.Errorf("SaveLibraries() = %v, want an error naming the library with a forbidden license", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, manifestName)); !os.IsNotExist(err) {
+		t.Errorf("SaveLibraries() wrote a manifest for a failed save")
+	}
+}
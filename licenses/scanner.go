@@ -0,0 +1,345 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Scanner finds the libraries of several roots, e.g. the modules of a monorepo or the
+// repositories that a server scans one after another, with shared state: the classifier,
+// the module infos looked up by the SourceResolver and the classifications of license
+// files. Rebuilding them for every call of LibrariesWithOptions is the expensive part of
+// repeated scans in one process. A Scanner is safe for concurrent use.
+//
+// The stages of the go-licenses commands are methods of Scanner: Scan finds libraries,
+// Check applies a license policy to them and Save copies their license files. Each takes
+// the results of the previous stage, so they can be tested or replaced one at a time.
+// Long-lived programs call WarmUp after NewScanner and Close when they are done.
+type Scanner struct {
+	classifier Classifier
+	opts       Options
+}
+
+// NewScanner returns a Scanner that identifies licenses with classifier and discovers
+// libraries as configured by opts. opts.Dir is ignored, each scan names its directory.
+// Without opts.SourceResolver, the Scanner has its own resolver like NewPkgsiteResolver
+// returns, which looks up each module version once for the life of the Scanner. Pass a
+// resolver whose client is configured otherwise, e.g. NewOfflineResolver, to change how
+// URLs are resolved.
+func NewScanner(classifier Classifier, opts Options) *Scanner {
+	if opts.SourceResolver == nil {
+		opts.SourceResolver = NewPkgsiteResolver(time.Second * 20)
+	}
+	return &Scanner{
+		classifier: &memoClassifier{classifier: classifier, results: make(map[memoKey]memoResult)},
+		opts:       opts,
+	}
+}
+
+// WarmUp prepares s for scans, e.g. before a server accepts requests: it runs the go
+// command and classifies the license of the Go toolchain, so that a broken environment
+// fails early rather than in the first scan.
+func (s *Scanner) WarmUp(ctx context.Context) error {
+	goroot, err := goEnv(&packages.Config{Context: ctx, Env: goEnviron(ctx)}, "GOROOT")
+	if err != nil {
+		return err
+	}
+	if _, _, err := s.classifier.Identify(filepath.Join(goroot, "LICENSE")); err != nil {
+		return fmt.Errorf("classifying the license of the Go toolchain: %w", err)
+	}
+	return nil
+}
+
+// Close writes the results added to opts.Cache, if any, to disk. The Scanner must not
+// be used afterwards.
+func (s *Scanner) Close() error {
+	if s.opts.Cache == nil {
+		return nil
+	}
+	return s.opts.Cache.Save()
+}
+
+// Scan is like LibrariesWithOptions with the options of s for the packages matching
+// importPaths in dir.
+func (s *Scanner) Scan(ctx context.Context, dir string, importPaths ...string) ([]*Library, error) {
+	opts := s.opts
+	opts.Dir = dir
+	return LibrariesWithOptions(ctx, s.classifier, opts, importPaths...)
+}
+
+// Root is a directory and the import paths of the packages to scan in it.
+type Root struct {
+	Dir         string
+	ImportPaths []string
+}
+
+// MergedLibraries scans roots in parallel and merges their libraries: a library of the same
+// module version and license file used by several roots is returned once, with the
+// packages of all of them. It is only test-only if it is in all roots that use it, and
+// direct if it is in any of them. The callbacks of the Scanner's options may be called
+// concurrently.
+func (s *Scanner) MergedLibraries(ctx context.Context, roots []Root) ([]*Library, error) {
+	results := make([][]*Library, len(roots))
+	errs := make([]error, len(roots))
+	// Every scan runs the go command, which is parallel itself.
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, root := range roots {
+		wg.Add(1)
+		go func(i int, root Root) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = s.Scan(ctx, root.Dir, root.ImportPaths...)
+		}(i, root)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", roots[i].Dir, err)
+		}
+	}
+	return mergeLibraries(results), nil
+}
+
+// Policy decides which licenses Scanner.Check allows, like the license flags of the
+// check command. AllowedLicenses and DisallowedTypes are mutually exclusive; if neither
+// is set, the Forbidden and Unknown types are disallowed.
+type Policy struct {
+	// AllowedLicenses are the names of the only licenses allowed, e.g. "MIT".
+	AllowedLicenses []string
+	// DisallowedLicenses are license names that are denied in any case.
+	DisallowedLicenses []string
+	// DisallowedTypes are the license types that are denied.
+	DisallowedTypes []Type
+}
+
+// Violation is a license of a library that a Policy doesn't allow.
+type Violation struct {
+	Library *Library
+	// License is the name of the license, empty if no license was identified.
+	License string
+	Type    Type
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s license %s (%s) is not allowed", v.Library, v.License, v.Type)
+}
+
+// Check returns the licenses of libs that policy doesn't allow. Libraries following the
+// REUSE specification are checked for each of their licenses.
+func (s *Scanner) Check(libs []*Library, policy Policy) ([]Violation, error) {
+	if len(policy.AllowedLicenses) > 0 && len(policy.DisallowedTypes) > 0 {
+		return nil, errors.New("allowed licenses and disallowed types can't be used at the same time")
+	}
+	if len(policy.AllowedLicenses) == 0 && len(policy.DisallowedTypes) == 0 {
+		policy.DisallowedTypes = []Type{Forbidden, Unknown}
+	}
+	var violations []Violation
+	for _, lib := range libs {
+		found, err := s.libraryLicenses(lib)
+		if err != nil {
+			return nil, fmt.Errorf("identifying the license of %s: %w", lib, err)
+		}
+		for _, v := range found {
+			if policy.allows(v.License, v.Type) {
+				continue
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
+
+// libraryLicenses returns the licenses of lib as violations yet to be judged.
+func (s *Scanner) libraryLicenses(lib *Library) ([]Violation, error) {
+	if len(lib.ReuseLicenses) > 0 {
+		var found []Violation
+		for _, expr := range lib.ReuseLicenses {
+			for _, name := range ExpressionLicenses(expr) {
+				found = append(found, Violation{Library: lib, License: name, Type: LicenseType(name)})
+			}
+		}
+		return found, nil
+	}
+	name, typ, err := s.classifier.Identify(lib.LicensePath)
+	if errors.Is(err, errUnknownLicense) {
+		name, typ, err = "", Unknown, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []Violation{{Library: lib, License: name, Type: typ}}, nil
+}
+
+func (p Policy) allows(name string, typ Type) bool {
+	if containsString(p.DisallowedLicenses, name) {
+		return false
+	}
+	if len(p.AllowedLicenses) > 0 {
+		return containsString(p.AllowedLicenses, name)
+	}
+	for _, t := range p.DisallowedTypes {
+		if t == typ {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Save is SaveLibraries with the classifier of s, which saves libs to dir like the save
+// command. opts.SkipSymlinks is taken from the options of s.
+func (s *Scanner) Save(libs []*Library, dir string, opts SaveOptions) error {
+	opts.SkipSymlinks = s.opts.SkipSymlinks
+	return SaveLibraries(s.classifier, libs, dir, opts)
+}
+
+// mergeLibraries merges the libraries of several scans, see Scanner.MergedLibraries.
+func mergeLibraries(scans [][]*Library) []*Library {
+	var merged []*Library
+	byKey := make(map[string]*Library)
+	for _, libs := range scans {
+		for _, lib := range libs {
+			key := libraryKey(lib)
+			m, ok := byKey[key]
+			if !ok {
+				copied := *lib
+				copied.Packages = append([]string(nil), lib.Packages...)
+				copied.Imports = make(map[string][]string, len(lib.Imports))
+				for pkg, imports := range lib.Imports {
+					copied.Imports[pkg] = imports
+				}
+				byKey[key] = &copied
+				merged = append(merged, &copied)
+				continue
+			}
+			for _, pkg := range lib.Packages {
+				if _, ok := m.Imports[pkg]; !ok {
+					m.Packages = append(m.Packages, pkg)
+				}
+				m.Imports[pkg] = lib.Imports[pkg]
+			}
+			m.TestOnly = m.TestOnly && lib.TestOnly
+			m.Direct = m.Direct || lib.Direct
+			if m.RequiredAt == nil {
+				m.RequiredAt = lib.RequiredAt
+			}
+			if len(lib.DependencyPath) > 0 && (len(m.DependencyPath) == 0 || len(lib.DependencyPath) < len(m.DependencyPath)) {
+				m.DependencyPath = lib.DependencyPath
+			}
+		}
+	}
+	for _, lib := range merged {
+		sort.Strings(lib.Packages)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+	return merged
+}
+
+// libraryKey identifies a library across scans by its module version and license file,
+// or by its package if it has no license file, since such libraries have one package.
+func libraryKey(lib *Library) string {
+	key := lib.LicensePath
+	if key == "" && len(lib.Packages) > 0 {
+		key = "package:" + lib.Packages[0]
+	}
+	if m := lib.Module(); m != nil {
+		key = m.Path + "@" + m.Version + " " + key
+	}
+	return key
+}
+
+// memoClassifier remembers the results of a classifier for license files that haven't
+// changed since they were classified, e.g. the files of the module cache that many roots
+// share.
+type memoClassifier struct {
+	classifier Classifier
+
+	mu      sync.Mutex
+	results map[memoKey]memoResult
+}
+
+type memoKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+type memoResult struct {
+	name string
+	typ  Type
+	err  error
+}
+
+func (c *memoClassifier) Identify(licensePath string) (string, Type, error) {
+	fi, err := os.Stat(licensePath)
+	if err != nil {
+		return c.classifier.Identify(licensePath)
+	}
+	key := memoKey{path: licensePath, size: fi.Size(), modTime: fi.ModTime()}
+	c.mu.Lock()
+	r, ok := c.results[key]
+	c.mu.Unlock()
+	if ok {
+		return r.name, r.typ, r.err
+	}
+	r.name, r.typ, r.err = c.classifier.Identify(licensePath)
+	c.mu.Lock()
+	c.results[key] = r
+	c.mu.Unlock()
+	return r.name, r.typ, r.err
+}
+
+// Info describes the wrapped classifier.
+func (c *memoClassifier) Info() ClassifierInfo {
+	return DescribeClassifier(c.classifier)
+}
+
+// NearestMatch forwards to the wrapped classifier, so that candidates of unknown licenses
+// list near misses in scans of a Scanner too.
+func (c *memoClassifier) NearestMatch(licensePath string) (string, float64, error) {
+	nm, ok := c.classifier.(nearestMatcher)
+	if !ok {
+		return "", 0, errors.New("classifier doesn't report near misses")
+	}
+	return nm.NearestMatch(licensePath)
+}
+
+// Matches forwards to the wrapped classifier, see the Matches function.
+func (c *memoClassifier) Matches(licensePath string) ([]LicenseMatch, error) {
+	return Matches(c.classifier, licensePath)
+}
@@ -0,0 +1,258 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// countingClassifier counts the calls of Identify per license path.
+type countingClassifier struct {
+	classifierStub
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *countingClassifier) Identify(licensePath string) (string, Type, error) {
+	c.mu.Lock()
+	c.calls[licensePath]++
+	c.mu.Unlock()
+	return c.classifierStub.Identify(licensePath)
+}
+
+func TestScannerMergedLibraries(t *testing.T) {
+	classifier := &countingClassifier{
+		classifierStub: classifierStub{
+			licenseNames: map[string]string{
+				"testdata/LICENSE":          "foo",
+				"testdata/direct/LICENSE":   "foo",
+				"testdata/indirect/LICENSE": "foo",
+			},
+			licenseTypes: map[string]Type{
+				"testdata/LICENSE":          Notice,
+				"testdata/direct/LICENSE":   Notice,
+				"testdata/indirect/LICENSE": Notice,
+			},
+		},
+		calls: make(map[string]int),
+	}
+	scanner := NewScanner(classifier, Options{})
+	roots := []Root{
+		{Dir: ".", ImportPaths: []string{"github.com/nilsbeck/go-licenses/licenses/testdata/direct"}},
+		{Dir: ".", ImportPaths: []string{"github.com/nilsbeck/go-licenses/licenses/testdata"}},
+	}
+	libs, err := scanner.MergedLibraries(context.Background(), roots)
+	if err != nil {
+		t.Fatalf("MergedLibraries() = (_, %q), want (_, nil)", err)
+	}
+	var got []string
+	for _, lib := range libs {
+		got = append(got, lib.Name())
+	}
+	want := []string{
+		"github.com/nilsbeck/go-licenses/licenses/testdata",
+		"github.com/nilsbeck/go-licenses/licenses/testdata/direct",
+		"github.com/nilsbeck/go-licenses/licenses/testdata/indirect",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MergedLibraries(): diff (-want +got)\n%s", diff)
+	}
+	if len(classifier.calls) == 0 {
+		t.Fatal("Identify was never called")
+	}
+	for path, n := range classifier.calls {
+		if n > 1 {
+			t.Errorf("Identify(%q) was called %d times, want once for all roots", path, n)
+		}
+	}
+	// A later scan reuses the classifications of the first.
+	if _, err := scanner.Scan(context.Background(), ".", "github.com/nilsbeck/go-licenses/licenses/testdata/direct"); err != nil {
+		t.Fatalf("Scan() = (_, %q), want (_, nil)", err)
+	}
+	for path, n := range classifier.calls {
+		if n > 1 {
+			t.Errorf("after another scan, Identify(%q) was called %d times, want once", path, n)
+		}
+	}
+}
+
+func TestMergeLibraries(t *testing.T) {
+	module := &Module{Path: "example.com/m", Version: "v1.0.0"}
+	scans := [][]*Library{
+		{{
+			LicensePath: "/modcache/example.com/m@v1.0.0/LICENSE",
+			Packages:    []string{"example.com/m/a"},
+			Imports:     map[string][]string{"example.com/m/a": nil},
+			TestOnly:    true,
+			module:      module,
+		}},
+		{{
+			LicensePath: "/modcache/example.com/m@v1.0.0/LICENSE",
+			Packages:    []string{"example.com/m/a", "example.com/m"},
+			Imports:     map[string][]string{"example.com/m/a": nil, "example.com/m": {"example.com/m/a"}},
+			Direct:      true,
+			module:      module,
+		}},
+	}
+	got := mergeLibraries(scans)
+	if len(got) != 1 {
+		t.Fatalf("mergeLibraries() returned %d libraries, want 1", len(got))
+	}
+	if diff := cmp.Diff([]string{"example.com/m", "example.com/m/a"}, got[0].Packages); diff != "" {
+		t.Errorf("merged Packages: diff (-want +got)\n%s", diff)
+	}
+	if got[0].TestOnly {
+		t.Errorf("merged TestOnly = true, want false since one root uses the library in non-test code")
+	}
+	if !got[0].Direct {
+		t.Errorf("merged Direct = false, want true since one root imports the library directly")
+	}
+	if len(scans[0][0].Packages) != 1 {
+		t.Errorf("mergeLibraries() modified the libraries of the scans")
+	}
+}
+
+func stageTestLibraries(t *testing.T) (*Scanner, []*Library) {
+	t.Helper()
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "MIT",
+			"testdata/direct/LICENSE":   "GPL-2.0",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Restricted,
+			"testdata/indirect/LICENSE": Forbidden,
+		},
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := func(dir, pkg string) *Library {
+		return &Library{LicensePath: filepath.Join(wd, dir, "LICENSE"), Packages: []string{pkg}}
+	}
+	return NewScanner(classifier, Options{}), []*Library{
+		lib("testdata", "example.com/notice"),
+		lib("testdata/direct", "example.com/restricted"),
+		lib("testdata/indirect", "example.com/forbidden"),
+	}
+}
+
+func TestScannerCheck(t *testing.T) {
+	scanner, libs := stageTestLibraries(t)
+	for _, test := range []struct {
+		desc    string
+		policy  Policy
+		want    []string
+		wantErr bool
+	}{
+		{
+			desc: "Default policy",
+			want: []string{"example.com/forbidden"},
+		},
+		{
+			desc:   "Disallowed types",
+			policy: Policy{DisallowedTypes: []Type{Restricted}},
+			want:   []string{"example.com/restricted"},
+		},
+		{
+			desc:   "Allowed and disallowed licenses",
+			policy: Policy{AllowedLicenses: []string{"MIT", "GPL-2.0"}, DisallowedLicenses: []string{"GPL-2.0"}},
+			want:   []string{"example.com/restricted", "example.com/forbidden"},
+		},
+		{
+			desc:    "Allowed licenses and disallowed types",
+			policy:  Policy{AllowedLicenses: []string{"MIT"}, DisallowedTypes: []Type{Forbidden}},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			violations, err := scanner.Check(libs, test.policy)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Check() = (%v, nil), want (_, error)", violations)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() = (_, %q), want (_, nil)", err)
+			}
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Library.Name())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Check(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScannerSave(t *testing.T) {
+	scanner, libs := stageTestLibraries(t)
+	dir := t.TempDir()
+	err := scanner.Save(libs, dir, SaveOptions{})
+	if err == nil || !strings.Contains(err.Error(), "example.com/forbidden") {
+		t.Errorf("Save() = %v, want an error naming the library with a forbidden license", err)
+	}
+	for _, f := range []string{
+		"example.com/notice/LICENSE",
+		"example.com/restricted/LICENSE",
+		"example.com/restricted/direct.go",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f))); err != nil {
+			t.Errorf("Save() didn't copy %s: %v", f, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "example.com", "forbidden")); !os.IsNotExist(err) {
+		t.Errorf("Save() copied the files of the library with a forbidden license")
+	}
+}
+
+func TestScannerClassifierExplainsMatches(t *testing.T) {
+	classifier, err := NewClassifier(0.9)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.9) = (_, %q), want (_, nil)", err)
+	}
+	s := NewScanner(classifier, Options{SourceResolver: sourcegraphStub{}})
+	const licensePath = "testdata/MIT/LICENSE.MIT"
+
+	matches, err := Matches(s.classifier, licensePath)
+	if err != nil || len(matches) == 0 || matches[0].Name != "MIT" {
+		t.Errorf("Matches(%q) = (%v, %v), want MIT first", licensePath, matches, err)
+	}
+	nm, ok := s.classifier.(nearestMatcher)
+	if !ok {
+		t.Fatalf("classifier of Scanner doesn't report near misses")
+	}
+	if name, _, err := nm.NearestMatch(licensePath); err != nil || name != "MIT" {
+		t.Errorf("NearestMatch(%q) = (%q, _, %v), want (%q, _, nil)", licensePath, name, err, "MIT")
+	}
+	// Classifiers that report neither are still supported.
+	s = NewScanner(classifierStub{}, Options{SourceResolver: sourcegraphStub{}})
+	if matches, err := Matches(s.classifier, licensePath); matches != nil || err != nil {
+		t.Errorf("Matches(%q) with stub = (%v, %v), want (nil, nil)", licensePath, matches, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/licenseclassifier"
+)
+
+// UnknownLicenseRef is the SPDX license reference used for licenses that could not be
+// identified, since an SPDX expression cannot contain NOASSERTION.
+const UnknownLicenseRef = "LicenseRef-Unknown"
+
+// AggregateExpression combines the license expressions of a set of libraries into a
+// single SPDX license expression that applies to all of them together, e.g.
+// "Apache-2.0 AND MIT". Duplicates are removed and operands are sorted for stable
+// output. Empty expressions and "Unknown" are represented by UnknownLicenseRef.
+func AggregateExpression(exprs []string) string {
+	seen := make(map[string]bool)
+	var operands []string
+	for _, e := range exprs {
+		e = strings.TrimSpace(e)
+		if e == "" || e == "Unknown" {
+			e = UnknownLicenseRef
+		}
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		operands = append(operands, e)
+	}
+	if len(operands) > 1 {
+		for i, e := range operands {
+			if isCompoundExpression(e) && !(strings.HasPrefix(e, "(") && strings.HasSuffix(e, ")")) {
+				// Compound expressions must keep their own precedence inside the AND.
+				operands[i] = "(" + e + ")"
+			}
+		}
+	}
+	sort.Strings(operands)
+	return strings.Join(operands, " AND ")
+}
+
+func isCompoundExpression(expr string) bool {
+	return strings.Contains(expr, " OR ") || strings.Contains(expr, " WITH ") || strings.Contains(expr, " AND ")
+}
+
+// ExpressionLicenseIDs returns the license identifiers referenced by an SPDX license
+// expression, without operators, parentheses and exception identifiers.
+func ExpressionLicenseIDs(expr string) []string {
+	var ids []string
+	tokens := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expr))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "AND", "OR":
+		case "WITH":
+			// Skip the exception identifier.
+			i++
+		default:
+			ids = append(ids, tokens[i])
+		}
+	}
+	return ids
+}
+
+// ExpressionLicenses returns the licenses referenced by an SPDX license expression,
+// without operators and parentheses. Licenses with an exception are returned together
+// with it, e.g. "GPL-2.0-only WITH Classpath-exception-2.0", since the exception changes
+// what the license allows.
+func ExpressionLicenses(expr string) []string {
+	var ls []string
+	tokens := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expr))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "AND", "OR":
+		case "WITH":
+			if len(ls) > 0 && i+1 < len(tokens) {
+				ls[len(ls)-1] += " WITH " + tokens[i+1]
+			}
+			i++
+		default:
+			ls = append(ls, tokens[i])
+		}
+	}
+	return ls
+}
+
+// LicenseType returns the type of a license given its name, which may be an SPDX
+// license identifier, optionally with an exception, e.g.
+// "GPL-2.0-only WITH Classpath-exception-2.0". Unknown licenses result in the Unknown
+// type.
+func LicenseType(name string) Type {
+	if i := strings.Index(name, " WITH "); i >= 0 {
+		name = name[:i]
+	}
+	if t := licenseclassifier.LicenseType(name); t != "" {
+		return Type(t)
+	}
+	// The classifier uses the deprecated SPDX identifiers of GNU licenses, e.g. GPL-2.0
+	// instead of GPL-2.0-only.
+	name = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "+"), "-only"), "-or-later")
+	return Type(licenseclassifier.LicenseType(name))
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAggregateExpression(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		exprs []string
+		want  string
+	}{
+		{
+			desc: "no licenses",
+			want: "",
+		},
+		{
+			desc:  "single license",
+			exprs: []string{"MIT"},
+			want:  "MIT",
+		},
+		{
+			desc:  "duplicates are removed and operands sorted",
+			exprs: []string{"MIT", "Apache-2.0", "MIT", "BSD-3-Clause"},
+			want:  "Apache-2.0 AND BSD-3-Clause AND MIT",
+		},
+		{
+			desc:  "unknown licenses",
+			exprs: []string{"MIT", "", "Unknown"},
+			want:  "LicenseRef-Unknown AND MIT",
+		},
+		{
+			desc:  "single compound expression",
+			exprs: []string{"MIT OR Apache-2.0"},
+			want:  "MIT OR Apache-2.0",
+		},
+		{
+			desc:  "compound expressions are parenthesized",
+			exprs: []string{"MIT OR Apache-2.0", "BSD-2-Clause"},
+			want:  "(MIT OR Apache-2.0) AND BSD-2-Clause",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := AggregateExpression(test.exprs); got != test.want {
+				t.Errorf("AggregateExpression(%q) = %q, want %q", test.exprs, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExpressionLicenseIDs(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		want []string
+	}{
+		{expr: "MIT", want: []string{"MIT"}},
+		{expr: "MIT OR Apache-2.0", want: []string{"MIT", "Apache-2.0"}},
+		{expr: "(MIT OR Apache-2.0) AND BSD-3-Clause", want: []string{"MIT", "Apache-2.0", "BSD-3-Clause"}},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", want: []string{"GPL-2.0-only"}},
+	} {
+		if diff := cmp.Diff(test.want, ExpressionLicenseIDs(test.expr)); diff != "" {
+			t.Errorf("ExpressionLicenseIDs(%q) diff (-want +got):\n%s", test.expr, diff)
+		}
+	}
+}
+
+func TestExpressionLicenses(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		want []string
+	}{
+		{expr: "MIT", want: []string{"MIT"}},
+		{expr: "(MIT OR Apache-2.0) AND BSD-3-Clause", want: []string{"MIT", "Apache-2.0", "BSD-3-Clause"}},
+		{expr: "GPL-2.0-only WITH Classpath-exception-2.0", want: []string{"GPL-2.0-only WITH Classpath-exception-2.0"}},
+		{expr: "(Apache-2.0 WITH LLVM-exception) AND MIT", want: []string{"Apache-2.0 WITH LLVM-exception", "MIT"}},
+	} {
+		if diff := cmp.Diff(test.want, ExpressionLicenses(test.expr)); diff != "" {
+			t.Errorf("ExpressionLicenses(%q) diff (-want +got):\n%s", test.expr, diff)
+		}
+	}
+}
+
+func TestLicenseType(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want Type
+	}{
+		{name: "MIT", want: Notice},
+		{name: "GPL-2.0", want: Restricted},
+		{name: "GPL-2.0-only", want: Restricted},
+		{name: "LGPL-2.1-or-later", want: Restricted},
+		{name: "GPL-2.0-only WITH Classpath-exception-2.0", want: Restricted},
+		{name: "Apache-2.0 WITH LLVM-exception", want: Notice},
+		{name: "LicenseRef-Proprietary", want: Unknown},
+	} {
+		if got := LicenseType(test.name); got != test.want {
+			t.Errorf("LicenseType(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
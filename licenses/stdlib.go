@@ -0,0 +1,99 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// StdLibModulePath is the module path of the Go standard library, as used by pkg.go.dev.
+const StdLibModulePath = "std"
+
+// stdLibrary returns a library for the standard library packages pkgPaths, licensed by
+// the LICENSE file of the Go toolchain in goroot.
+func stdLibrary(cfg *packages.Config, goroot string, pkgPaths []string, opts Options) (*Library, error) {
+	goVersion, err := goEnv(cfg, "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pkgPaths)
+	licensePath := filepath.Join(goroot, "LICENSE")
+	return &Library{
+		LicensePath:  licensePath,
+		LicenseFiles: findLicenseFiles(licensePath),
+		Packages:     pkgPaths,
+		module: &Module{
+			Path:    StdLibModulePath,
+			Version: semverForGoVersion(goVersion),
+			Dir:     filepath.Join(goroot, "src"),
+			// The standard library comes with the Go toolchain, not from the checksum
+			// database.
+			ChecksumExclusion: ExclusionStdLib,
+		},
+		traceURLs: opts.TraceURLs,
+		resolver:  opts.SourceResolver,
+		cache:     opts.Cache,
+	}, nil
+}
+
+// goEnv returns the value of the Go environment variable name, as reported by the go
+// command in the environment that packages.Load uses for cfg.
+func goEnv(cfg *packages.Config, name string) (string, error) {
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, "go", "env", name)
+	cmd.Dir = cfg.Dir
+	cmd.Env = cfg.Env
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// semverForGoVersion converts a Go version like "go1.21.0" or "go1.21rc2" into the
+// semantic version used for the standard library module, e.g. "v1.21.0" or
+// "v1.21.0-rc.2". It returns "" for development versions of Go.
+func semverForGoVersion(goVersion string) string {
+	v := strings.TrimPrefix(goVersion, "go")
+	if v == goVersion || v == "" {
+		return ""
+	}
+	prerelease := ""
+	if i := strings.IndexAny(v, "abcdefghijklmnopqrstuvwxyz"); i >= 0 {
+		v, prerelease = v[:i], v[i:]
+		j := strings.IndexAny(prerelease, "0123456789")
+		if j < 0 {
+			return ""
+		}
+		prerelease = "-" + prerelease[:j] + "." + prerelease[j:]
+	}
+	switch strings.Count(v, ".") {
+	case 0:
+		v += ".0.0"
+	case 1:
+		v += ".0"
+	}
+	return "v" + v + prerelease
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestIsStdLib(t *testing.T) {
+	goroot := filepath.Join(t.TempDir(), "go")
+	for _, test := range []struct {
+		desc string
+		pkg  *packages.Package
+		want bool
+	}{
+		{
+			desc: "Package in GOROOT",
+			pkg:  &packages.Package{Name: "strings", GoFiles: []string{filepath.Join(goroot, "src", "strings", "strings.go")}},
+			want: true,
+		},
+		{
+			desc: "Package in another Go installation",
+			pkg:  &packages.Package{Name: "strings", GoFiles: []string{filepath.Join(goroot+"1.21", "src", "strings", "strings.go")}},
+			want: false,
+		},
+		{
+			desc: "Unsafe has no Go files",
+			pkg:  &packages.Package{Name: "unsafe"},
+			want: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := isStdLib(test.pkg, goroot); got != test.want {
+				t.Errorf("isStdLib(%v, %q) = %v, want %v", test.pkg.GoFiles, goroot, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSemverForGoVersion(t *testing.T) {
+	for _, test := range []struct {
+		goVersion string
+		want      string
+	}{
+		{goVersion: "go1.21.0", want: "v1.21.0"},
+		{goVersion: "go1.20", want: "v1.20.0"},
+		{goVersion: "go1", want: "v1.0.0"},
+		{goVersion: "go1.21rc2", want: "v1.21.0-rc.2"},
+		{goVersion: "go1.18beta1", want: "v1.18.0-beta.1"},
+		{goVersion: "devel go1.22-1f2a3b4 Tue Aug 1 00:00:00 2023 +0000", want: ""},
+	} {
+		if got := semverForGoVersion(test.goVersion); got != test.want {
+			t.Errorf("semverForGoVersion(%q) = %q, want %q", test.goVersion, got, test.want)
+		}
+	}
+}
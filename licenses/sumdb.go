@@ -0,0 +1,108 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/packages"
+)
+
+// Reasons why the go command doesn't verify a module against the checksum database, see
+// Module.ChecksumExclusion.
+const (
+	ExclusionSumDBOff       = "gosumdb_off"
+	ExclusionNoSumDB        = "gonosumdb"
+	ExclusionPrivate        = "goprivate"
+	ExclusionMainModule     = "main_module"
+	ExclusionLocalDirectory = "local_directory"
+	ExclusionStdLib         = "stdlib"
+)
+
+// checksumPolicy describes which modules the go command verifies against the checksum
+// database, see https://go.dev/ref/mod#private-module-privacy.
+type checksumPolicy struct {
+	// off is true if GOSUMDB=off disables the checksum database.
+	off bool
+	// db is the name of the checksum database, e.g. sum.golang.org.
+	db string
+	// noSumDB are the GONOSUMDB module path patterns, which default to GOPRIVATE.
+	noSumDB string
+	// private are the GOPRIVATE module path patterns.
+	private string
+}
+
+// loadChecksumPolicy reads the checksum policy of the go command used for cfg.
+func loadChecksumPolicy(cfg *packages.Config) (checksumPolicy, error) {
+	sumDB, err := goEnv(cfg, "GOSUMDB")
+	if err != nil {
+		return checksumPolicy{}, err
+	}
+	noSumDB, err := goEnv(cfg, "GONOSUMDB")
+	if err != nil {
+		return checksumPolicy{}, err
+	}
+	private, err := goEnv(cfg, "GOPRIVATE")
+	if err != nil {
+		return checksumPolicy{}, err
+	}
+	return checksumPolicy{off: sumDB == "off", db: checksumDBName(sumDB), noSumDB: noSumDB, private: private}, nil
+}
+
+// checksumDBName returns the name of the checksum database configured by GOSUMDB, which
+// may be followed by its public key and URL, e.g. "sum.golang.org+<key> https://...".
+func checksumDBName(sumDB string) string {
+	fields := strings.Fields(sumDB)
+	if len(fields) == 0 {
+		return "sum.golang.org"
+	}
+	if i := strings.Index(fields[0], "+"); i >= 0 {
+		return fields[0][:i]
+	}
+	return fields[0]
+}
+
+// apply sets the checksum database fields of m.
+func (p checksumPolicy) apply(m *Module) {
+	m.ChecksumExclusion = p.exclusion(m)
+	m.ChecksumVerified = m.ChecksumExclusion == ""
+	m.ChecksumDB = ""
+	if m.ChecksumVerified {
+		m.ChecksumDB = p.db
+	}
+}
+
+// exclusion returns why the content of m is not verified against the checksum database,
+// or "" if it is. Modules without a version, e.g. the main module or replacements by a
+// directory, are never verified.
+func (p checksumPolicy) exclusion(m *Module) string {
+	switch {
+	case m.Path == StdLibModulePath:
+		return ExclusionStdLib
+	case m.Main:
+		return ExclusionMainModule
+	case m.Version == "":
+		return ExclusionLocalDirectory
+	case p.off:
+		return ExclusionSumDBOff
+	case !module.MatchPrefixPatterns(p.noSumDB, m.Path):
+		return ""
+	case p.noSumDB == p.private && module.MatchPrefixPatterns(p.private, m.Path):
+		// GONOSUMDB is not set and defaults to GOPRIVATE.
+		return ExclusionPrivate
+	}
+	return ExclusionNoSumDB
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestChecksumPolicyExclusion(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		policy checksumPolicy
+		module Module
+		want   string
+	}{
+		{
+			desc:   "Public module",
+			module: Module{Path: "github.com/google/go-cmp", Version: "v0.5.9"},
+			want:   "",
+		},
+		{
+			desc:   "Checksum database is off",
+			policy: checksumPolicy{off: true},
+			module: Module{Path: "github.com/google/go-cmp", Version: "v0.5.9"},
+			want:   ExclusionSumDBOff,
+		},
+		{
+			desc:   "Private module",
+			policy: checksumPolicy{noSumDB: "corp.example.com,github.com/acme/*"},
+			module: Module{Path: "github.com/acme/widgets/v2", Version: "v2.1.0"},
+			want:   ExclusionNoSumDB,
+		},
+		{
+			desc:   "Module not matching GONOSUMDB",
+			policy: checksumPolicy{noSumDB: "corp.example.com"},
+			module: Module{Path: "github.com/acme/widgets", Version: "v1.0.0"},
+			want:   "",
+		},
+		{
+			desc:   "Private module by GOPRIVATE",
+			policy: checksumPolicy{noSumDB: "corp.example.com", private: "corp.example.com"},
+			module: Module{Path: "corp.example.com/widgets", Version: "v1.0.0"},
+			want:   ExclusionPrivate,
+		},
+		{
+			desc:   "Main module",
+			module: Module{Path: "github.com/acme/app", Main: true},
+			want:   ExclusionMainModule,
+		},
+		{
+			desc:   "Replaced by a directory",
+			module: Module{Path: "github.com/acme/widgets"},
+			want:   ExclusionLocalDirectory,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.policy.exclusion(&test.module); got != test.want {
+				t.Errorf("exclusion(%+v) = %q, want %q", test.module, got, test.want)
+			}
+		})
+	}
+}
+
+func TestChecksumDBName(t *testing.T) {
+	for _, test := range []struct {
+		sumDB string
+		want  string
+	}{
+		{sumDB: "", want: "sum.golang.org"},
+		{sumDB: "sum.golang.org", want: "sum.golang.org"},
+		{sumDB: "sum.golang.google.cn", want: "sum.golang.google.cn"},
+		{sumDB: "sum.example.com+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ry+cTAkbx9Zz6G3cU https://sum.example.com/db", want: "sum.example.com"},
+	} {
+		if got := checksumDBName(test.sumDB); got != test.want {
+			t.Errorf("checksumDBName(%q) = %q, want %q", test.sumDB, got, test.want)
+		}
+	}
+}
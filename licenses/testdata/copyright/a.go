@@ -0,0 +1,5 @@
+// Copyright 2016 The Foo Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package copyright
@@ -0,0 +1,6 @@
+/*
+ * Copyright (c) 2017, 2019 Baz <baz@example.com>
+ * Copyright 2016 The Foo Authors. All rights reserved.
+ */
+
+package copyright
@@ -0,0 +1,4 @@
+package sub
+
+// The copyright statements of files are at their top, so this is not one:
+// Copyright 2020 Nobody
@@ -0,0 +1,3 @@
+// Copyright (c) 2014 The Authors
+
+package doccomment
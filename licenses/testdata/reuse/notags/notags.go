@@ -0,0 +1,2 @@
+// Package notags has no SPDX file tags, so the licenses in LICENSES/ apply.
+package notags
@@ -0,0 +1,6 @@
+// SPDX-FileCopyrightText: 2022 Google LLC
+//
+// SPDX-License-Identifier: MIT OR Apache-2.0
+
+// Package reuse follows the REUSE specification (https://reuse.software).
+package reuse
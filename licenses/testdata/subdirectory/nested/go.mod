@@ -0,0 +1,3 @@
+module example.com/subdirectory/nested
+
+go 1.17
@@ -0,0 +1,115 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readVendoredModules reads the modules.txt file of vendorDir, which the go command
+// writes with `go mod vendor`, and returns the modules of the vendored packages by
+// import path. Modules replaced with a module version are returned as that version;
+// modules replaced with a local directory have no upstream version and are left out.
+func readVendoredModules(vendorDir string) (map[string]*Module, error) {
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("vendor mode needs vendor/modules.txt, run `go mod vendor`: %w", err)
+	}
+	defer f.Close()
+	modules := make(map[string]*Module)
+	var current *Module
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "## "):
+			// Annotations of the current module, e.g. "## explicit; go 1.17".
+		case strings.HasPrefix(line, "# "):
+			current = parseVendoredModule(strings.Fields(strings.TrimPrefix(line, "# ")), vendorDir)
+		case line != "" && current != nil:
+			modules[line] = current
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Name(), err)
+	}
+	return modules, nil
+}
+
+// parseVendoredModule parses the fields of a module line of modules.txt, e.g.
+// "golang.org/x/net v0.1.0" or "example.com/a v1.0.0 => example.com/fork v1.0.1". It
+// returns nil for modules replaced with a local directory and for lines it doesn't
+// understand.
+func parseVendoredModule(fields []string, vendorDir string) *Module {
+	old, replacement := fields, []string(nil)
+	for i, f := range fields {
+		if f == "=>" {
+			old, replacement = fields[:i], fields[i+1:]
+			break
+		}
+	}
+	if len(old) == 0 || len(old) > 2 {
+		return nil
+	}
+	m := &Module{
+		Path: old[0],
+		// Vendored files are below the path of the module being replaced.
+		Dir: filepath.Join(vendorDir, filepath.FromSlash(old[0])),
+	}
+	if len(old) == 2 {
+		m.Version = strings.TrimSuffix(old[1], "+incompatible")
+	}
+	switch len(replacement) {
+	case 0:
+		if m.Version == "" {
+			return nil
+		}
+		return m
+	case 2:
+		m.Replaces = &Module{Path: m.Path, Version: m.Version}
+		m.Path = replacement[0]
+		m.Version = strings.TrimSuffix(replacement[1], "+incompatible")
+		return m
+	default:
+		// Replaced with a local directory, e.g. "example.com/a v1.0.0 => ../a".
+		return nil
+	}
+}
+
+// vendoredModules are the modules listed in the modules.txt files of vendor directories
+// by directory, which are read when the first package of a directory is looked up.
+type vendoredModules map[string]map[string]*Module
+
+// module returns the module of the vendored package pkgPath in pkgDir, or nil if pkgDir
+// is not in a vendor directory or its modules.txt lists no upstream version of it.
+func (v vendoredModules) module(pkgDir, pkgPath string) (*Module, error) {
+	vendorDir := strings.TrimSuffix(pkgDir, string(filepath.Separator)+filepath.FromSlash(pkgPath))
+	if vendorDir == pkgDir || filepath.Base(vendorDir) != "vendor" {
+		return nil, nil
+	}
+	modules, ok := v[vendorDir]
+	if !ok {
+		var err error
+		if modules, err = readVendoredModules(vendorDir); err != nil {
+			return nil, err
+		}
+		v[vendorDir] = modules
+	}
+	return modules[pkgPath], nil
+}
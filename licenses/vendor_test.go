@@ -0,0 +1,124 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadVendoredModules(t *testing.T) {
+	vendorDir := t.TempDir()
+	modulesTxt := `# github.com/mitchellh/go-homedir v1.1.0
+## explicit
+github.com/mitchellh/go-homedir
+# github.com/pkg/errors v0.8.0 => github.com/pkg/errors v0.9.1
+## explicit; go 1.12
+github.com/pkg/errors
+# golang.org/x/net v0.0.1 => example.com/net-fork v0.0.2+incompatible
+golang.org/x/net/html
+golang.org/x/net/html/atom
+# example.com/local v1.0.0 => ../local
+example.com/local
+# example.com/all => example.com/all-fork v1.2.3
+example.com/all/sub
+`
+	if err := os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte(modulesTxt), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readVendoredModules(vendorDir)
+	if err != nil {
+		t.Fatalf("readVendoredModules() = (_, %q), want (_, nil)", err)
+	}
+	net := &Module{
+		Path:     "example.com/net-fork",
+		Version:  "v0.0.2",
+		Dir:      filepath.Join(vendorDir, "golang.org", "x", "net"),
+		Replaces: &Module{Path: "golang.org/x/net", Version: "v0.0.1"},
+	}
+	want := map[string]*Module{
+		"github.com/mitchellh/go-homedir": {
+			Path:    "github.com/mitchellh/go-homedir",
+			Version: "v1.1.0",
+			Dir:     filepath.Join(vendorDir, "github.com", "mitchellh", "go-homedir"),
+		},
+		"github.com/pkg/errors": {
+			Path:     "github.com/pkg/errors",
+			Version:  "v0.9.1",
+			Dir:      filepath.Join(vendorDir, "github.com", "pkg", "errors"),
+			Replaces: &Module{Path: "github.com/pkg/errors", Version: "v0.8.0"},
+		},
+		"golang.org/x/net/html":      net,
+		"golang.org/x/net/html/atom": net,
+		"example.com/all/sub": {
+			Path:     "example.com/all-fork",
+			Version:  "v1.2.3",
+			Dir:      filepath.Join(vendorDir, "example.com", "all"),
+			Replaces: &Module{Path: "example.com/all"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readVendoredModules(): diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestReadVendoredModulesMissing(t *testing.T) {
+	if _, err := readVendoredModules(t.TempDir()); err == nil {
+		t.Error("readVendoredModules() = (_, nil), want (_, error) without modules.txt")
+	}
+}
+
+func TestLibrariesVendor(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("..", "testdata", "modules", "vendored03"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"../testdata/modules/vendored03/LICENSE":                                        "Apache-2.0",
+			"../testdata/modules/vendored03/vendor/github.com/mitchellh/go-homedir/LICENSE": "MIT",
+		},
+		licenseTypes: map[string]Type{
+			"../testdata/modules/vendored03/LICENSE":                                        Notice,
+			"../testdata/modules/vendored03/vendor/github.com/mitchellh/go-homedir/LICENSE": Notice,
+		},
+	}
+	libs, err := LibrariesWithOptions(context.Background(), classifier, Options{Dir: dir, Vendor: true}, ".")
+	if err != nil {
+		t.Fatalf("LibrariesWithOptions() = (_, %q), want (_, nil)", err)
+	}
+	for _, lib := range libs {
+		if lib.Name() != "github.com/mitchellh/go-homedir" {
+			continue
+		}
+		m := lib.Module()
+		if m.Path != "github.com/mitchellh/go-homedir" || m.Version != "v1.1.0" {
+			t.Errorf("module of vendored library = %s@%s, want github.com/mitchellh/go-homedir@v1.1.0", m.Path, m.Version)
+		}
+		url, err := lib.FileURL(context.Background(), lib.LicensePath)
+		if err != nil {
+			t.Fatalf("FileURL() = (_, %q), want (_, nil)", err)
+		}
+		if want := "https://github.com/mitchellh/go-homedir/blob/v1.1.0/LICENSE"; url != want {
+			t.Errorf("FileURL() = %q, want %q", url, want)
+		}
+		return
+	}
+	t.Errorf("LibrariesWithOptions() = %v, want a library for github.com/mitchellh/go-homedir", libs)
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/otiai10/copy"
+)
+
+// verifiedCopy copies src to dest like copy.Copy and then verifies that every copied
+// file has the checksum of its source, so that incomplete writes, e.g. to network
+// storage, fail the save instead of going unnoticed.
+func verifiedCopy(src, dest string, opt copy.Options) error {
+	if err := copy.Copy(src, dest, opt); err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if opt.Skip != nil {
+			if skip, err := opt.Skip(p); err != nil {
+				return err
+			} else if skip && d.IsDir() {
+				return filepath.SkipDir
+			} else if skip {
+				return nil
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if opt.OnSymlink != nil && opt.OnSymlink(p) == copy.Skip {
+				return nil
+			}
+			if info, err = os.Stat(p); err != nil {
+				return err
+			}
+		}
+		if !info.Mode().IsRegular() {
+			// Symlinked directories are copied, but not verified.
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		return verifyFile(p, filepath.Join(dest, rel))
+	})
+}
+
+// verifyFile checks that the file dst has the same contents as src.
+func verifyFile(src, dst string) error {
+	want, err := fileSHA256(src)
+	if err != nil {
+		return err
+	}
+	got, err := fileSHA256(dst)
+	if err != nil {
+		return fmt.Errorf("verifying copy of %s: %w", src, err)
+	}
+	if got != want {
+		return fmt.Errorf("verifying copy of %s: %s has SHA-256 %s, want %s", src, dst, got, want)
+	}
+	return nil
+}
+
+// verifyArchive checks that every file in the archive written by archiveSrc has the same
+// contents as the file below src that it was created from.
+func verifyArchive(src, archive string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("verifying archive %s: %w", archive, err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("verifying archive %s: %w", archive, err)
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return fmt.Errorf("verifying archive %s: %w", archive, err)
+		}
+		srcPath := filepath.Join(src, filepath.FromSlash(path.Clean(hdr.Name)))
+		want, err := fileSHA256(srcPath)
+		if err != nil {
+			return err
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			return fmt.Errorf("verifying archive %s: %s has SHA-256 %s, want %s", archive, hdr.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "LICENSE")
+	writeTestFile(t, src, "license text")
+	for _, test := range []struct {
+		desc    string
+		dst     string // contents of the copy, no copy if empty
+		wantErr string
+	}{
+		{desc: "identical copy", dst: "license text"},
+		{desc: "truncated copy", dst: "license", wantErr: "has SHA-256"},
+		{desc: "missing copy", wantErr: "verifying copy of " + src},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "LICENSE")
+			if test.dst != "" {
+				writeTestFile(t, dst, test.dst)
+			}
+			err := verifyFile(src, dst)
+			if test.wantErr == "" && err != nil {
+				t.Errorf("verifyFile() = %v, want nil", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("verifyFile() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyArchive(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		// change modifies the source directory after it has been archived.
+		change  func(t *testing.T, src string)
+		wantErr string
+	}{
+		{desc: "unchanged source"},
+		{
+			desc:    "changed file",
+			change:  func(t *testing.T, src string) { writeTestFile(t, filepath.Join(src, "pkg", "a.go"), "package changed") },
+			wantErr: "pkg/a.go has SHA-256",
+		},
+		{
+			desc: "deleted file",
+			change: func(t *testing.T, src string) {
+				if err := os.Remove(filepath.Join(src, "LICENSE")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "LICENSE",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			src := t.TempDir()
+			writeTestFile(t, filepath.Join(src, "LICENSE"), "license text")
+			writeTestFile(t, filepath.Join(src, "pkg", "a.go"), "package pkg")
+			dest := t.TempDir()
+			if err := archiveSrc(src, dest, false); err != nil {
+				t.Fatal(err)
+			}
+			if test.change != nil {
+				test.change(t, src)
+			}
+			err := verifyArchive(src, filepath.Join(dest, SourceArchiveName))
+			if test.wantErr == "" && err != nil {
+				t.Errorf("verifyArchive() = %v, want nil", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("verifyArchive() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyArchiveCorrupt(t *testing.T) {
+	src := t.TempDir()
+	archive := filepath.Join(t.TempDir(), SourceArchiveName)
+	writeTestFile(t, archive, "not a gzip file")
+	if err := verifyArchive(src, archive); err == nil || !strings.Contains(err.Error(), "verifying archive") {
+		t.Errorf("verifyArchive() = %v, want an error verifying the archive", err)
+	}
+}
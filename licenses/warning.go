@@ -0,0 +1,74 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// WarningKind groups warnings of the same cause.
+type WarningKind string
+
+const (
+	// WarningEmptyVersion is emitted for modules without a version, e.g. the main module,
+	// whose file URLs point to the default branch.
+	WarningEmptyVersion = WarningKind("empty-version")
+	// WarningVendoredModule is emitted for vendored libraries whose module can't be
+	// identified, so that they are attributed to the vendoring module or have no URL.
+	WarningVendoredModule = WarningKind("vendored-module")
+	// WarningNonGoCode is emitted for packages with non-Go files, whose dependencies
+	// can't be inspected.
+	WarningNonGoCode = WarningKind("non-go-code")
+	// WarningLicenseInComment is emitted for packages licensed by a header comment
+	// rather than a license file.
+	WarningLicenseInComment = WarningKind("license-in-comment")
+	// WarningNotInModuleCache is emitted for modules of go.sum missing from the module
+	// cache, see GoSumLibraries.
+	WarningNotInModuleCache = WarningKind("not-in-module-cache")
+	// WarningUnidentifiedLicense is emitted for packages with a license file that can't
+	// be identified, which were attributed the license of a parent directory instead.
+	WarningUnidentifiedLicense = WarningKind("unidentified-license")
+)
+
+// Warning is a problem that doesn't stop a scan, but may make its results incomplete or
+// wrong, e.g. a license URL that points to the default branch.
+type Warning struct {
+	Kind WarningKind
+	// Module is the path of the module the warning is about, if any.
+	Module  string
+	Message string
+}
+
+type warningHandlerKey struct{}
+
+// WithWarningHandler returns a context that passes the warnings of the functions of this
+// package called with it to handle, e.g. to summarize them at the end of a run, rather
+// than logging them.
+func WithWarningHandler(ctx context.Context, handle func(Warning)) context.Context {
+	return context.WithValue(ctx, warningHandlerKey{}, handle)
+}
+
+// warnf passes a warning to the handler of ctx, or logs it if there is none.
+func warnf(ctx context.Context, kind WarningKind, module string, format string, args ...interface{}) {
+	w := Warning{Kind: kind, Module: module, Message: fmt.Sprintf(format, args...)}
+	if handle, ok := ctx.Value(warningHandlerKey{}).(func(Warning)); ok {
+		handle(w)
+		return
+	}
+	klog.WarningDepth(1, w.Message)
+}
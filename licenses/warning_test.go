@@ -0,0 +1,40 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithWarningHandler(t *testing.T) {
+	var got []Warning
+	ctx := WithWarningHandler(context.Background(), func(w Warning) {
+		got = append(got, w)
+	})
+	if _, err := NewOfflineResolver("").ModuleInfo(ctx, "github.com/google/trillian", ""); err != nil {
+		t.Fatalf("ModuleInfo() = (_, %q), want (_, nil)", err)
+	}
+	want := []Warning{{
+		Kind:    WarningEmptyVersion,
+		Module:  "github.com/google/trillian",
+		Message: "module github.com/google/trillian has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("warnings: diff (-want +got)\n%s", diff)
+	}
+}
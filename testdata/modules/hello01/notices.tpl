@@ -0,0 +1,2 @@
+{{range .}}{{template "row" .}}
+{{end}}
@@ -0,0 +1 @@
+{{define "row"}} - {{ broken
@@ -0,0 +1 @@
+{{define "row"}} - {{.Name}} ({{.LicenseName}}){{end}}